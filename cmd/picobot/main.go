@@ -3,27 +3,36 @@ package main
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"log"
 
 	"github.com/local/picobot/internal/agent"
 	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/agent/tools"
 	"github.com/local/picobot/internal/channels"
 	"github.com/local/picobot/internal/chat"
 	"github.com/local/picobot/internal/config"
 	"github.com/local/picobot/internal/cron"
 	"github.com/local/picobot/internal/heartbeat"
 	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/store"
+	"github.com/local/picobot/internal/transcribe"
+	"github.com/local/picobot/internal/usage"
 )
 
 const version = "0.2.0"
@@ -112,45 +121,88 @@ func NewRootCmd() *cobra.Command {
 
 	agentCmd := &cobra.Command{
 		Use:   "agent",
-		Short: "Run a single-shot agent query (use -m)",
+		Short: "Chat with the agent in the terminal, or run a single-shot query with -m",
 		Run: func(cmd *cobra.Command, args []string) {
 			msg, _ := cmd.Flags().GetString("message")
 			modelFlag, _ := cmd.Flags().GetString("model")
-			if msg == "" {
-				fmt.Println("Specify a message with -m \"your message\"")
-				return
-			}
 
+			name, _ := cmd.Flags().GetString("agent")
 			hub := chat.NewHub(100)
-			cfg, _ := config.LoadConfig()
+			cfg := loadAgentConfig()
 			provider := providers.NewProviderFromConfig(cfg)
 
-			// choose model: flag > config default > provider default
-			model := modelFlag
-			if model == "" && cfg.Agents.Defaults.Model != "" {
-				model = cfg.Agents.Defaults.Model
+			ag, p, err := newAgent(cfg, name, hub, provider, modelFlag, nil)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+				return
 			}
-			if model == "" {
-				model = provider.GetDefaultModel()
+			defer ag.Close()
+			st, err := store.Open(cfg.Storage, cfg.Agents.Defaults.Workspace)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error opening storage:", err)
+				return
+			}
+			defer st.Close()
+			if name != config.DefaultAgent {
+				st = st.ForAgent(name, p.Workspace)
+			}
+			if err := ag.SetStore(st); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error loading storage:", err)
+				return
 			}
 
-			maxIter := cfg.Agents.Defaults.MaxToolIterations
-			if maxIter <= 0 {
-				maxIter = 100
+			if msg != "" {
+				timeout, _ := cmd.Flags().GetDuration("timeout")
+				resp, err := ag.ProcessDirect(msg, timeout, toolActivity(cmd.ErrOrStderr()))
+				if err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+					return
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), resp)
+				return
 			}
-			ag := agent.NewAgentLoop(hub, provider, model, maxIter, cfg.Agents.Defaults.Workspace, nil, cfg.MCPServers)
-			defer ag.Close()
 
-			resp, err := ag.ProcessDirect(msg, 60*time.Second)
-			if err != nil {
+			// interactive chat in session cli:<session>
+			sessionName, _ := cmd.Flags().GetString("session")
+			if sessionName == "" {
+				sessionName = name
+			}
+			historyPath := ""
+			if cfgPath, _, err := config.ResolveDefaultPaths(); err == nil {
+				historyPath = filepath.Join(filepath.Dir(cfgPath), "cli_history")
+			}
+			terminal := channels.NewTerminal(hub, sessionName, os.Stdin, cmd.OutOrStdout(), historyPath)
+			if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+				log.SetOutput(terminal)
+			} else {
+				log.SetOutput(io.Discard)
+			}
+			defer log.SetOutput(os.Stderr)
+
+			ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+			defer cancel()
+			hub.StartRouter(ctx)
+			done := make(chan struct{})
+			go func() {
+				ag.Run(ctx)
+				close(done)
+			}()
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Chatting with agent %s in session cli:%s. /help lists commands, /exit quits.\n", name, sessionName)
+			fmt.Fprintln(cmd.OutOrStdout(), "End a line with \\ to continue it, or put a longer message between two \"\"\" lines.")
+			if err := terminal.Run(ctx); err != nil {
 				fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
-				return
 			}
-			fmt.Fprintln(cmd.OutOrStdout(), resp)
+			cancel()
+			<-done
 		},
 	}
-	agentCmd.Flags().StringP("message", "m", "", "Message to send to the agent")
+	agentCmd.Flags().StringP("message", "m", "", "Message to send to the agent (single-shot; without it, chat interactively)")
 	agentCmd.Flags().StringP("model", "M", "", "Model to use (overrides config/provider default)")
+	agentCmd.Flags().StringP("agent", "a", config.DefaultAgent, "Agent profile to ask (see agents.profiles)")
+	agentCmd.Flags().StringP("session", "s", "", "Session to continue in interactive mode, kept as cli:<session> (default: the agent name)")
+	agentCmd.Flags().DurationP("timeout", "t", 60*time.Second, "Time limit for a single-shot query")
+	agentCmd.Flags().BoolP("verbose", "v", false, "Show log output in interactive mode")
 	rootCmd.AddCommand(agentCmd)
 
 	gatewayCmd := &cobra.Command{
@@ -158,41 +210,67 @@ func NewRootCmd() *cobra.Command {
 		Short: "Start long-running gateway (agent, channels, heartbeat)",
 		Run: func(cmd *cobra.Command, args []string) {
 			hub := chat.NewHub(200)
-			cfg, _ := config.LoadConfig()
+			cfg := loadAgentConfig()
 			provider := providers.NewProviderFromConfig(cfg)
-
-			// choose model: flag > config > provider default
 			modelFlag, _ := cmd.Flags().GetString("model")
-			model := modelFlag
-			if model == "" && cfg.Agents.Defaults.Model != "" {
-				model = cfg.Agents.Defaults.Model
-			}
-			if model == "" {
-				model = provider.GetDefaultModel()
+			if err := cfg.ValidateRoutes(); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "error: agents.routes:", err)
+				return
 			}
 
+			// one agent loop per profile; the router picks the one that answers
+			router := agent.NewRouter(hub, cfg.Agents.Routes)
+
 			// create scheduler with fire callback that routes back through the agent loop, so the LLM can process the reminder and respond naturally to the user.
+			// The reminder goes to the profile that scheduled it.
 			scheduler := cron.NewScheduler(func(job cron.Job) {
 				log.Printf("cron fired: %s — %s", job.Name, job.Message)
-				hub.In <- chat.Inbound{
+				router.Deliver(job.Agent, chat.Inbound{
 					Channel:  job.Channel,
 					SenderID: "cron",
 					ChatID:   job.ChatID,
 					Content:  fmt.Sprintf("[Scheduled reminder fired] %s — Please relay this to the user in a friendly way.", job.Message),
-				}
+				})
 			})
 
-			maxIter := cfg.Agents.Defaults.MaxToolIterations
-			if maxIter <= 0 {
-				maxIter = 100
+			// sessions, memory items, cron jobs and usage go to the configured storage
+			st, err := store.Open(cfg.Storage, cfg.Agents.Defaults.Workspace)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "failed to open storage: %v\n", err)
+				return
+			}
+			defer st.Close()
+			if st.Jobs != nil {
+				if err := scheduler.SetStore(st.Jobs); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to load cron jobs: %v\n", err)
+				}
+			}
+
+			defer router.Close()
+			for _, name := range cfg.AgentNames() {
+				ag, p, err := newAgent(cfg, name, router.Hub(name), provider, modelFlag, scheduler)
+				if err != nil {
+					fmt.Fprintln(cmd.ErrOrStderr(), "error:", err)
+					return
+				}
+				router.Add(name, ag)
+				agentStore := st
+				if name != config.DefaultAgent {
+					agentStore = st.ForAgent(name, p.Workspace)
+				}
+				if err := ag.SetStore(agentStore); err != nil {
+					fmt.Fprintf(os.Stderr, "failed to load storage: %v\n", err)
+					return
+				}
+				if err := ag.ScheduleConsolidation(cfg.Memory.Consolidation); err != nil {
+					fmt.Fprintf(os.Stderr, "memory consolidation disabled: %v\n", err)
+				}
 			}
-			ag := agent.NewAgentLoop(hub, provider, model, maxIter, cfg.Agents.Defaults.Workspace, scheduler, cfg.MCPServers)
-			defer ag.Close()
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
-			// start agent loop
-			go ag.Run(ctx)
+			// start agent loops
+			go router.Run(ctx)
 
 			// start cron scheduler
 			go scheduler.Start(ctx.Done())
@@ -204,9 +282,12 @@ func NewRootCmd() *cobra.Command {
 			}
 			heartbeat.StartHeartbeat(ctx, cfg.Agents.Defaults.Workspace, hbInterval, hub)
 
+			// voice messages are transcribed by the channels before reaching the agent
+			tr := transcribe.NewFromConfig(cfg)
+
 			// start telegram if enabled
 			if cfg.Channels.Telegram.Enabled {
-				if err := channels.StartTelegram(ctx, hub, cfg.Channels.Telegram.Token, cfg.Channels.Telegram.AllowFrom); err != nil {
+				if err := channels.StartTelegram(ctx, hub, cfg.Channels.Telegram.Token, cfg.Channels.Telegram.AllowFrom, tr); err != nil {
 					fmt.Fprintf(os.Stderr, "failed to start telegram: %v\n", err)
 				}
 			}
@@ -236,7 +317,7 @@ func NewRootCmd() *cobra.Command {
 					home, _ := os.UserHomeDir()
 					dbPath = filepath.Join(home, dbPath[2:])
 				}
-				if err := channels.StartWhatsApp(ctx, hub, dbPath, cfg.Channels.WhatsApp.AllowFrom); err != nil {
+				if err := channels.StartWhatsApp(ctx, hub, dbPath, cfg.Channels.WhatsApp.AllowFrom, tr); err != nil {
 					fmt.Fprintf(os.Stderr, "failed to start whatsapp: %v\n", err)
 				}
 			}
@@ -255,7 +336,7 @@ func NewRootCmd() *cobra.Command {
 			cancel()
 		},
 	}
-	gatewayCmd.Flags().StringP("model", "M", "", "Model to use (overrides model in config.json)")
+	gatewayCmd.Flags().StringP("model", "M", "", "Model to use (overrides the default agent's model in config.json)")
 	rootCmd.AddCommand(gatewayCmd)
 
 	// memory subcommands: read, append, write, recent
@@ -263,6 +344,7 @@ func NewRootCmd() *cobra.Command {
 		Use:   "memory",
 		Short: "Inspect or modify workspace memory files",
 	}
+	memoryCmd.PersistentFlags().String("scope", "", "Memory scope to use, e.g. telegram:123456 (default: shared memory)")
 
 	readCmd := &cobra.Command{
 		Use:   "read [today|long]",
@@ -279,7 +361,7 @@ func NewRootCmd() *cobra.Command {
 			if strings.HasPrefix(ws, "~/") {
 				ws = filepath.Join(home, ws[2:])
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			mem := memory.NewMemoryStoreWithWorkspace(ws, 100).Scope(memoryScope(cmd))
 			switch target {
 			case "today":
 				out, _ := mem.ReadToday()
@@ -313,7 +395,7 @@ func NewRootCmd() *cobra.Command {
 			if strings.HasPrefix(ws, "~/") {
 				ws = filepath.Join(home, ws[2:])
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			mem := memory.NewMemoryStoreWithWorkspace(ws, 100).Scope(memoryScope(cmd))
 			switch target {
 			case "today":
 				if err := mem.AppendToday(content); err != nil {
@@ -362,7 +444,7 @@ func NewRootCmd() *cobra.Command {
 			if strings.HasPrefix(ws, "~/") {
 				ws = filepath.Join(home, ws[2:])
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			mem := memory.NewMemoryStoreWithWorkspace(ws, 100).Scope(memoryScope(cmd))
 			if err := mem.WriteLongTerm(content); err != nil {
 				fmt.Fprintln(cmd.ErrOrStderr(), "write failed:", err)
 				return
@@ -386,7 +468,7 @@ func NewRootCmd() *cobra.Command {
 			if strings.HasPrefix(ws, "~/") {
 				ws = filepath.Join(home, ws[2:])
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			mem := memory.NewMemoryStoreWithWorkspace(ws, 100).Scope(memoryScope(cmd))
 			out, _ := mem.GetRecentMemories(days)
 			fmt.Fprintln(cmd.OutOrStdout(), out)
 		},
@@ -419,7 +501,7 @@ func NewRootCmd() *cobra.Command {
 			if strings.HasPrefix(ws, "~/") {
 				ws = filepath.Join(home, ws[2:])
 			}
-			mem := memory.NewMemoryStoreWithWorkspace(ws, 100)
+			mem := memory.NewMemoryStoreWithWorkspace(ws, 100).Scope(memoryScope(cmd))
 			// Build memory items from today's file (split into lines) and long-term memory
 			items := make([]memory.MemoryItem, 0)
 			if td, err := mem.ReadToday(); err == nil && td != "" {
@@ -461,7 +543,307 @@ func NewRootCmd() *cobra.Command {
 	rankCmd.Flags().BoolP("verbose", "v", false, "Enable verbose diagnostic logging (to stdout)")
 	memoryCmd.AddCommand(rankCmd)
 
+	searchCmd := &cobra.Command{
+		Use:   "search -q <query>",
+		Short: "Full-text search across all memory files",
+		Run: func(cmd *cobra.Command, args []string) {
+			q, _ := cmd.Flags().GetString("query")
+			if q == "" {
+				fmt.Fprintln(cmd.ErrOrStderr(), "-q query required")
+				return
+			}
+			top, _ := cmd.Flags().GetInt("top")
+			cfg, _ := config.LoadConfig()
+			mem := memory.NewMemoryStoreWithWorkspace(resolveWorkspace(cfg), 100).Scope(memoryScope(cmd))
+			res, err := mem.Search(q, top)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "search failed:", err)
+				return
+			}
+			if len(res) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no matches")
+				return
+			}
+			for _, r := range res {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s:%d  %.2f  %s\n", r.File, r.Line, r.Score, r.Snippet)
+			}
+		},
+	}
+	searchCmd.Flags().StringP("query", "q", "", "Words to search for")
+	searchCmd.Flags().IntP("top", "k", 10, "Maximum number of results")
+	memoryCmd.AddCommand(searchCmd)
+
+	consolidateCmd := &cobra.Command{
+		Use:   "consolidate [--days N] [--archive]",
+		Short: "Merge durable facts from recent daily notes into MEMORY.md",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.LoadConfig()
+			days, _ := cmd.Flags().GetInt("days")
+			archive, _ := cmd.Flags().GetBool("archive")
+			if !cmd.Flags().Changed("days") && cfg.Memory.Consolidation.Days > 0 {
+				days = cfg.Memory.Consolidation.Days
+			}
+			provider := providers.NewProviderFromConfig(cfg)
+			model := cfg.Agents.Defaults.Model
+			if model == "" {
+				model = provider.GetDefaultModel()
+			}
+			mem := memory.NewMemoryStoreWithWorkspace(resolveWorkspace(cfg), 100).Scope(memoryScope(cmd))
+			rep, err := memory.Consolidate(cmd.Context(), mem, provider, model, memory.ConsolidateOptions{Days: days, Archive: archive})
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "consolidation failed:", err)
+				return
+			}
+			out := cmd.OutOrStdout()
+			for _, f := range rep.Added {
+				fmt.Fprintf(out, "+ [%s] %s\n", f.Section, f.Text)
+			}
+			for _, f := range rep.Updated {
+				fmt.Fprintf(out, "~ [%s] %s (was: %s)\n", f.Section, f.Text, f.Replaces)
+			}
+			fmt.Fprintf(out, "%d added, %d updated, %d already known\n", len(rep.Added), len(rep.Updated), rep.Skipped)
+			if len(rep.Archived) > 0 {
+				fmt.Fprintf(out, "archived: %s\n", strings.Join(rep.Archived, ", "))
+			}
+		},
+	}
+	consolidateCmd.Flags().IntP("days", "d", 7, "Number of days of notes to read")
+	consolidateCmd.Flags().Bool("archive", false, "Move the consolidated notes (except today's) to memory/archive/")
+	memoryCmd.AddCommand(consolidateCmd)
+
 	rootCmd.AddCommand(memoryCmd)
+
+	usageCmd := &cobra.Command{
+		Use:   "usage [--by session|sender|channel|model] [--days N]",
+		Short: "Report token usage and estimated cost",
+		Run: func(cmd *cobra.Command, args []string) {
+			by, _ := cmd.Flags().GetString("by")
+			days, _ := cmd.Flags().GetInt("days")
+			cfg, _ := config.LoadConfig()
+			var since time.Time
+			if days > 0 {
+				since = time.Now().AddDate(0, 0, -days)
+			}
+			st, err := store.Open(cfg.Storage, resolveWorkspace(cfg))
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "opening storage failed:", err)
+				return
+			}
+			defer st.Close()
+			records, err := st.Usage.Records(since)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "reading usage ledger failed:", err)
+				return
+			}
+			totals, err := usage.Aggregate(records, by, cfg.Usage.Prices)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+				return
+			}
+			if len(totals) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no usage recorded")
+				return
+			}
+
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "%s\tREQUESTS\tPROMPT\tCOMPLETION\tCOST (USD)\n", strings.ToUpper(by))
+			sum := usage.Totals{Key: "TOTAL", Priced: true}
+			for _, t := range totals {
+				fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", t.Key, t.Requests, t.PromptTokens, t.CompletionTokens, formatCost(t))
+				sum.Requests += t.Requests
+				sum.PromptTokens += t.PromptTokens
+				sum.CompletionTokens += t.CompletionTokens
+				sum.Cost += t.Cost
+				sum.Priced = sum.Priced && t.Priced
+			}
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\n", sum.Key, sum.Requests, sum.PromptTokens, sum.CompletionTokens, formatCost(sum))
+			tw.Flush()
+		},
+	}
+	usageCmd.Flags().String("by", "session", "Group by session, sender, channel or model")
+	usageCmd.Flags().IntP("days", "d", 0, "Only include the last N days (0 = all time)")
+	rootCmd.AddCommand(usageCmd)
+
+	// sessions subcommands: list, show, export, delete, prune
+	sessionsCmd := &cobra.Command{
+		Use:   "sessions",
+		Short: "Inspect, export and clean up stored conversations",
+	}
+
+	sessionsListCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List sessions with their message count and last activity",
+		Run: func(cmd *cobra.Command, args []string) {
+			sm, done, ok := openSessions(cmd)
+			if !ok {
+				return
+			}
+			defer done()
+			list := sm.List()
+			if len(list) == 0 {
+				fmt.Fprintln(cmd.OutOrStdout(), "no sessions stored")
+				return
+			}
+			tw := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "KEY\tMESSAGES\tLAST ACTIVITY")
+			for _, s := range list {
+				last := "-"
+				if t := s.LastActive(); !t.IsZero() {
+					last = t.Local().Format(time.DateTime)
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%s\n", s.Key, len(s.Messages), last)
+			}
+			tw.Flush()
+		},
+	}
+
+	sessionsShowCmd := &cobra.Command{
+		Use:   "show <key>",
+		Short: "Print the transcript of a session",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			sm, done, ok := openSessions(cmd)
+			if !ok {
+				return
+			}
+			defer done()
+			s, found := sm.Get(args[0])
+			if !found {
+				fmt.Fprintln(cmd.ErrOrStderr(), "no such session: "+args[0])
+				return
+			}
+			if err := session.Export(cmd.OutOrStdout(), s, "text"); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+			}
+		},
+	}
+
+	sessionsExportCmd := &cobra.Command{
+		Use:   "export <key> --format md|json|jsonl",
+		Short: "Export a session as Markdown, JSON or JSON Lines",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			format, _ := cmd.Flags().GetString("format")
+			sm, done, ok := openSessions(cmd)
+			if !ok {
+				return
+			}
+			defer done()
+			s, found := sm.Get(args[0])
+			if !found {
+				fmt.Fprintln(cmd.ErrOrStderr(), "no such session: "+args[0])
+				return
+			}
+			if err := session.Export(cmd.OutOrStdout(), s, format); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+			}
+		},
+	}
+	sessionsExportCmd.Flags().StringP("format", "f", "md", "Output format: md, json or jsonl")
+
+	sessionsDeleteCmd := &cobra.Command{
+		Use:   "delete <key>",
+		Short: "Delete a session",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			sm, done, ok := openSessions(cmd)
+			if !ok {
+				return
+			}
+			defer done()
+			if _, found := sm.Get(args[0]); !found {
+				fmt.Fprintln(cmd.ErrOrStderr(), "no such session: "+args[0])
+				return
+			}
+			if err := sm.Delete(args[0]); err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "delete failed:", err)
+				return
+			}
+			fmt.Fprintln(cmd.OutOrStdout(), "Deleted "+args[0])
+		},
+	}
+
+	sessionsPruneCmd := &cobra.Command{
+		Use:   "prune --older-than 30d",
+		Short: "Delete sessions with no activity for the given period",
+		Run: func(cmd *cobra.Command, args []string) {
+			olderThan, _ := cmd.Flags().GetString("older-than")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			age, err := parseAge(olderThan)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), err)
+				return
+			}
+			sm, done, ok := openSessions(cmd)
+			if !ok {
+				return
+			}
+			defer done()
+			cutoff := time.Now().Add(-age)
+			pruned, unknown := 0, 0
+			for _, s := range sm.List() {
+				last := s.LastActive()
+				if last.IsZero() {
+					unknown++ // never guess the age of sessions without timestamps
+					continue
+				}
+				if last.After(cutoff) {
+					continue
+				}
+				if !dryRun {
+					if err := sm.Delete(s.Key); err != nil {
+						fmt.Fprintf(cmd.ErrOrStderr(), "delete %s failed: %v\n", s.Key, err)
+						continue
+					}
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), s.Key)
+				pruned++
+			}
+			verb := "Deleted"
+			if dryRun {
+				verb = "Would delete"
+			}
+			fmt.Fprintf(cmd.OutOrStdout(), "%s %d session(s) inactive since %s\n", verb, pruned, cutoff.Local().Format(time.DateTime))
+			if unknown > 0 {
+				fmt.Fprintf(cmd.OutOrStdout(), "Kept %d session(s) without timestamps (from before timestamps were recorded)\n", unknown)
+			}
+		},
+	}
+	sessionsPruneCmd.Flags().String("older-than", "30d", "Inactivity period, e.g. 30d, 2w or 12h")
+	sessionsPruneCmd.Flags().Bool("dry-run", false, "Only list the sessions that would be deleted")
+
+	sessionsCmd.AddCommand(sessionsListCmd, sessionsShowCmd, sessionsExportCmd, sessionsDeleteCmd, sessionsPruneCmd)
+	rootCmd.AddCommand(sessionsCmd)
+
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Import session files and the usage ledger into the SQLite database",
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg, _ := config.LoadConfig()
+			path := store.SQLitePath(cfg.Storage)
+			db, err := store.OpenSQLite(path)
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "opening database failed:", err)
+				return
+			}
+			defer db.Close()
+			rep, err := store.Migrate(store.Files(resolveWorkspace(cfg)), db.Store())
+			if err != nil {
+				fmt.Fprintln(cmd.ErrOrStderr(), "migration failed:", err)
+				return
+			}
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Imported %d session(s) and %d usage record(s) into %s\n", rep.Sessions, rep.UsageRecords, path)
+			if rep.UsageSkipped {
+				fmt.Fprintln(out, "Usage records were skipped: the database already has some.")
+			}
+			if cfg.Storage.Backend != "sqlite" {
+				fmt.Fprintln(out, `Set "storage": {"backend": "sqlite"} in config.json to start using it.`)
+			}
+		},
+	}
+	rootCmd.AddCommand(migrateCmd)
+
 	return rootCmd
 }
 
@@ -480,6 +862,138 @@ func promptLine(reader *bufio.Reader, prompt string) string {
 	return strings.TrimSpace(line)
 }
 
+// resolveWorkspace returns the configured workspace with a leading "~/"
+// expanded, defaulting to ~/.picobot/workspace.
+func resolveWorkspace(cfg config.Config) string {
+	ws := cfg.Agents.Defaults.Workspace
+	if ws == "" {
+		ws = "~/.picobot/workspace"
+	}
+	if strings.HasPrefix(ws, "~/") {
+		home, _ := os.UserHomeDir()
+		ws = filepath.Join(home, ws[2:])
+	}
+	return ws
+}
+
+// loadAgentConfig loads the config for the agent and gateway commands, with
+// the workspace resolved like resolveWorkspace so that agents never keep
+// their sessions and memory in the current directory.
+func loadAgentConfig() config.Config {
+	cfg, _ := config.LoadConfig()
+	cfg.Agents.Defaults.Workspace = resolveWorkspace(cfg)
+	return cfg
+}
+
+// newAgent builds the loop of agent profile name on hub, configured from cfg
+// the same way for the gateway and the terminal chat. modelFlag, when set,
+// overrides the model of the default agent.
+func newAgent(cfg config.Config, name string, hub *chat.Hub, provider providers.LLMProvider, modelFlag string, scheduler *cron.Scheduler) (*agent.AgentLoop, config.AgentProfile, error) {
+	p, err := cfg.AgentProfile(name)
+	if err != nil {
+		return nil, p, err
+	}
+	// choose model: flag > config > provider default
+	model := p.Model
+	if modelFlag != "" && name == config.DefaultAgent {
+		model = modelFlag
+	}
+	if model == "" {
+		model = provider.GetDefaultModel()
+	}
+	maxIter := p.MaxToolIterations
+	if maxIter <= 0 {
+		maxIter = 100
+	}
+	if _, err := os.Stat(p.Workspace); name != config.DefaultAgent || os.IsNotExist(err) {
+		// a profile's own workspace, or a default one not onboarded yet, gets
+		// the default bootstrap files to edit
+		if strings.HasPrefix(p.Workspace, "~/") {
+			home, _ := os.UserHomeDir()
+			p.Workspace = filepath.Join(home, p.Workspace[2:])
+		}
+		if err := config.InitializeWorkspace(p.Workspace); err != nil {
+			return nil, p, fmt.Errorf("agent %s: workspace: %w", name, err)
+		}
+	}
+	servers := make(map[string]config.MCPServerConfig, len(p.MCPServers))
+	for _, server := range p.MCPServers {
+		servers[server] = cfg.MCPServers[server]
+	}
+
+	ag := agent.NewAgentLoop(hub, provider, model, maxIter, p.Workspace, scheduler, servers)
+	ag.SetStreaming(cfg.Agents.Defaults.Streaming)
+	ag.SetConcurrency(cfg.Agents.Defaults.MaxConcurrency)
+	ag.SetToolConcurrency(cfg.Agents.Defaults.MaxParallelTools)
+	ag.SetContextBudget(cfg.Agents.Defaults.ContextBudget)
+	ag.SetEmbedder(providers.NewEmbedderFromConfig(cfg))
+	if err := ag.SetTools(p.Tools); err != nil {
+		ag.Close()
+		return nil, p, fmt.Errorf("agent %s: tools: %w", name, err)
+	}
+	if err := ag.SetMemoryScope(p.MemoryScope); err != nil {
+		ag.Close()
+		return nil, p, fmt.Errorf("agent %s: %w", name, err)
+	}
+	if err := ag.SetApproval(cfg.Approval); err != nil {
+		ag.Close()
+		return nil, p, fmt.Errorf("approval: %w", err)
+	}
+	return ag, p, nil
+}
+
+// memoryScope returns the --scope flag of the memory commands.
+func memoryScope(cmd *cobra.Command) string {
+	scope, _ := cmd.Flags().GetString("scope")
+	return scope
+}
+
+// openSessions loads every stored session from the configured storage. It
+// reports errors on cmd and returns ok=false; otherwise done closes the store.
+func openSessions(cmd *cobra.Command) (sm *session.SessionManager, done func(), ok bool) {
+	cfg, _ := config.LoadConfig()
+	st, err := store.Open(cfg.Storage, resolveWorkspace(cfg))
+	if err != nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), "opening storage failed:", err)
+		return nil, nil, false
+	}
+	sm = session.NewSessionManagerWithStore(st.Sessions)
+	if err := sm.LoadAll(); err != nil {
+		st.Close()
+		fmt.Fprintln(cmd.ErrOrStderr(), "loading sessions failed:", err)
+		return nil, nil, false
+	}
+	return sm, func() { st.Close() }, true
+}
+
+// parseAge parses a period such as "30d", "2w" or any time.ParseDuration
+// value ("12h", "90m").
+func parseAge(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v <= 0 {
+				return 0, fmt.Errorf("invalid period %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid period %q (use e.g. 30d, 2w or 12h)", s)
+	}
+	return d, nil
+}
+
+// formatCost renders an estimated cost, or "-" when no price is configured
+// for any of the models involved.
+func formatCost(t usage.Totals) string {
+	if t.Cost == 0 && !t.Priced {
+		return "-"
+	}
+	return fmt.Sprintf("%.4f", t.Cost)
+}
+
 // parseAllowFrom splits a comma-separated string into a trimmed slice.
 // Returns an empty slice (not nil) if the input is blank.
 func parseAllowFrom(s string) []string {
@@ -669,3 +1183,21 @@ func setupWhatsAppInteractive(cfg config.Config, cfgPath string) {
 
 	fmt.Println("\nWhatsApp setup complete! Run 'picobot gateway' to start.")
 }
+
+// toolActivity reports the tool calls of a CLI turn to w, as chats get them
+// as notifications.
+func toolActivity(w io.Writer) agent.TurnHooks {
+	return agent.TurnHooks{
+		BeforeTool: func(ctx context.Context, tc providers.ToolCall) {
+			args, _ := json.Marshal(tc.Arguments)
+			fmt.Fprintf(w, "🤖 Running: %s %s\n", tc.Name, args)
+		},
+		AfterTool: func(ctx context.Context, tc providers.ToolCall, r tools.CallResult) {
+			if r.Err != nil {
+				fmt.Fprintf(w, "📢 %s failed (%s): %v\n", tc.Name, r.Elapsed, r.Err)
+				return
+			}
+			fmt.Fprintf(w, "📢 %s done (%s)\n", tc.Name, r.Elapsed)
+		},
+	}
+}
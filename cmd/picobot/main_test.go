@@ -6,9 +6,12 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/local/picobot/internal/agent/memory"
 	"github.com/local/picobot/internal/config"
+	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/usage"
 )
 
 func TestMemoryCLI_ReadAppendWriteRecent(t *testing.T) {
@@ -123,6 +126,54 @@ func TestMemoryCLI_Rank(t *testing.T) {
 	}
 }
 
+func TestMemoryCLI_Search(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	if _, _, err := config.Onboard(); err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+	cfg, _ := config.LoadConfig()
+	mem := memory.NewMemoryStoreWithWorkspace(resolveWorkspace(cfg), 100)
+	_ = mem.WriteFile("2026-01-05.md", "booked the dentist for friday\nbought oat milk\n")
+
+	cmd := NewRootCmd()
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"memory", "search", "-q", "dentist"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("search failed: %v", err)
+	}
+	out := buf.String()
+	if !strings.HasPrefix(out, "2026-01-05.md:1 ") || !strings.Contains(out, "booked the dentist for friday") || strings.Contains(out, "oat milk") {
+		t.Fatalf("unexpected search output: %q", out)
+	}
+}
+
+func TestMemoryCLI_Scope(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	if _, _, err := config.Onboard(); err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+	run := func(args ...string) string {
+		cmd := NewRootCmd()
+		buf := &bytes.Buffer{}
+		cmd.SetOut(buf)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		return buf.String()
+	}
+	run("memory", "append", "today", "-c", "scoped note", "--scope", "telegram:7")
+	if out := run("memory", "read", "today"); strings.Contains(out, "scoped note") {
+		t.Fatalf("expected the shared note to exclude scoped memory, got %q", out)
+	}
+	if out := run("memory", "read", "today", "--scope", "telegram:7"); !strings.Contains(out, "scoped note") {
+		t.Fatalf("expected the scoped note, got %q", out)
+	}
+}
+
 func TestAgentCLI_ModelFlag(t *testing.T) {
 	// set HOME to a temp dir so onboard writes to temp
 	tmp := t.TempDir()
@@ -148,3 +199,107 @@ func TestAgentCLI_ModelFlag(t *testing.T) {
 		t.Fatalf("expected stub echo output, got: %q", out)
 	}
 }
+
+func TestUsageCLI_ByChannel(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	if _, _, err := config.Onboard(); err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+	cfgPath, _, _ := config.ResolveDefaultPaths()
+	cfg, _ := config.LoadConfig()
+	cfg.Usage.Prices = map[string]config.ModelPrice{"gpt-4o-mini": {Prompt: 1, Completion: 2}}
+	_ = config.SaveConfig(cfg, cfgPath)
+
+	ledger := usage.NewLedger(resolveWorkspace(cfg))
+	_ = ledger.Add(usage.Record{Session: "telegram:1", Channel: "telegram", Sender: "u1", Model: "gpt-4o-mini", PromptTokens: 1000000, CompletionTokens: 500000})
+	_ = ledger.Add(usage.Record{Session: "discord:9", Channel: "discord", Sender: "u2", Model: "local", PromptTokens: 10})
+
+	cmd := NewRootCmd()
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"usage", "--by", "channel"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"CHANNEL", "telegram", "2.0000", "discord", "TOTAL"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in output, got: %q", want, out)
+		}
+	}
+}
+
+func TestSessionsCLI(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	if _, _, err := config.Onboard(); err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+	cfg, _ := config.LoadConfig()
+	sm := session.NewSessionManager(resolveWorkspace(cfg))
+	recent := sm.GetOrCreate("telegram:1")
+	recent.AddMessage("user", "remind me about tea")
+	recent.AddMessage("assistant", "Sure.")
+	old := sm.GetOrCreate("discord:9")
+	old.Add(session.Message{Role: "user", Content: "hello", Time: time.Now().AddDate(0, -2, 0)})
+	legacy := sm.GetOrCreate("slack:C1")
+	legacy.Add(session.Message{Role: "user", Content: "no timestamp", Time: time.Now()})
+	for _, s := range []*session.Session{recent, old, legacy} {
+		if err := sm.Save(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	legacy.Messages[0].Time = time.Time{}
+	_ = sm.Save(legacy)
+
+	run := func(args ...string) string {
+		cmd := NewRootCmd()
+		buf := &bytes.Buffer{}
+		cmd.SetOut(buf)
+		cmd.SetErr(buf)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v failed: %v", args, err)
+		}
+		return buf.String()
+	}
+
+	out := run("sessions", "list")
+	for _, want := range []string{"KEY", "telegram:1", "discord:9", "slack:C1"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in list output, got: %q", want, out)
+		}
+	}
+	if out := run("sessions", "show", "telegram:1"); !strings.Contains(out, "remind me about tea") {
+		t.Fatalf("unexpected show output: %q", out)
+	}
+	if out := run("sessions", "export", "telegram:1", "--format", "json"); !strings.Contains(out, `"key": "telegram:1"`) {
+		t.Fatalf("unexpected export output: %q", out)
+	}
+
+	out = run("sessions", "prune", "--older-than", "30d")
+	if !strings.Contains(out, "discord:9") || !strings.Contains(out, "Deleted 1 session(s)") || !strings.Contains(out, "Kept 1 session(s) without timestamps") {
+		t.Fatalf("unexpected prune output: %q", out)
+	}
+	if out := run("sessions", "delete", "telegram:1"); !strings.Contains(out, "Deleted telegram:1") {
+		t.Fatalf("unexpected delete output: %q", out)
+	}
+	out = run("sessions", "list")
+	if strings.Contains(out, "telegram:1") || strings.Contains(out, "discord:9") || !strings.Contains(out, "slack:C1") {
+		t.Fatalf("expected only slack:C1 to remain, got: %q", out)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	for in, want := range map[string]time.Duration{"30d": 30 * 24 * time.Hour, "2w": 14 * 24 * time.Hour, "12h": 12 * time.Hour} {
+		if got, err := parseAge(in); err != nil || got != want {
+			t.Fatalf("parseAge(%q) = %v, %v; want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "xd", "-1d", "soon"} {
+		if _, err := parseAge(in); err == nil {
+			t.Fatalf("parseAge(%q): expected an error", in)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+//go:build !lite
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/local/picobot/internal/config"
+	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/usage"
+)
+
+func TestMigrateCLI_ImportsIntoSQLite(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("HOME", tmp)
+	if _, _, err := config.Onboard(); err != nil {
+		t.Fatalf("onboard failed: %v", err)
+	}
+	cfgPath, _, _ := config.ResolveDefaultPaths()
+	cfg, _ := config.LoadConfig()
+	ws := resolveWorkspace(cfg)
+
+	sess := &session.Session{Key: "telegram:1"}
+	sess.AddMessage("user", "hi")
+	_ = session.NewFileStore(ws).SaveSession(sess)
+	_ = usage.NewLedger(ws).Add(usage.Record{Session: "telegram:1", Channel: "telegram", Sender: "u1", Model: "m", PromptTokens: 10})
+
+	cmd := NewRootCmd()
+	buf := &bytes.Buffer{}
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"migrate"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "Imported 1 session(s) and 1 usage record(s)") {
+		t.Fatalf("unexpected migrate output: %q", out)
+	}
+
+	// with the sqlite backend selected, reports read from the database
+	cfg.Storage.Backend = "sqlite"
+	_ = config.SaveConfig(cfg, cfgPath)
+	cmd = NewRootCmd()
+	buf.Reset()
+	cmd.SetOut(buf)
+	cmd.SetArgs([]string{"usage", "--by", "sender"})
+	if err := cmd.Execute(); err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "telegram:u1") {
+		t.Fatalf("expected migrated usage in report, got: %q", out)
+	}
+}
@@ -0,0 +1,220 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local/picobot/internal/agent/tools"
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/config"
+)
+
+// maxApprovalArgs caps the arguments echoed in an approval request.
+const maxApprovalArgs = 500
+
+// SetApproval makes the tool calls selected by cfg wait for the user's
+// approval, asked in the chat the request came from. Without rules it uses
+// tools.DefaultApprovalRules.
+func (a *AgentLoop) SetApproval(cfg config.ApprovalConfig) error {
+	if !cfg.Enabled {
+		a.tools.SetApprovalPolicy(nil)
+		return nil
+	}
+	rules := tools.DefaultApprovalRules
+	if len(cfg.Rules) > 0 {
+		rules = make([]tools.ApprovalRule, len(cfg.Rules))
+		for i, r := range cfg.Rules {
+			rules[i] = tools.ApprovalRule{Tool: r.Tool, Args: r.Args}
+		}
+	}
+	p, err := tools.NewApprovalPolicy(rules, time.Duration(cfg.TimeoutS)*time.Second)
+	if err != nil {
+		return err
+	}
+	a.tools.SetApprovalPolicy(p)
+	return nil
+}
+
+// pendingApproval is a tool call waiting for an answer in a chat.
+type pendingApproval struct {
+	id      string
+	key     string // channel:chatID the question was asked in
+	sender  string // who may answer; "" lets anyone in the chat answer
+	tool    string
+	created time.Time
+	answer  chan bool // buffered; receives exactly one answer
+}
+
+// approvals tracks the tool calls waiting for an answer.
+type approvals struct {
+	mu      sync.Mutex
+	pending map[string]*pendingApproval // by id
+}
+
+func (ap *approvals) add(p *pendingApproval) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	if ap.pending == nil {
+		ap.pending = make(map[string]*pendingApproval)
+	}
+	ap.pending[p.id] = p
+}
+
+func (ap *approvals) remove(id string) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	delete(ap.pending, id)
+}
+
+// resolve answers the pending approval id in chat key (the oldest one there
+// when id is empty) on behalf of sender and returns the call's tool name.
+// found is false when nothing matching is pending, and allowed is false when
+// sender may not answer it.
+func (ap *approvals) resolve(key, sender, id string, ok bool) (tool string, found, allowed bool) {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	var p *pendingApproval
+	if id != "" {
+		p = ap.pending[id]
+		if p != nil && p.key != key {
+			p = nil
+		}
+	} else {
+		var inChat []*pendingApproval
+		for _, q := range ap.pending {
+			if q.key == key {
+				inChat = append(inChat, q)
+			}
+		}
+		sort.Slice(inChat, func(i, j int) bool { return inChat[i].created.Before(inChat[j].created) })
+		if len(inChat) > 0 {
+			p = inChat[0]
+		}
+	}
+	if p == nil {
+		return "", false, false
+	}
+	if p.sender != "" && p.sender != sender {
+		return p.tool, true, false
+	}
+	delete(ap.pending, p.id)
+	p.answer <- ok
+	return p.tool, true, true
+}
+
+// waiting reports whether sender has a tool call waiting for an answer in
+// chat key.
+func (ap *approvals) waiting(key, sender string) bool {
+	ap.mu.Lock()
+	defer ap.mu.Unlock()
+	for _, p := range ap.pending {
+		if p.key == key && (p.sender == "" || p.sender == sender) {
+			return true
+		}
+	}
+	return false
+}
+
+// chatApprover asks for approval in the chat a message came from.
+type chatApprover struct {
+	a      *AgentLoop
+	msg    chat.Inbound
+	sender string
+}
+
+// approverFor returns the approver for tool calls made while answering msg,
+// or nil when there is no chat to ask (heartbeat).
+func (a *AgentLoop) approverFor(msg chat.Inbound) tools.Approver {
+	if isSystemChannel(msg.Channel) {
+		return nil
+	}
+	sender := msg.SenderID
+	if isSystemSender(sender) {
+		sender = "" // a reminder or sub-agent result has no requester; anyone in the chat may answer
+	}
+	return &chatApprover{a: a, msg: msg, sender: sender}
+}
+
+func (c *chatApprover) Approve(ctx context.Context, req tools.ApprovalRequest) (bool, error) {
+	id, err := randomID()
+	if err != nil {
+		return false, err
+	}
+	p := &pendingApproval{
+		id:      id,
+		key:     c.msg.Channel + ":" + c.msg.ChatID,
+		sender:  c.sender,
+		tool:    req.Tool,
+		created: time.Now(),
+		answer:  make(chan bool, 1),
+	}
+	c.a.approvals.add(p)
+	defer c.a.approvals.remove(p.id)
+
+	args, _ := json.Marshal(req.Args)
+	text := fmt.Sprintf("🔐 Allow %s %s?\nReply /approve or /deny", req.Tool, truncateRunes(string(args), maxApprovalArgs))
+	if dl, ok := ctx.Deadline(); ok {
+		text += fmt.Sprintf(" (denied automatically in %s)", time.Until(dl).Round(time.Second))
+	}
+	c.a.send(chat.Outbound{
+		Channel: c.msg.Channel,
+		ChatID:  c.msg.ChatID,
+		Content: text + ".",
+		Buttons: []chat.Button{
+			{Text: "✅ Approve", Data: "/approve " + p.id},
+			{Text: "❌ Deny", Data: "/deny " + p.id},
+		},
+	})
+
+	select {
+	case ok := <-p.answer:
+		return ok, nil
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+func (a *AgentLoop) cmdApprove(ctx context.Context, msg chat.Inbound, args string) string {
+	return a.answerApproval(msg, strings.TrimSpace(args), true)
+}
+
+func (a *AgentLoop) cmdDeny(ctx context.Context, msg chat.Inbound, args string) string {
+	return a.answerApproval(msg, strings.TrimSpace(args), false)
+}
+
+func (a *AgentLoop) answerApproval(msg chat.Inbound, id string, ok bool) string {
+	tool, found, allowed := a.approvals.resolve(msg.Channel+":"+msg.ChatID, msg.SenderID, id, ok)
+	switch {
+	case !found:
+		return "Nothing is waiting for approval here."
+	case !allowed:
+		return "Only the person who made the request can approve " + tool + "."
+	case ok:
+		return "Approved: running " + tool + "."
+	}
+	return "Denied: " + tool + " will not run."
+}
+
+// approvalReply maps a plain-text answer to a pending approval ("yes", "no")
+// to the /approve or /deny command, so users need not type the command.
+func (a *AgentLoop) approvalReply(msg chat.Inbound) (chat.Inbound, bool) {
+	var cmd string
+	switch strings.ToLower(strings.Trim(strings.TrimSpace(msg.Content), ".!")) {
+	case "yes", "y", "ok", "approve", "approved", "allow":
+		cmd = "/approve"
+	case "no", "n", "deny", "denied", "don't", "stop":
+		cmd = "/deny"
+	default:
+		return msg, false
+	}
+	if isSystemChannel(msg.Channel) || !a.approvals.waiting(msg.Channel+":"+msg.ChatID, msg.SenderID) {
+		return msg, false
+	}
+	msg.Content = cmd
+	return msg, true
+}
@@ -0,0 +1,159 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+)
+
+// maxCommandReply caps command replies that echo stored content (/memory).
+const maxCommandReply = 3500
+
+// registerCommands adds the built-in slash commands to the hub's registry.
+func (a *AgentLoop) registerCommands() {
+	cmds := a.hub.Commands
+	cmds.Register(chat.Command{Name: "help", Description: "List available commands", Immediate: true, Handler: a.cmdHelp})
+	cmds.Register(chat.Command{Name: "reset", Description: "Clear this chat's conversation history", Handler: a.cmdReset})
+	cmds.Register(chat.Command{Name: "model", Usage: "[name|default]", Description: "Show or change the model used in this chat", Handler: a.cmdModel})
+	cmds.Register(chat.Command{Name: "memory", Description: "Show long-term memory and today's notes", Immediate: true, Handler: a.cmdMemory})
+	cmds.Register(chat.Command{Name: "jobs", Description: "List scheduled jobs for this chat", Immediate: true, Handler: a.cmdJobs})
+	cmds.Register(chat.Command{Name: "tools", Description: "List the tools the agent can use", Immediate: true, Handler: a.cmdTools})
+	cmds.Register(chat.Command{Name: "approve", Usage: "[id]", Description: "Allow the tool call waiting for approval", Immediate: true, Handler: a.cmdApprove})
+	cmds.Register(chat.Command{Name: "deny", Usage: "[id]", Description: "Refuse the tool call waiting for approval", Immediate: true, Handler: a.cmdDeny})
+	cmds.Register(chat.Command{Name: "stop", Description: "Stop the reply currently being generated", Immediate: true, Handler: a.cmdStop})
+}
+
+// handleCommand answers msg if it is a slash command and reports whether it
+// did.
+func (a *AgentLoop) handleCommand(ctx context.Context, msg chat.Inbound) bool {
+	reply, ok := a.commandReply(ctx, msg)
+	if reply != "" {
+		a.send(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: reply})
+	}
+	return ok
+}
+
+// commandReply runs msg if it is a slash command and returns the reply, which
+// may be empty. Unknown commands get a hint instead of reaching the LLM.
+func (a *AgentLoop) commandReply(ctx context.Context, msg chat.Inbound) (string, bool) {
+	name, args, ok := chat.Parse(msg.Content)
+	if !ok || isSystemChannel(msg.Channel) {
+		return "", false
+	}
+	if cmd, ok := a.hub.Commands.Lookup(name); ok {
+		return cmd.Handler(ctx, msg, args), true
+	}
+	return fmt.Sprintf("Unknown command /%s. Send /help for the list of commands.", name), true
+}
+
+// isImmediateCommand reports whether msg is a command that must not wait for
+// the chat's running turn.
+func (a *AgentLoop) isImmediateCommand(msg chat.Inbound) bool {
+	name, _, ok := chat.Parse(msg.Content)
+	if !ok || isSystemChannel(msg.Channel) {
+		return false
+	}
+	cmd, ok := a.hub.Commands.Lookup(name)
+	return ok && cmd.Immediate
+}
+
+func (a *AgentLoop) cmdHelp(ctx context.Context, msg chat.Inbound, args string) string {
+	var sb strings.Builder
+	sb.WriteString("Commands:")
+	for _, c := range a.hub.Commands.List() {
+		sb.WriteString("\n/" + c.Name)
+		if c.Usage != "" {
+			sb.WriteString(" " + c.Usage)
+		}
+		sb.WriteString(" - " + c.Description)
+	}
+	return sb.String()
+}
+
+func (a *AgentLoop) cmdReset(ctx context.Context, msg chat.Inbound, args string) string {
+	sess := a.sessions.GetOrCreate(msg.Channel + ":" + msg.ChatID)
+	sess.Clear()
+	if err := a.sessions.Save(sess); err != nil {
+		return fmt.Sprintf("Could not reset the conversation: %v", err)
+	}
+	return "Conversation cleared."
+}
+
+func (a *AgentLoop) cmdModel(ctx context.Context, msg chat.Inbound, args string) string {
+	key := msg.Channel + ":" + msg.ChatID
+	switch args {
+	case "":
+		return "Model for this chat: " + a.modelFor(key)
+	case "default":
+		a.setModel(key, "")
+		return "Model for this chat reset to " + a.model + "."
+	}
+	if strings.ContainsAny(args, " \t\n") {
+		return "Usage: /model [name|default]"
+	}
+	a.setModel(key, args)
+	return "Model for this chat set to " + args + "."
+}
+
+func (a *AgentLoop) cmdMemory(ctx context.Context, msg chat.Inbound, args string) string {
+	memCtx, err := a.memoryFor(msg).GetMemoryContext()
+	if err != nil {
+		return fmt.Sprintf("Could not read memory: %v", err)
+	}
+	memCtx = strings.TrimSpace(memCtx)
+	if memCtx == "" {
+		return "No memories stored yet."
+	}
+	if r := []rune(memCtx); len(r) > maxCommandReply {
+		memCtx = string(r[:maxCommandReply]) + "\n... (truncated)"
+	}
+	return memCtx
+}
+
+func (a *AgentLoop) cmdJobs(ctx context.Context, msg chat.Inbound, args string) string {
+	if a.scheduler == nil {
+		return "Scheduling is not available."
+	}
+	var jobs []string
+	list := a.scheduler.List()
+	sort.Slice(list, func(i, j int) bool { return list[i].FireAt.Before(list[j].FireAt) })
+	for _, j := range list {
+		if j.Channel != msg.Channel || j.ChatID != msg.ChatID {
+			continue
+		}
+		line := fmt.Sprintf("- %s (%s): %q in %s", j.Name, j.ID, j.Message, time.Until(j.FireAt).Round(time.Second))
+		if j.Recurring {
+			line += fmt.Sprintf(", every %s", j.Interval)
+		}
+		jobs = append(jobs, line)
+	}
+	if len(jobs) == 0 {
+		return "No scheduled jobs for this chat."
+	}
+	return "Scheduled jobs:\n" + strings.Join(jobs, "\n")
+}
+
+func (a *AgentLoop) cmdTools(ctx context.Context, msg chat.Inbound, args string) string {
+	defs := a.tools.Definitions()
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	var sb strings.Builder
+	sb.WriteString("Tools:")
+	for _, d := range defs {
+		sb.WriteString("\n- " + d.Name + ": " + d.Description)
+	}
+	return sb.String()
+}
+
+func (a *AgentLoop) cmdStop(ctx context.Context, msg chat.Inbound, args string) string {
+	if a.cancelTurn(msg.Channel + ":" + msg.ChatID) {
+		return "Stopped."
+	}
+	if _, ok := msg.Metadata["reaction"]; ok {
+		return "" // a stray stop reaction needs no reply
+	}
+	return "Nothing to stop."
+}
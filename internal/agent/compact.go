@@ -0,0 +1,143 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
+)
+
+// charsPerToken is the rough number of characters per token used to estimate
+// prompt size without a tokenizer.
+const charsPerToken = 4
+
+// keepRecentMessages is the number of most recent history messages that are
+// never compacted, so the model always sees the latest exchanges verbatim.
+const keepRecentMessages = 6
+
+// maxCompactionRounds bounds the summarisation calls made for one turn.
+const maxCompactionRounds = 3
+
+const summaryPrompt = `You maintain a running summary of a conversation between a user and an assistant.
+Merge the previous summary (if any) and the new messages into one concise summary of at most 300 words.
+Keep facts, decisions, open tasks, names and the user's preferences; drop small talk.
+Reply with the summary only.`
+
+// truncateRunes shortens s to at most n runes, marking the cut.
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
+
+// estimateTokens approximates the token count of a prompt.
+func estimateTokens(messages []providers.Message) int {
+	n := 0
+	for _, m := range messages {
+		n += len(m.Content)
+		for _, tc := range m.ToolCalls {
+			n += len(tc.Name) + len(fmt.Sprint(tc.Arguments))
+		}
+	}
+	return n / charsPerToken
+}
+
+// SetContextBudget enables compaction: when the estimated prompt exceeds
+// tokens, or the history reaches session.MaxHistorySize messages, the oldest
+// turns are summarised with the LLM into the session summary. Sessions then
+// keep every message on save, so that turns with many tool calls never push
+// history out before it is summarised. 0 disables compaction.
+func (a *AgentLoop) SetContextBudget(tokens int) {
+	a.contextBudget = tokens
+	a.sessions.SetMaxHistory(a.historyLimit())
+}
+
+// historyLimit is the number of messages sessions keep on save: all of them
+// with compaction on, since it bounds the history itself.
+func (a *AgentLoop) historyLimit() int {
+	if a.contextBudget > 0 {
+		return 0
+	}
+	return session.MaxHistorySize
+}
+
+// compact returns the prompt produced by build, first folding the oldest
+// history of sess into its summary when the prompt is over budget.
+func (a *AgentLoop) compact(ctx context.Context, sess *session.Session, msg chat.Inbound, model string, build func() []providers.Message) []providers.Message {
+	messages := build()
+	if a.contextBudget <= 0 || isSystemChannel(msg.Channel) {
+		return messages
+	}
+	for round := 0; round < maxCompactionRounds; round++ {
+		over := estimateTokens(messages) > a.contextBudget
+		full := len(sess.Messages) >= session.MaxHistorySize
+		if (!over && !full) || len(sess.Messages) <= keepRecentMessages {
+			break
+		}
+		cut := len(sess.Messages) / 2
+		if limit := len(sess.Messages) - keepRecentMessages; cut > limit {
+			cut = limit
+		}
+		// never start the kept history with orphaned tool results
+		cut = session.TrimStart(sess.Messages, cut)
+		summary, err := a.summarise(ctx, msg, model, sess.Summary, sess.Messages[:cut])
+		if err != nil {
+			log.Printf("compaction of %s failed: %v", sess.Key, err)
+			break
+		}
+		sess.Summary = summary
+		sess.Messages = append([]session.Message(nil), sess.Messages[cut:]...)
+		if err := a.sessions.Save(sess); err != nil {
+			log.Printf("error saving session: %v", err)
+		}
+		log.Printf("compacted %d history entries of %s into the summary", cut, sess.Key)
+		messages = build()
+	}
+	return messages
+}
+
+// maxSummarisedToolResult caps each tool result sent for summarisation.
+const maxSummarisedToolResult = 500
+
+// summarise asks the model to merge history into the previous summary.
+func (a *AgentLoop) summarise(ctx context.Context, msg chat.Inbound, model, previous string, history []session.Message) (string, error) {
+	var sb strings.Builder
+	if previous != "" {
+		sb.WriteString("Previous summary:\n" + previous + "\n\n")
+	}
+	sb.WriteString("New messages:")
+	for _, m := range history {
+		switch {
+		case m.Role == "tool":
+			fmt.Fprintf(&sb, "\ntool result: %s", truncateRunes(m.Content, maxSummarisedToolResult))
+		case len(m.ToolCalls) > 0:
+			for _, tc := range m.ToolCalls {
+				fmt.Fprintf(&sb, "\nassistant called %s %v", tc.Name, tc.Arguments)
+			}
+			if m.Content != "" {
+				fmt.Fprintf(&sb, "\nassistant: %s", m.Content)
+			}
+		default:
+			fmt.Fprintf(&sb, "\n%s: %s", m.Role, m.Content)
+		}
+	}
+	resp, err := a.provider.Chat(ctx, []providers.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: sb.String()},
+	}, nil, model)
+	if err != nil {
+		return "", err
+	}
+	a.recordUsage(msg, resp.Usage, model)
+	summary := strings.TrimSpace(resp.Content)
+	if summary == "" {
+		return "", fmt.Errorf("empty summary")
+	}
+	return summary, nil
+}
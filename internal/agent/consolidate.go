@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/config"
+)
+
+// consolidateTimeout bounds one consolidation run.
+const consolidateTimeout = 5 * time.Minute
+
+// ScheduleConsolidation registers the daily memory consolidation on the
+// scheduler: every day at cfg.At the last cfg.Days of notes are merged into
+// MEMORY.md. It does nothing when consolidation is disabled or the loop has no
+// scheduler.
+func (a *AgentLoop) ScheduleConsolidation(cfg config.ConsolidationConfig) error {
+	if !cfg.Enabled || a.scheduler == nil {
+		return nil
+	}
+	first, err := nextDailyRun(cfg.At, time.Now())
+	if err != nil {
+		return err
+	}
+	opts := memory.ConsolidateOptions{Days: cfg.Days, Archive: cfg.Archive}
+	a.scheduler.AddFunc("memory consolidation", first, 24*time.Hour, func() {
+		ctx, cancel := context.WithTimeout(context.Background(), consolidateTimeout)
+		defer cancel()
+		a.ConsolidateMemory(ctx, opts)
+	})
+	return nil
+}
+
+// ConsolidateMemory runs one consolidation of the shared tier and of every
+// memory scope with the loop's provider and model, recording token usage and
+// logging the outcome. It returns the first error after trying every scope.
+func (a *AgentLoop) ConsolidateMemory(ctx context.Context, opts memory.ConsolidateOptions) error {
+	stores := []*memory.MemoryStore{a.memory}
+	ids, err := a.memory.Scopes()
+	if err != nil {
+		log.Printf("memory consolidation: listing scopes: %v", err)
+	}
+	for _, id := range ids {
+		stores = append(stores, a.memory.Scope(id))
+	}
+
+	var firstErr error
+	for _, mem := range stores {
+		name := mem.ScopeID()
+		if name == "" {
+			name = "shared"
+		}
+		rep, err := memory.Consolidate(ctx, mem, a.provider, a.model, opts)
+		if rep != nil {
+			a.recordUsage(chat.Inbound{Channel: "cron", ChatID: "consolidate", SenderID: "system"}, rep.Usage, a.model)
+		}
+		if err != nil {
+			log.Printf("memory consolidation (%s) failed: %v", name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		log.Printf("memory consolidation (%s): %d added, %d updated, %d already known, %d day(s) archived",
+			name, len(rep.Added), len(rep.Updated), rep.Skipped, len(rep.Archived))
+	}
+	return firstErr
+}
+
+// nextDailyRun returns the next time after now at the local clock time at
+// ("HH:MM", default "03:00").
+func nextDailyRun(at string, now time.Time) (time.Time, error) {
+	if at == "" {
+		at = "03:00"
+	}
+	t, err := time.Parse("15:04", at)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid consolidation time %q (want HH:MM)", at)
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
@@ -0,0 +1,19 @@
+package agent
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextDailyRun(t *testing.T) {
+	now := time.Date(2026, 3, 10, 2, 30, 0, 0, time.Local)
+	if got, _ := nextDailyRun("", now); !got.Equal(time.Date(2026, 3, 10, 3, 0, 0, 0, time.Local)) {
+		t.Fatalf("expected 03:00 the same day, got %v", got)
+	}
+	if got, _ := nextDailyRun("01:15", now); !got.Equal(time.Date(2026, 3, 11, 1, 15, 0, 0, time.Local)) {
+		t.Fatalf("expected 01:15 the next day, got %v", got)
+	}
+	if _, err := nextDailyRun("25:00", now); err == nil {
+		t.Fatal("expected an error for an invalid time")
+	}
+}
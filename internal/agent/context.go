@@ -10,8 +10,13 @@ import (
 	"github.com/local/picobot/internal/agent/memory"
 	"github.com/local/picobot/internal/agent/skills"
 	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
 )
 
+// factSheetSize is how many facts (most recently confirmed first) the prompt
+// lists; older ones stay reachable through query_facts.
+const factSheetSize = 40
+
 // ContextBuilder builds messages for the LLM from session history and current message.
 type ContextBuilder struct {
 	workspace    string
@@ -29,7 +34,22 @@ func NewContextBuilder(workspace string, r memory.Ranker, topK int) *ContextBuil
 	}
 }
 
-func (cb *ContextBuilder) BuildMessages(history []string, currentMessage string, channel, chatID string, memoryContext string, memories []memory.MemoryItem) []providers.Message {
+// withMemory returns cb with its ranker narrowed to mem's scope, for rankers
+// that search memory files themselves.
+func (cb *ContextBuilder) withMemory(mem *memory.MemoryStore) *ContextBuilder {
+	er, ok := cb.ranker.(*memory.EmbeddingRanker)
+	if !ok {
+		return cb
+	}
+	c := *cb
+	c.ranker = er.For(mem)
+	return &c
+}
+
+// BuildMessages assembles the prompt. summary is the rolling summary of turns
+// that were compacted out of history and facts the rendered fact sheet (see
+// MemoryStore.FactSheet); both are added to the system message.
+func (cb *ContextBuilder) BuildMessages(history []session.Message, summary string, currentMessage string, channel, chatID string, memoryContext string, facts string, memories []memory.MemoryItem) []providers.Message {
 	msgs := make([]providers.Message, 0, len(history)+2)
 
 	// Combine all system instructions into one message at position 0 to avoid errors in strict chat templates (e.g. llama.cpp)
@@ -57,7 +77,7 @@ func (cb *ContextBuilder) BuildMessages(history []string, currentMessage string,
 		channel, chatID))
 
 	// Memory tool instruction
-	sysParts = append(sysParts, "If you decide something should be remembered, call the tool 'write_memory' with JSON arguments: {\"target\": \"today\"|\"long\", \"content\": \"...\", \"append\": true|false}. Use a tool call rather than plain chat text when writing memory.")
+	sysParts = append(sysParts, "If you decide something should be remembered, call the tool 'write_memory' with JSON arguments: {\"target\": \"today\"|\"long\", \"content\": \"...\", \"append\": true|false}. Use a tool call rather than plain chat text when writing memory. For stable facts about the user, people or things (e.g. the user's city), call 'upsert_fact' instead, so a changed fact replaces the old value rather than contradicting it.")
 
 	// Skills context
 	loadedSkills, err := cb.skillsLoader.LoadAll()
@@ -78,9 +98,17 @@ func (cb *ContextBuilder) BuildMessages(history []string, currentMessage string,
 		sysParts = append(sysParts, "Memory:\n"+memoryContext)
 	}
 
+	// Structured facts
+	if facts != "" {
+		sysParts = append(sysParts, "Known facts (subject: predicate = value):\n"+facts)
+	}
+
 	// Top-K ranked memories
+	// (the embedding ranker also searches its file index, so it runs even
+	// without recent items)
 	selected := memories
-	if cb.ranker != nil && len(memories) > 0 {
+	_, indexed := cb.ranker.(*memory.EmbeddingRanker)
+	if cb.ranker != nil && (len(memories) > 0 || indexed) {
 		selected = cb.ranker.Rank(currentMessage, memories, cb.topK)
 	}
 	if len(selected) > 0 {
@@ -92,25 +120,17 @@ func (cb *ContextBuilder) BuildMessages(history []string, currentMessage string,
 		sysParts = append(sysParts, sb.String())
 	}
 
+	// Summary of older turns that no longer fit in the history
+	if summary != "" {
+		sysParts = append(sysParts, "Summary of the earlier conversation:\n"+summary)
+	}
+
 	// Emit the single consolidated system message
 	msgs = append(msgs, providers.Message{Role: "system", Content: strings.Join(sysParts, "\n\n")})
 
-	// Replay history, preserving each message's original role (user/assistant).
-	// Items are stored in "role: content" format by session.AddMessage.
-	for _, h := range history {
-		if len(h) == 0 {
-			continue
-		}
-		role := "user"
-		content := h
-		if idx := strings.Index(h, ": "); idx > 0 {
-			r := h[:idx]
-			if r == "user" || r == "assistant" {
-				role = r
-				content = h[idx+2:]
-			}
-		}
-		msgs = append(msgs, providers.Message{Role: role, Content: content})
+	// Replay history, including earlier tool calls and their results.
+	for _, h := range history[session.TrimStart(history, 0):] {
+		msgs = append(msgs, providers.Message{Role: h.Role, Content: h.Content, ToolCalls: h.ToolCalls, ToolCallID: h.ToolCallID})
 	}
 
 	// Current user message
@@ -3,11 +3,16 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/local/picobot/internal/agent/memory"
@@ -16,12 +21,23 @@ import (
 	"github.com/local/picobot/internal/config"
 	"github.com/local/picobot/internal/cron"
 	"github.com/local/picobot/internal/mcp"
+	"github.com/local/picobot/internal/media"
 	"github.com/local/picobot/internal/providers"
 	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/store"
+	"github.com/local/picobot/internal/usage"
 )
 
 var rememberRE = regexp.MustCompile(`(?i)^remember(?:\s+to)?\s+(.+)$`)
 
+// streamUpdateInterval throttles progressive updates of a streamed reply so
+// that channels stay well within their message-edit rate limits.
+const streamUpdateInterval = 750 * time.Millisecond
+
+// defaultConcurrency is the number of sessions processed in parallel when
+// SetConcurrency is not called.
+const defaultConcurrency = 4
+
 // sendChannelNotification delivers a non-blocking status message back to the
 // originating channel so the user can see tool progress in real time.
 // It is a no-op for system channels (heartbeat, cron) that have no user-facing chat.
@@ -52,18 +68,46 @@ func isSystemChannel(channel string) bool {
 	}
 }
 
+// isSystemSender reports whether sender is picobot itself rather than a
+// person: cron reminders and sub-agent results are posted to a chat under
+// these senders.
+func isSystemSender(sender string) bool {
+	return sender == "cron" || sender == subAgentSender
+}
+
 // AgentLoop is the core processing loop; it holds an LLM provider, tools, sessions and context builder.
 type AgentLoop struct {
-	hub           *chat.Hub
-	provider      providers.LLMProvider
-	tools         *tools.Registry
-	sessions      *session.SessionManager
-	context       *ContextBuilder
-	memory        *memory.MemoryStore
-	model         string
-	maxIterations int
-	running       bool
-	mcpClients    []*mcp.Client
+	name            string // agent profile, set by Router.Add
+	hub             *chat.Hub
+	provider        providers.LLMProvider
+	tools           *tools.Registry
+	sessions        *session.SessionManager
+	context         *ContextBuilder
+	memory          *memory.MemoryStore
+	memoryScope     string // memory.ScopeGlobal, ScopeSender or ScopeChat
+	workspace       string
+	scheduler       *cron.Scheduler
+	usage           usage.Store
+	model           string
+	maxIterations   int
+	streaming       bool
+	concurrency     int
+	toolConcurrency int // tool calls of one response run at once; 0 = tools.DefaultParallelCalls
+	contextBudget   int // estimated prompt tokens that trigger compaction; 0 = off
+	running         bool
+	mcpClients      []*mcp.Client
+
+	queueMu sync.Mutex
+	queues  map[string][]chat.Inbound // pending messages per session key
+
+	modelMu sync.Mutex
+	models  map[string]string // per-session model overrides set with /model
+
+	turnMu sync.Mutex
+	turns  map[string]context.CancelFunc // running turn per session key, for /stop
+
+	approvals approvals  // tool calls waiting for /approve or /deny
+	subagents *subAgents // background tasks started with the spawn tool
 }
 
 // NewAgentLoop creates a new AgentLoop with the given provider.
@@ -93,20 +137,26 @@ func NewAgentLoop(b *chat.Hub, provider providers.LLMProvider, model string, max
 	reg.Register(tools.NewExecTool(60))
 	reg.Register(tools.NewWebTool())
 	reg.Register(tools.NewWebSearchTool())
-	reg.Register(tools.NewSpawnTool())
 	if scheduler != nil {
 		reg.Register(tools.NewCronTool(scheduler))
 	}
 
 	sm := session.NewSessionManager(workspace)
+	if err := sm.LoadAll(); err != nil {
+		log.Printf("error loading sessions: %v", err)
+	}
 	ctx := NewContextBuilder(workspace, memory.NewLLMRanker(provider, model), 5)
 	mem := memory.NewMemoryStoreWithWorkspace(workspace, 100)
 	// register memory tools (all share the same store instance)
 	reg.Register(tools.NewWriteMemoryTool(mem))
 	reg.Register(tools.NewListMemoryTool(mem))
 	reg.Register(tools.NewReadMemoryTool(mem))
+	reg.Register(tools.NewSearchMemoryTool(mem))
 	reg.Register(tools.NewEditMemoryTool(mem))
 	reg.Register(tools.NewDeleteMemoryTool(mem))
+	reg.Register(tools.NewUpsertFactTool(mem))
+	reg.Register(tools.NewQueryFactsTool(mem))
+	reg.Register(tools.NewRetractFactTool(mem))
 
 	// register skill management tools (share the same os.Root)
 	skillMgr := tools.NewSkillManager(root)
@@ -140,21 +190,290 @@ func NewAgentLoop(b *chat.Hub, provider providers.LLMProvider, model string, max
 		log.Printf("MCP server %q: registered %d tools", name, len(client.Tools()))
 	}
 
-	return &AgentLoop{hub: b, provider: provider, tools: reg, sessions: sm, context: ctx, memory: mem, model: model, maxIterations: maxIterations, mcpClients: mcpClients}
+	a := &AgentLoop{hub: b, provider: provider, tools: reg, sessions: sm, context: ctx, memory: mem, workspace: workspace, scheduler: scheduler, usage: usage.NewLedger(workspace), model: model, maxIterations: maxIterations, mcpClients: mcpClients}
+	a.subagents = &subAgents{a: a}
+	reg.Register(tools.NewSpawnTool(a.subagents))
+	a.registerCommands()
+	return a
+}
+
+// SetConcurrency sets how many sessions are processed in parallel. Values
+// below 1 select the default. It must be called before Run.
+func (a *AgentLoop) SetConcurrency(n int) {
+	a.concurrency = n
+}
+
+// SetToolConcurrency sets how many tool calls of one model response run at
+// once. 1 runs them one after another; values below 1 select the default.
+func (a *AgentLoop) SetToolConcurrency(n int) {
+	a.toolConcurrency = n
+}
+
+// SetTools limits the agent to the named tools; a name ending in "*" (e.g.
+// "mcp_github_*") selects every tool with that prefix. Names of tools that
+// are not registered (e.g. cron without a scheduler) are skipped. nil keeps
+// all tools. It must be called before Run.
+func (a *AgentLoop) SetTools(names []string) error {
+	if names == nil {
+		return nil
+	}
+	var selected []string
+	for _, name := range names {
+		prefix, ok := strings.CutSuffix(name, "*")
+		if !ok {
+			if a.tools.Get(name) == nil {
+				log.Printf("tool %q is not available, skipping it", name)
+				continue
+			}
+			selected = append(selected, name)
+			continue
+		}
+		for _, d := range a.tools.Definitions() {
+			if strings.HasPrefix(d.Name, prefix) {
+				selected = append(selected, d.Name)
+			}
+		}
+	}
+	reg, err := a.tools.Subset(selected)
+	if err != nil {
+		return err
+	}
+	a.tools = reg
+	return nil
+}
+
+// SetStreaming enables progressive delivery of replies on interactive channels
+// when the provider implements providers.StreamingProvider.
+func (a *AgentLoop) SetStreaming(enabled bool) {
+	a.streaming = enabled
+}
+
+// SetEmbedder switches memory ranking to embedding similarity, searching a
+// vector index over every memory file kept at workspace/index/memory.json.
+// A nil embedder leaves the default ranker in place.
+func (a *AgentLoop) SetEmbedder(e providers.Embedder) {
+	if e == nil {
+		return
+	}
+	a.context.ranker = memory.NewEmbeddingRanker(e, a.memory, filepath.Join(a.workspace, "index", "memory.json"))
+}
+
+// SetMemoryScope partitions memory by sender or chat (memory.ScopeSender,
+// memory.ScopeChat) on top of the shared tier, or keeps one shared memory for
+// everyone (memory.ScopeGlobal or "").
+func (a *AgentLoop) SetMemoryScope(mode string) error {
+	if !memory.ValidScopeMode(mode) {
+		return fmt.Errorf("unknown memory scope %q (want global, sender or chat)", mode)
+	}
+	a.memoryScope = mode
+	return nil
+}
+
+// memoryFor returns the memory store of the person or chat msg comes from.
+// System triggers (heartbeat, and reminders and sub-agent results, see
+// isSystemSender) and the local CLI use the shared tier.
+func (a *AgentLoop) memoryFor(msg chat.Inbound) *memory.MemoryStore {
+	if isSystemChannel(msg.Channel) || isSystemSender(msg.SenderID) || msg.Channel == "cli" {
+		return a.memory
+	}
+	return a.memory.Scope(memory.ScopeID(a.memoryScope, msg.Channel, msg.SenderID, msg.ChatID))
+}
+
+// SetStore switches sessions, memory items and usage records to st, loading
+// what it already holds. It must be called before Run.
+func (a *AgentLoop) SetStore(st *store.Store) error {
+	sm := session.NewSessionManagerWithStore(st.Sessions)
+	sm.SetMaxHistory(a.historyLimit())
+	if err := sm.LoadAll(); err != nil {
+		return fmt.Errorf("loading sessions: %w", err)
+	}
+	if st.Memory != nil {
+		if err := a.memory.UseItemStore(st.Memory); err != nil {
+			return fmt.Errorf("loading memory items: %w", err)
+		}
+	}
+	a.sessions = sm
+	a.usage = st.Usage
+	return nil
+}
+
+// modelFor returns the model used for a session: its /model override or the
+// default model.
+func (a *AgentLoop) modelFor(key string) string {
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	if m := a.models[key]; m != "" {
+		return m
+	}
+	return a.model
 }
 
-// Close shuts down all MCP server connections.
+// setModel sets the model override of a session; an empty model removes it.
+func (a *AgentLoop) setModel(key, model string) {
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	if model == "" {
+		delete(a.models, key)
+		return
+	}
+	if a.models == nil {
+		a.models = make(map[string]string)
+	}
+	a.models[key] = model
+}
+
+// startTurn derives a cancellable context for a session's turn so that /stop
+// can abort it. The returned function must be called when the turn ends.
+func (a *AgentLoop) startTurn(ctx context.Context, key string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	a.turnMu.Lock()
+	if a.turns == nil {
+		a.turns = make(map[string]context.CancelFunc)
+	}
+	a.turns[key] = cancel
+	a.turnMu.Unlock()
+	return ctx, func() {
+		a.turnMu.Lock()
+		delete(a.turns, key)
+		a.turnMu.Unlock()
+		cancel()
+	}
+}
+
+// cancelTurn cancels the running turn of a session and reports whether there
+// was one.
+func (a *AgentLoop) cancelTurn(key string) bool {
+	a.turnMu.Lock()
+	defer a.turnMu.Unlock()
+	cancel, ok := a.turns[key]
+	if ok {
+		cancel()
+		delete(a.turns, key)
+	}
+	return ok
+}
+
+// hasTurn reports whether a turn of the session is running.
+func (a *AgentLoop) hasTurn(key string) bool {
+	a.turnMu.Lock()
+	defer a.turnMu.Unlock()
+	_, ok := a.turns[key]
+	return ok
+}
+
+// send delivers an outbound message without blocking the caller.
+func (a *AgentLoop) send(out chat.Outbound) {
+	select {
+	case a.hub.Out <- out:
+	default:
+		log.Println("Outbound channel full, dropping message")
+	}
+}
+
+// chat calls the provider for one iteration of a turn. When streaming is
+// enabled it forwards throttled partial updates tagged with streamID to the
+// originating chat and returns the text streamed so far, which is kept even
+// when the call fails or is cancelled.
+func (a *AgentLoop) chat(ctx context.Context, messages []providers.Message, toolDefs []providers.ToolDefinition, model, channel, chatID, streamID string) (providers.LLMResponse, string, error) {
+	sp, ok := a.provider.(providers.StreamingProvider)
+	if !a.streaming || !ok || isSystemChannel(channel) {
+		resp, err := a.provider.Chat(ctx, messages, toolDefs, model)
+		return resp, "", err
+	}
+
+	var sb strings.Builder
+	var lastUpdate time.Time
+	resp, err := sp.ChatStream(ctx, messages, toolDefs, model, func(delta string) {
+		sb.WriteString(delta)
+		if time.Since(lastUpdate) < streamUpdateInterval {
+			return
+		}
+		lastUpdate = time.Now()
+		out := chat.Outbound{Channel: channel, ChatID: chatID, Content: sb.String(), StreamID: streamID, Partial: true}
+		select {
+		case a.hub.Out <- out:
+		default:
+			// a dropped partial update is harmless; the next one carries the full text
+		}
+	})
+	return resp, strings.TrimSpace(sb.String()), err
+}
+
+// mediaClient downloads images referenced by inbound messages.
+var mediaClient = &http.Client{Timeout: 30 * time.Second}
+
+// attachMedia loads the images referenced by an inbound message and turns the
+// user message m into a multimodal message. Images that cannot be loaded are
+// described in the text so the model can tell the user.
+func attachMedia(ctx context.Context, m *providers.Message, refs []string) {
+	text := m.Content
+	var images []providers.ContentPart
+	for _, ref := range refs {
+		uri, err := media.Load(ctx, mediaClient, ref, media.MaxImageBytes)
+		if err != nil {
+			log.Printf("media: skipping attachment: %v", err)
+			text += fmt.Sprintf("\n[An attached image could not be loaded: %v]", err)
+			continue
+		}
+		images = append(images, providers.ContentPart{Type: "image", ImageURL: uri})
+	}
+	if text == "" && len(images) > 0 {
+		text = "(image)"
+	}
+	m.Content = text
+	if len(images) > 0 {
+		m.Parts = append([]providers.ContentPart{{Type: "text", Text: text}}, images...)
+	}
+}
+
+// recordUsage appends the token usage of one provider call to the ledger.
+// Calls that report no usage (e.g. the stub provider) are not recorded.
+// model is the requested model, used when the provider does not report one.
+func (a *AgentLoop) recordUsage(msg chat.Inbound, u providers.Usage, model string) {
+	if u.PromptTokens == 0 && u.CompletionTokens == 0 {
+		return
+	}
+	if u.Model != "" {
+		model = u.Model
+	}
+	rec := usage.Record{
+		Session:          msg.Channel + ":" + msg.ChatID,
+		Channel:          msg.Channel,
+		Sender:           msg.SenderID,
+		Model:            model,
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	}
+	if err := a.usage.Add(rec); err != nil {
+		log.Printf("error recording usage: %v", err)
+	}
+}
+
+// Close stops running sub-agents and shuts down all MCP server connections.
 func (a *AgentLoop) Close() {
+	a.subagents.cancelAll()
 	for _, c := range a.mcpClients {
 		_ = c.Close()
 	}
 }
 
 // Run starts processing inbound messages. This is a blocking call until context is canceled.
+//
+// Messages are queued per session (channel + chat): each session's messages
+// are handled in order, while different sessions run in parallel on up to
+// SetConcurrency workers. Run waits for in-flight messages before returning.
 func (a *AgentLoop) Run(ctx context.Context) {
 	a.running = true
 	log.Println("Agent loop started")
 
+	workers := a.concurrency
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
 	for a.running {
 		select {
 		case <-ctx.Done():
@@ -167,191 +486,342 @@ func (a *AgentLoop) Run(ctx context.Context) {
 				a.running = false
 				return
 			}
-
-			log.Printf("Processing message from %s:%s\n", msg.Channel, msg.SenderID)
-
-			// Quick heuristic: if user asks the agent to remember something explicitly,
-			// store it in today's note and reply immediately without calling the LLM.
-			trimmed := strings.TrimSpace(msg.Content)
-			rememberRe := rememberRE
-			if matches := rememberRe.FindStringSubmatch(trimmed); len(matches) == 2 {
-				note := matches[1]
-				if err := a.memory.AppendToday(note); err != nil {
-					log.Printf("error appending to memory: %v", err)
-				}
-				out := chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: "OK, I've remembered that."}
-				select {
-				case a.hub.Out <- out:
-				default:
-					log.Println("Outbound channel full, dropping message")
-				}
-				// Only save session for interactive channels, not system triggers.
-				if !isSystemChannel(msg.Channel) {
-					sess := a.sessions.GetOrCreate(msg.Channel + ":" + msg.ChatID)
-					sess.AddMessage("user", msg.Content)
-					sess.AddMessage("assistant", "OK, I've remembered that.")
-					if err := a.sessions.Save(sess); err != nil {
-						log.Printf("error saving session: %v", err)
-					}
-				}
-				continue
-			}
-
-			// Set tool context (so message tool knows channel+chat)
-			if mt := a.tools.Get("message"); mt != nil {
-				if mtool, ok := mt.(interface{ SetContext(string, string) }); ok {
-					mtool.SetContext(msg.Channel, msg.ChatID)
-				}
+			if m, ok := a.approvalReply(msg); ok {
+				msg = m // a plain "yes"/"no" answers the pending approval
 			}
-			if ct := a.tools.Get("cron"); ct != nil {
-				if ctool, ok := ct.(interface{ SetContext(string, string) }); ok {
-					ctool.SetContext(msg.Channel, msg.ChatID)
-				}
+			if a.isImmediateCommand(msg) {
+				// e.g. /stop must not wait behind the turn it is stopping
+				a.handleCommand(ctx, msg)
+				continue
 			}
+			a.enqueue(ctx, msg, sem, &wg)
+		}
+	}
+}
 
-			// Build messages from session, long-term memory, and recent memory.
-			// System channels (heartbeat, cron) get a blank ephemeral session so
-			// their history never accumulates and bloats the context window.
-			var sess *session.Session
-			if isSystemChannel(msg.Channel) {
-				sess = &session.Session{Key: msg.Channel + ":" + msg.ChatID}
-			} else {
-				sess = a.sessions.GetOrCreate(msg.Channel + ":" + msg.ChatID)
-			}
-			// get file-backed memory context (long-term + today)
-			memCtx, _ := a.memory.GetMemoryContext()
-			memories := a.memory.Recent(5)
-			messages := a.context.BuildMessages(sess.GetHistory(), msg.Content, msg.Channel, msg.ChatID, memCtx, memories)
-
-			iteration := 0
-			finalContent := ""
-			lastToolResult := ""
-			toolDefs := a.tools.Definitions()
-			for iteration < a.maxIterations {
-				iteration++
-				resp, err := a.provider.Chat(ctx, messages, toolDefs, a.model)
-				if err != nil {
-					log.Printf("provider error: %v", err)
-					finalContent = "Sorry, I encountered an error while processing your request."
-					break
-				}
-
-				if resp.HasToolCalls {
-					// append assistant message with tool_calls attached
-					messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
-					// execute each tool call and return results with "tool" role
-					for _, tc := range resp.ToolCalls {
-						argsJSON, _ := json.Marshal(tc.Arguments)
-						sendChannelNotification(a.hub, msg.Channel, msg.ChatID,
-							fmt.Sprintf("🤖 Running: %s %s", tc.Name, argsJSON))
-
-						start := time.Now()
-						res, err := a.tools.Execute(ctx, tc.Name, tc.Arguments)
-						elapsed := time.Since(start).Round(time.Millisecond)
-
-						if err != nil {
-							sendChannelNotification(a.hub, msg.Channel, msg.ChatID,
-								fmt.Sprintf("📢 %s failed (%s): %v", tc.Name, elapsed, err))
-							res = "(tool error) " + err.Error()
-						} else {
-							sendChannelNotification(a.hub, msg.Channel, msg.ChatID,
-								fmt.Sprintf("📢 %s done (%s)", tc.Name, elapsed))
-						}
-						lastToolResult = res
-						messages = append(messages, providers.Message{Role: "tool", Content: res, ToolCallID: tc.ID})
-					}
-					// loop again
-					continue
-				} else {
-					finalContent = resp.Content
-					break
-				}
-			}
+// enqueue adds msg to its session's queue and starts a worker for the session
+// if none is running.
+func (a *AgentLoop) enqueue(ctx context.Context, msg chat.Inbound, sem chan struct{}, wg *sync.WaitGroup) {
+	key := msg.Channel + ":" + msg.ChatID
+	a.queueMu.Lock()
+	defer a.queueMu.Unlock()
+	if a.queues == nil {
+		a.queues = make(map[string][]chat.Inbound)
+	}
+	a.queues[key] = append(a.queues[key], msg)
+	if len(a.queues[key]) > 1 {
+		return // the session's worker will pick it up
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		a.drain(ctx, key, sem)
+	}()
+}
 
-			if finalContent == "" && lastToolResult != "" {
-				finalContent = lastToolResult
-			} else if finalContent == "" {
-				finalContent = "I've completed processing but have no response to give."
-			}
+// drain processes the queue of one session until it is empty. The message
+// being processed stays at the head of the queue, so a non-empty queue always
+// has exactly one worker.
+func (a *AgentLoop) drain(ctx context.Context, key string, sem chan struct{}) {
+	for {
+		a.queueMu.Lock()
+		msg := a.queues[key][0]
+		a.queueMu.Unlock()
 
-			// Save session for interactive channels only.
-			// System channels (heartbeat, cron) are stateless triggers — their
-			// history must not be persisted, otherwise the file grows unboundedly.
-			if !isSystemChannel(msg.Channel) {
-				sess.AddMessage("user", msg.Content)
-				sess.AddMessage("assistant", finalContent)
-				if err := a.sessions.Save(sess); err != nil {
-					log.Printf("error saving session: %v", err)
-				}
-			}
+		select {
+		case sem <- struct{}{}:
+			a.processMessage(ctx, msg)
+			<-sem
+		case <-ctx.Done():
+		}
 
-			out := chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: finalContent}
-			select {
-			case a.hub.Out <- out:
-			default:
-				log.Println("Outbound channel full, dropping message")
-			}
-		default:
-			// idle tick
-			time.Sleep(100 * time.Millisecond)
+		a.queueMu.Lock()
+		q := a.queues[key][1:]
+		if len(q) == 0 || ctx.Err() != nil {
+			delete(a.queues, key)
+			a.queueMu.Unlock()
+			return
 		}
+		a.queues[key] = q
+		a.queueMu.Unlock()
 	}
 }
 
-// ProcessDirect sends a message directly to the provider and returns the response.
-// It supports tool calling - if the model requests tools, they will be executed.
-func (a *AgentLoop) ProcessDirect(content string, timeout time.Duration) (string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+// processMessage handles one inbound message: it runs the model and tools and
+// sends the reply.
+func (a *AgentLoop) processMessage(ctx context.Context, msg chat.Inbound) {
+	log.Printf("Processing message from %s:%s\n", msg.Channel, msg.SenderID)
+
+	r, ok := a.respond(ctx, msg, turnOptions{
+		stream:   true,
+		approver: a.approverFor(msg), // tool calls that need approval are asked about in this chat
+		hooks:    []TurnHooks{a.notifyHooks(msg)},
+	})
+	if ok {
+		a.send(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: r.content, StreamID: r.streamID})
+	}
+}
 
-	// Set tool context so message/cron tools know the originating channel,
-	// matching what Run() does for hub-based messages.
-	if mt := a.tools.Get("message"); mt != nil {
-		if mtool, ok := mt.(interface{ SetContext(string, string) }); ok {
-			mtool.SetContext("cli", "direct")
-		}
+// turnOptions adapt respond to the caller.
+type turnOptions struct {
+	// ephemeral answers without loading or saving session history, as is
+	// always done for system channels.
+	ephemeral bool
+	// stream streams the reply to the chat when streaming is enabled.
+	stream bool
+	// approver is asked about tool calls that need approval; nil refuses them.
+	approver tools.Approver
+	hooks    []TurnHooks
+}
+
+// reply is respond's answer to a message.
+type reply struct {
+	content  string
+	streamID string // the streamed message content replaces, if any
+	err      error  // the model call failed; content apologises
+}
+
+// respond answers msg: slash commands and the remember shortcut directly,
+// anything else with a turn of the model and tools. It reports false when
+// there is nothing to send, because a command had no reply or the turn was
+// cancelled.
+func (a *AgentLoop) respond(ctx context.Context, msg chat.Inbound, opts turnOptions) (reply, bool) {
+	// Slash commands are answered without calling the LLM.
+	if text, ok := a.commandReply(ctx, msg); ok {
+		return reply{content: text}, text != ""
 	}
-	if ct := a.tools.Get("cron"); ct != nil {
-		if ctool, ok := ct.(interface{ SetContext(string, string) }); ok {
-			ctool.SetContext("cli", "direct")
+
+	key := msg.Channel + ":" + msg.ChatID
+	// System channels (heartbeat, cron) are stateless triggers — their
+	// history must not be persisted, otherwise the file grows unboundedly.
+	ephemeral := opts.ephemeral || isSystemChannel(msg.Channel)
+
+	// Quick heuristic: if user asks the agent to remember something explicitly,
+	// store it in today's note and reply immediately without calling the LLM.
+	trimmed := strings.TrimSpace(msg.Content)
+	mem := a.memoryFor(msg)
+	if matches := rememberRE.FindStringSubmatch(trimmed); len(matches) == 2 {
+		note := matches[1]
+		if err := mem.AppendToday(note); err != nil {
+			log.Printf("error appending to memory: %v", err)
+		}
+		if !ephemeral {
+			sess := a.sessions.GetOrCreate(key)
+			sess.Add(userMessage(msg))
+			sess.AddMessage("assistant", "OK, I've remembered that.")
+			if err := a.sessions.Save(sess); err != nil {
+				log.Printf("error saving session: %v", err)
+			}
 		}
+		return reply{content: "OK, I've remembered that."}, true
 	}
 
-	// Build full context (bootstrap files, skills, memory) just like the main loop
-	memCtx, _ := a.memory.GetMemoryContext()
+	runCtx := ctx
+	ctx, endTurn := a.startTurn(ctx, key)
+	defer endTurn()
+	model := a.modelFor(key)
+
+	// message/cron tools read the originating chat from the context
+	ctx = tools.WithOrigin(ctx, msg.Channel, msg.ChatID)
+	// reminders it schedules come back to this profile
+	ctx = tools.WithAgent(ctx, a.name)
+	// memory tools act on the sender's (or chat's) memory
+	ctx = memory.WithScope(ctx, mem.ScopeID())
+	// facts stored during this turn record where they came from
+	ctx = memory.WithSource(ctx, key, msg.Content)
+	// sub-agents started during the turn report back to the sender
+	ctx = withRequest(ctx, msg)
+	if opts.approver != nil {
+		ctx = tools.WithApprover(ctx, opts.approver)
+	}
+
+	// Build messages from session, long-term memory, and recent memory.
+	// Ephemeral answers get a blank session so that no history is used.
+	var sess *session.Session
+	if ephemeral {
+		sess = &session.Session{Key: key}
+	} else {
+		sess = a.sessions.GetOrCreate(key)
+	}
+	// get file-backed memory context (long-term + today)
+	memCtx, _ := mem.GetMemoryContext()
+	facts, err := mem.FactSheet(factSheetSize)
+	if err != nil {
+		log.Printf("error reading facts: %v", err)
+	}
 	memories := a.memory.Recent(5)
-	messages := a.context.BuildMessages(nil, content, "cli", "direct", memCtx, memories)
+	cb := a.context.withMemory(mem)
+	messages := a.compact(ctx, sess, msg, model, func() []providers.Message {
+		return cb.BuildMessages(sess.GetHistory(), sess.Summary, msg.Content, msg.Channel, msg.ChatID, memCtx, facts, memories)
+	})
+	if len(msg.Media) > 0 {
+		attachMedia(ctx, &messages[len(messages)-1], msg.Media)
+	}
 
-	// Support tool calling iterations (similar to main loop)
-	var lastToolResult string
-	for iteration := 0; iteration < a.maxIterations; iteration++ {
-		resp, err := a.provider.Chat(ctx, messages, a.tools.Definitions(), a.model)
-		if err != nil {
-			return "", err
+	t := a.newTurn(msg, model)
+	// partial is the text streamed before the turn was cancelled
+	var partial, finalStreamID string
+	if opts.stream {
+		// streamedText and streamID belong to the latest model call
+		var streamedText, streamID string
+		turnID := strconv.FormatInt(time.Now().UnixNano(), 36)
+		t.Chat = func(ctx context.Context, iteration int, messages []providers.Message, defs []providers.ToolDefinition) (providers.LLMResponse, error) {
+			streamID = fmt.Sprintf("%s-%d", turnID, iteration)
+			resp, text, err := a.chat(ctx, messages, defs, model, msg.Channel, msg.ChatID, streamID)
+			streamedText = text
+			return resp, err
 		}
-
-		if !resp.HasToolCalls {
-			// No tool calls, return the response (fall back to last tool result if empty)
-			if resp.Content != "" {
-				return resp.Content, nil
+		t.Hooks = append(t.Hooks, TurnHooks{AfterLLM: func(ctx context.Context, _ int, resp providers.LLMResponse, err error) {
+			switch {
+			case streamedText == "" || runCtx.Err() != nil:
+			case ctx.Err() != nil:
+				// close the half-streamed message so the channel stops editing it
+				a.send(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: streamedText + "\n\n(stopped)", StreamID: streamID})
+				partial = streamedText
+			case err == nil && resp.HasToolCalls:
+				// finalise the text streamed before the model switched to tool calls
+				a.send(chat.Outbound{Channel: msg.Channel, ChatID: msg.ChatID, Content: resp.Content, StreamID: streamID})
+			default:
+				finalStreamID = streamID // the reply (or error) replaces the partial text
 			}
-			if lastToolResult != "" {
-				return lastToolResult, nil
+		}})
+	}
+	t.Hooks = append(t.Hooks, opts.hooks...)
+	res := t.Run(ctx, messages)
+
+	// turn collects the tool calls and results of this turn for the session
+	turn := make([]session.Message, 0, len(res.Messages))
+	for _, m := range res.Messages {
+		content := m.Content
+		if m.Role == "tool" {
+			content = truncateRunes(content, maxStoredToolResult)
+		}
+		turn = append(turn, session.Message{Role: m.Role, Content: content, ToolCalls: m.ToolCalls, ToolCallID: m.ToolCallID})
+	}
+	if res.Cancelled {
+		if runCtx.Err() == nil {
+			log.Printf("turn for %s cancelled", key)
+			if !ephemeral {
+				a.recordCancelled(sess, msg, turn, partial)
 			}
-			return resp.Content, nil
 		}
+		return reply{}, false
+	}
 
-		// Execute tool calls
-		messages = append(messages, providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls})
-		for _, tc := range resp.ToolCalls {
-			result, err := a.tools.Execute(ctx, tc.Name, tc.Arguments)
-			if err != nil {
-				result = "(tool error) " + err.Error()
-			}
-			lastToolResult = result
-			messages = append(messages, providers.Message{Role: "tool", Content: result, ToolCallID: tc.ID})
+	r := reply{content: res.Content, streamID: finalStreamID}
+	if res.Err != nil {
+		log.Printf("provider error: %v", res.Err)
+		r.content = "Sorry, I encountered an error while processing your request."
+		r.err = res.Err
+	}
+	if !ephemeral {
+		sess.Add(userMessage(msg))
+		for _, m := range turn {
+			sess.Add(m)
 		}
+		sess.AddMessage("assistant", r.content)
+		if err := a.sessions.Save(sess); err != nil {
+			log.Printf("error saving session: %v", err)
+		}
+	}
+	return r, true
+}
+
+// newTurn returns a turn with the loop's provider, tools and limits that
+// records the token usage of its model calls against msg.
+func (a *AgentLoop) newTurn(msg chat.Inbound, model string) *Turn {
+	return &Turn{
+		Provider:        a.provider,
+		Model:           model,
+		Tools:           a.tools,
+		MaxIterations:   a.maxIterations,
+		ToolConcurrency: a.toolConcurrency,
+		Hooks: []TurnHooks{{AfterLLM: func(ctx context.Context, _ int, resp providers.LLMResponse, _ error) {
+			a.recordUsage(msg, resp.Usage, model)
+		}}},
+	}
+}
+
+// notifyHooks report tool activity to the chat msg came from.
+func (a *AgentLoop) notifyHooks(msg chat.Inbound) TurnHooks {
+	return TurnHooks{
+		BeforeTool: func(ctx context.Context, tc providers.ToolCall) {
+			argsJSON, _ := json.Marshal(tc.Arguments)
+			sendChannelNotification(a.hub, msg.Channel, msg.ChatID,
+				fmt.Sprintf("🤖 Running: %s %s", tc.Name, argsJSON))
+		},
+		AfterTool: func(ctx context.Context, tc providers.ToolCall, r tools.CallResult) {
+			switch {
+			case errors.Is(r.Err, tools.ErrNotApproved):
+			case r.Err != nil:
+				sendChannelNotification(a.hub, msg.Channel, msg.ChatID,
+					fmt.Sprintf("📢 %s failed (%s): %v", tc.Name, r.Elapsed, r.Err))
+			default:
+				sendChannelNotification(a.hub, msg.Channel, msg.ChatID,
+					fmt.Sprintf("📢 %s done (%s)", tc.Name, r.Elapsed))
+			}
+		},
+	}
+}
+
+// historyContent returns the text of an inbound message as kept in session
+// history.
+func historyContent(msg chat.Inbound) string {
+	if len(msg.Media) == 0 {
+		return msg.Content
+	}
+	// images are not kept in history; leave a marker so later turns know one was sent
+	return strings.TrimSpace(msg.Content + fmt.Sprintf(" [%d image(s) attached]", len(msg.Media)))
+}
+
+// maxStoredToolResult caps the tool output kept in session history; the
+// model saw the full result during the turn.
+const maxStoredToolResult = 4000
+
+// userMessage returns the session record of an inbound message.
+func userMessage(msg chat.Inbound) session.Message {
+	return session.Message{
+		Role:     "user",
+		Content:  historyContent(msg),
+		Sender:   msg.SenderID,
+		Time:     msg.Timestamp,
+		Metadata: msg.Metadata,
 	}
+}
+
+// cancelledMarker ends the assistant entry of a turn stopped by the user.
+const cancelledMarker = "[cancelled by user]"
+
+// recordCancelled saves a turn that was stopped before it finished: the user
+// message, the tool calls that completed and whatever the assistant had
+// produced, followed by a cancelled marker, so the next turn knows the
+// request was abandoned.
+func (a *AgentLoop) recordCancelled(sess *session.Session, msg chat.Inbound, turn []session.Message, partial string) {
+	sess.Add(userMessage(msg))
+	for _, m := range turn {
+		sess.Add(m)
+	}
+	sess.AddMessage("assistant", strings.TrimSpace(partial+" "+cancelledMarker))
+	if err := a.sessions.Save(sess); err != nil {
+		log.Printf("error saving session: %v", err)
+	}
+}
 
-	return "Max iterations reached without final response", nil
+// ProcessDirect answers a message outside of any chat and returns the reply.
+// It runs the same turn as messages from the hub, without session history;
+// hooks observe it, e.g. to show tool activity. Tool calls that need approval
+// are refused, as there is no one to ask.
+func (a *AgentLoop) ProcessDirect(content string, timeout time.Duration, hooks ...TurnHooks) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	msg := chat.Inbound{Channel: "cli", SenderID: "user", ChatID: "direct", Content: content, Timestamp: time.Now()}
+	r, ok := a.respond(ctx, msg, turnOptions{ephemeral: true, hooks: hooks})
+	if r.err != nil {
+		return "", r.err
+	}
+	if !ok {
+		return "", ctx.Err()
+	}
+	return r.content, nil
 }
@@ -0,0 +1,111 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/config"
+	"github.com/local/picobot/internal/providers"
+)
+
+// launchProvider asks for the launch tool, then reports the tool's result.
+type launchProvider struct{}
+
+func (launchProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	if last := messages[len(messages)-1]; last.Role == "tool" {
+		return providers.LLMResponse{Content: "result: " + last.Content}, nil
+	}
+	tc := providers.ToolCall{ID: "1", Name: "launch", Arguments: map[string]interface{}{"target": "moon"}}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+func (launchProvider) GetDefaultModel() string { return "fake-model" }
+
+// launchTool counts its runs.
+type launchTool struct{ runs atomic.Int32 }
+
+func (t *launchTool) Name() string                       { return "launch" }
+func (t *launchTool) Description() string                { return "launch a rocket" }
+func (t *launchTool) Parameters() map[string]interface{} { return nil }
+func (t *launchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	t.runs.Add(1)
+	return "launched", nil
+}
+
+func TestToolCallWaitsForApproval(t *testing.T) {
+	b := chat.NewHub(20)
+	ag := NewAgentLoop(b, launchProvider{}, "fake-model", 5, t.TempDir(), nil, nil)
+	tool := &launchTool{}
+	ag.tools.Register(tool)
+	if err := ag.SetApproval(config.ApprovalConfig{Enabled: true, Rules: []config.ApprovalRule{{Tool: "launch"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	// next returns the next outbound message that is not a tool notification.
+	next := func() chat.Outbound {
+		for {
+			select {
+			case out := <-b.Out:
+				if strings.HasPrefix(out.Content, "🤖") || strings.HasPrefix(out.Content, "📢") {
+					continue
+				}
+				return out
+			case <-ctx.Done():
+				t.Fatal("timeout waiting for a reply")
+			}
+		}
+	}
+	send := func(sender, text string) {
+		b.In <- chat.Inbound{Channel: "telegram", SenderID: sender, ChatID: "group", Content: text}
+	}
+
+	send("alice", "launch the rocket")
+	ask := next()
+	if !strings.Contains(ask.Content, `Allow launch {"target":"moon"}?`) || len(ask.Buttons) != 2 {
+		t.Fatalf("expected an approval request with buttons, got %+v", ask)
+	}
+	approve := ask.Buttons[0].Data
+	if !strings.HasPrefix(approve, "/approve ") {
+		t.Fatalf("unexpected approve button %+v", ask.Buttons[0])
+	}
+
+	send("bob", approve)
+	if got := next().Content; !strings.Contains(got, "Only the person who made the request") {
+		t.Fatalf("expected bob to be refused, got %q", got)
+	}
+	send("alice", approve) // the button press
+	if got := next().Content; got != "Approved: running launch." {
+		t.Fatalf("unexpected confirmation %q", got)
+	}
+	if got := next().Content; got != "result: launched" {
+		t.Fatalf("expected the turn to resume, got %q", got)
+	}
+
+	send("alice", "launch it again")
+	next() // approval request
+	send("alice", "no")
+	if got := next().Content; got != "Denied: launch will not run." {
+		t.Fatalf("unexpected confirmation %q", got)
+	}
+	if got := next().Content; got != "Stopped: launch not approved: denied by the user." {
+		t.Fatalf("expected the turn to stop, got %q", got)
+	}
+	if tool.runs.Load() != 1 {
+		t.Fatalf("expected one launch, got %d", tool.runs.Load())
+	}
+	if got := ag.sessions.GetOrCreate("telegram:group").GetHistory(); got[len(got)-1].Content != "Stopped: launch not approved: denied by the user." {
+		t.Fatalf("expected the stopped turn in history, got %+v", got[len(got)-1])
+	}
+
+	send("alice", "/approve")
+	if got := next().Content; got != "Nothing is waiting for approval here." {
+		t.Fatalf("unexpected reply %q", got)
+	}
+}
@@ -0,0 +1,137 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// modelProvider records the model of every call.
+type modelProvider struct {
+	models chan string
+}
+
+func (m *modelProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	m.models <- model
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (m *modelProvider) GetDefaultModel() string { return "default-model" }
+
+// nextReply returns the next outbound message, failing the test on timeout.
+func nextReply(t *testing.T, b *chat.Hub) chat.Outbound {
+	t.Helper()
+	select {
+	case out := <-b.Out:
+		return out
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for reply")
+	}
+	return chat.Outbound{}
+}
+
+func TestSlashCommandsSkipTheLLM(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &modelProvider{models: make(chan string, 10)}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+	b.Commands.Register(chat.Command{Name: "ping", Description: "Channel command", Handler: func(ctx context.Context, msg chat.Inbound, args string) string {
+		return "pong " + args
+	}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "/help@picobot"}
+	if out := nextReply(t, b); !strings.Contains(out.Content, "/reset") || !strings.Contains(out.Content, "/ping") {
+		t.Fatalf("expected built-in and channel commands in help, got %q", out.Content)
+	}
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "/ping  x"}
+	if out := nextReply(t, b); out.Content != "pong x" {
+		t.Fatalf("expected channel command reply, got %q", out.Content)
+	}
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "/nope"}
+	if out := nextReply(t, b); !strings.Contains(out.Content, "Unknown command /nope") {
+		t.Fatalf("expected unknown command hint, got %q", out.Content)
+	}
+
+	// /model changes the model for this chat only
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "/model big-model"}
+	nextReply(t, b)
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "hi"}
+	if m := <-p.models; m != "big-model" {
+		t.Fatalf("expected override model, got %q", m)
+	}
+	nextReply(t, b)
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "2", Content: "hi"}
+	if m := <-p.models; m != "default-model" {
+		t.Fatalf("expected default model in another chat, got %q", m)
+	}
+	nextReply(t, b)
+
+	// /reset clears the history
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "/reset"}
+	if out := nextReply(t, b); out.Content != "Conversation cleared." {
+		t.Fatalf("unexpected reset reply %q", out.Content)
+	}
+	if h := ag.sessions.GetOrCreate("telegram:1").GetHistory(); len(h) != 0 {
+		t.Fatalf("expected empty history after /reset, got %v", h)
+	}
+
+	select {
+	case m := <-p.models:
+		t.Fatalf("commands must not call the provider (got call with %q)", m)
+	default:
+	}
+}
+
+func TestStopCommandCancelsRunningTurn(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &gatedProvider{release: make(chan struct{})}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "discord", ChatID: "1", Content: "slow task"}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		ag.turnMu.Lock()
+		running := len(ag.turns) > 0
+		ag.turnMu.Unlock()
+		if running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("turn never started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	b.In <- chat.Inbound{Channel: "discord", ChatID: "1", Content: "/stop"}
+	if out := nextReply(t, b); out.Content != "Stopped." {
+		t.Fatalf("expected stop confirmation, got %q", out.Content)
+	}
+	b.In <- chat.Inbound{Channel: "discord", ChatID: "1", Content: "next"}
+	if out := nextReply(t, b); out.Content != "re: next" {
+		t.Fatalf("expected the stopped turn to send nothing and the next message to be answered, got %q", out.Content)
+	}
+
+	h := ag.sessions.GetOrCreate("discord:1").GetHistory()
+	if len(h) < 2 || h[0].Content != "slow task" || h[1].Role != "assistant" || h[1].Content != cancelledMarker {
+		t.Fatalf("expected the cancelled turn in history, got %v", h)
+	}
+
+	// a stray stop reaction gets no reply
+	b.In <- chat.Inbound{Channel: "discord", ChatID: "1", Content: "/stop", Metadata: map[string]interface{}{"reaction": "🛑"}}
+	select {
+	case out := <-b.Out:
+		t.Fatalf("expected no reply to a stop reaction with nothing running, got %q", out.Content)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
+)
+
+// summaryProvider answers summarisation requests with a fixed summary and
+// records the system prompt of ordinary calls.
+type summaryProvider struct {
+	summaries int
+	system    chan string
+}
+
+func (s *summaryProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	if messages[0].Content == summaryPrompt {
+		s.summaries++
+		return providers.LLMResponse{Content: "user likes green tea"}, nil
+	}
+	s.system <- messages[0].Content
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+
+func (s *summaryProvider) GetDefaultModel() string { return "m" }
+
+func TestEstimateTokens(t *testing.T) {
+	msgs := []providers.Message{{Content: strings.Repeat("a", 400)}, {Content: strings.Repeat("b", 40)}}
+	if got := estimateTokens(msgs); got != 110 {
+		t.Fatalf("expected 110 tokens, got %d", got)
+	}
+}
+
+func TestAgentCompactsHistoryOverBudget(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &summaryProvider{system: make(chan string, 1)}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+	ag.SetContextBudget(500)
+
+	sess := ag.sessions.GetOrCreate("telegram:1")
+	for i := 0; i < 10; i++ {
+		sess.AddMessage("user", strings.Repeat("tea ", 250))
+		sess.AddMessage("assistant", "noted")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+	b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "what do I like?"}
+
+	select {
+	case sys := <-p.system:
+		if !strings.Contains(sys, "Summary of the earlier conversation:\nuser likes green tea") {
+			t.Fatalf("expected the summary in the system prompt, got %q", sys)
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for provider call")
+	}
+	nextReply(t, b)
+
+	if p.summaries == 0 {
+		t.Fatalf("expected a summarisation call")
+	}
+	if sess.Summary != "user likes green tea" || len(sess.Messages) > keepRecentMessages+2 {
+		t.Fatalf("expected compacted session, got summary %q and %d history entries", sess.Summary, len(sess.Messages))
+	}
+}
+
+func TestToolHeavyTurnsAreNotTrimmedBeforeCompaction(t *testing.T) {
+	for _, c := range []struct {
+		budget int
+		want   int
+	}{
+		{0, session.MaxHistorySize},          // without compaction history is cut on save
+		{100000, session.MaxHistorySize + 6}, // with it, nothing is lost before it is summarised
+	} {
+		b := chat.NewHub(20)
+		ag := NewAgentLoop(b, lookupLoopProvider{rounds: 4}, "fake", 10, t.TempDir(), nil, nil)
+		ag.tools.Register(lookupTool{})
+		ag.SetContextBudget(c.budget)
+		sess := ag.sessions.GetOrCreate("telegram:1")
+		for i := 0; i < session.MaxHistorySize-4; i++ {
+			sess.AddMessage("user", fmt.Sprint("message ", i))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		go ag.Run(ctx)
+		b.In <- chat.Inbound{Channel: "telegram", ChatID: "1", Content: "moons of Mars?"}
+		for {
+			if out := nextReply(t, b); strings.HasPrefix(out.Content, "answer:") {
+				break
+			}
+		}
+		cancel()
+
+		// 46 messages + the question, 4 tool calls with their results and the answer
+		if got := len(sess.Messages); got != c.want {
+			t.Fatalf("budget %d: expected %d messages, got %d", c.budget, c.want, got)
+		}
+		if c.budget > 0 && sess.Messages[0].Content != "message 0" {
+			t.Fatalf("expected the oldest message to be kept for compaction, got %q", sess.Messages[0].Content)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// gatedProvider blocks on messages starting with "slow" until release is
+// closed, and otherwise echoes the user message back.
+type gatedProvider struct {
+	release chan struct{}
+}
+
+func (g *gatedProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	last := messages[len(messages)-1].Content
+	if strings.HasPrefix(last, "slow") {
+		select {
+		case <-g.release:
+		case <-ctx.Done():
+			return providers.LLMResponse{}, ctx.Err()
+		}
+	}
+	return providers.LLMResponse{Content: "re: " + last}, nil
+}
+
+func (g *gatedProvider) GetDefaultModel() string { return "gated" }
+
+func TestAgentProcessesSessionsConcurrently(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &gatedProvider{release: make(chan struct{})}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "slack", SenderID: "u1", ChatID: "A", Content: "slow one"}
+	b.In <- chat.Inbound{Channel: "slack", SenderID: "u1", ChatID: "A", Content: "after slow"}
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "u2", ChatID: "B", Content: "fast"}
+
+	next := func() chat.Outbound {
+		for {
+			select {
+			case out := <-b.Out:
+				if strings.HasPrefix(out.Content, "re: ") {
+					return out
+				}
+			case <-ctx.Done():
+				t.Fatal("timeout waiting for reply")
+			}
+		}
+	}
+
+	// B must not wait for A's slow turn.
+	if out := next(); out.ChatID != "B" || out.Content != "re: fast" {
+		t.Fatalf("expected B's reply first, got %+v", out)
+	}
+
+	// A's messages are answered in order once the slow turn finishes.
+	close(p.release)
+	if out := next(); out.ChatID != "A" || out.Content != "re: slow one" {
+		t.Fatalf("expected A's first reply, got %+v", out)
+	}
+	if out := next(); out.ChatID != "A" || out.Content != "re: after slow" {
+		t.Fatalf("expected A's second reply, got %+v", out)
+	}
+}
@@ -0,0 +1,74 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/agent/tools"
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// factProvider stores the user's city with upsert_fact on the first message
+// and records the system prompt of every call.
+type factProvider struct {
+	mu      sync.Mutex
+	calls   int
+	prompts []string
+}
+
+func (p *factProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.calls++
+	p.prompts = append(p.prompts, messages[0].Content)
+	if p.calls == 1 {
+		args := map[string]interface{}{"subject": "user", "predicate": "city", "object": "Lisbon", "confidence": "high"}
+		tc := providers.ToolCall{ID: "1", Name: "upsert_fact", Arguments: args}
+		return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+	}
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+func (p *factProvider) GetDefaultModel() string { return "fake-model" }
+
+func TestFactsStoredWithProvenanceAndPrompted(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &factProvider{}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 5, t.TempDir(), nil, nil)
+	ag.memory = memory.NewMemoryStoreWithWorkspace(t.TempDir(), 100)
+	ag.tools.Register(tools.NewUpsertFactTool(ag.memory))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+	send := func(text string) {
+		b.In <- chat.Inbound{Channel: "telegram", SenderID: "7", ChatID: "7", Content: text}
+		for {
+			select {
+			case out := <-b.Out:
+				if out.Content == "ok" {
+					return
+				}
+			case <-ctx.Done():
+				t.Fatal("timeout waiting for reply")
+			}
+		}
+	}
+	send("I just moved to Lisbon")
+	send("where do I live?")
+
+	f, ok, err := ag.memory.Facts().Lookup("user", "city")
+	if err != nil || !ok || f.Object != "Lisbon" || f.Session != "telegram:7" || f.Message != "I just moved to Lisbon" {
+		t.Fatalf("expected the fact with its provenance, got %+v %v %v", f, ok, err)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	last := p.prompts[len(p.prompts)-1]
+	if !strings.Contains(last, "Known facts (subject: predicate = value):\nuser: city = Lisbon") {
+		t.Fatalf("expected the fact sheet in the prompt:\n%s", last)
+	}
+}
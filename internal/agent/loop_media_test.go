@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// captureProvider records the last user message it was sent.
+type captureProvider struct {
+	last chan providers.Message
+}
+
+func (c *captureProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	c.last <- messages[len(messages)-1]
+	return providers.LLMResponse{Content: "It says $12.50"}, nil
+}
+
+func (c *captureProvider) GetDefaultModel() string { return "vision" }
+
+func TestAgentAttachesInboundMedia(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &captureProvider{last: make(chan providers.Message, 1)}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	img := "data:image/png;base64,iVBORw0KGgo="
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "u", ChatID: "1", Content: "total?", Media: []string{img, "ftp://nope"}}
+
+	select {
+	case m := <-p.last:
+		if len(m.Parts) != 2 {
+			t.Fatalf("expected text + one image part, got %+v", m.Parts)
+		}
+		if m.Parts[0].Type != "text" || !strings.HasPrefix(m.Parts[0].Text, "total?") || !strings.Contains(m.Parts[0].Text, "could not be loaded") {
+			t.Fatalf("unexpected text part: %+v", m.Parts[0])
+		}
+		if m.Parts[1].Type != "image" || m.Parts[1].ImageURL != img {
+			t.Fatalf("unexpected image part: %+v", m.Parts[1])
+		}
+	case <-ctx.Done():
+		t.Fatal("timeout waiting for provider call")
+	}
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// promptRecorder replies "ok" and keeps the system prompt of every call.
+type promptRecorder struct {
+	mu      sync.Mutex
+	prompts []string
+}
+
+func (p *promptRecorder) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.prompts = append(p.prompts, messages[0].Content)
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+func (p *promptRecorder) GetDefaultModel() string { return "fake-model" }
+
+func TestMemoryScopedBySender(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &promptRecorder{}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 5, t.TempDir(), nil, nil)
+	ag.memory = memory.NewMemoryStoreWithWorkspace(t.TempDir(), 100)
+	if err := ag.SetMemoryScope("sender"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ag.SetMemoryScope("nobody"); err == nil {
+		t.Fatal("expected an unknown scope to be rejected")
+	}
+	ag.memory.WriteLongTerm("Family dinner is on Sundays.")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	send := func(sender, text string) string {
+		b.In <- chat.Inbound{Channel: "telegram", SenderID: sender, ChatID: "family-group", Content: text}
+		select {
+		case out := <-b.Out:
+			return out.Content
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for reply")
+			return ""
+		}
+	}
+	go ag.Run(ctx)
+
+	if got := send("alice", "Remember my password hint is the first cat"); got != "OK, I've remembered that." {
+		t.Fatalf("unexpected reply %q", got)
+	}
+	send("bob", "when is dinner?")
+	send("alice", "what is my hint?")
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.prompts) != 2 {
+		t.Fatalf("expected 2 provider calls, got %d", len(p.prompts))
+	}
+	if strings.Contains(p.prompts[0], "first cat") || !strings.Contains(p.prompts[0], "Family dinner") {
+		t.Fatalf("bob's prompt should have only shared memory:\n%s", p.prompts[0])
+	}
+	if !strings.Contains(p.prompts[1], "first cat") || !strings.Contains(p.prompts[1], "Family dinner") {
+		t.Fatalf("alice's prompt should have her memory and the shared memory:\n%s", p.prompts[1])
+	}
+}
@@ -0,0 +1,95 @@
+package agent
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// fakeStreamProvider emits its reply as deltas through ChatStream.
+type fakeStreamProvider struct {
+	deltas []string
+}
+
+func (f *fakeStreamProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	return f.ChatStream(ctx, messages, tools, model, nil)
+}
+
+func (f *fakeStreamProvider) ChatStream(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string, onDelta func(string)) (providers.LLMResponse, error) {
+	full := ""
+	for _, d := range f.deltas {
+		full += d
+		if onDelta != nil {
+			onDelta(d)
+		}
+	}
+	return providers.LLMResponse{Content: full}, nil
+}
+
+func (f *fakeStreamProvider) GetDefaultModel() string { return "fake" }
+
+func TestAgentStreamsPartialUpdates(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &fakeStreamProvider{deltas: []string{"Hel", "lo ", "world"}}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+	ag.SetStreaming(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "user", ChatID: "one", Content: "hi"}
+
+	var partial *chat.Outbound
+	deadline := time.After(1 * time.Second)
+	for {
+		select {
+		case out := <-b.Out:
+			if out.Partial {
+				if out.StreamID == "" {
+					t.Fatalf("partial update without StreamID: %+v", out)
+				}
+				if partial == nil {
+					partial = &out
+				}
+				continue
+			}
+			if partial == nil {
+				t.Fatalf("expected a partial update before the final reply")
+			}
+			if partial.Content != "Hel" {
+				t.Fatalf("expected first partial to carry the first delta, got %q", partial.Content)
+			}
+			if out.Content != "Hello world" || out.StreamID != partial.StreamID {
+				t.Fatalf("unexpected final reply: %+v (stream %q)", out, partial.StreamID)
+			}
+			return
+		case <-deadline:
+			t.Fatalf("timeout waiting for final outbound message")
+		}
+	}
+}
+
+func TestAgentDoesNotStreamWhenDisabled(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &fakeStreamProvider{deltas: []string{"Hel", "lo"}}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "user", ChatID: "one", Content: "hi"}
+
+	select {
+	case out := <-b.Out:
+		if out.Partial || out.StreamID != "" || out.Content != "Hello" {
+			t.Fatalf("expected a single plain reply, got %+v", out)
+		}
+	case <-time.After(1 * time.Second):
+		t.Fatalf("timeout waiting for outbound message")
+	}
+}
@@ -0,0 +1,152 @@
+package agent
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
+)
+
+// subAgentProvider plays both parts. As a sub-agent it looks the topic up
+// with the lookup tool and reports what it found; as the parent it starts a
+// sub-agent with the arguments given in the user message ("spawn {...}"),
+// waits for it when asked ("wait <id>"), and relays delivered results.
+type subAgentProvider struct{}
+
+func (subAgentProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	if strings.HasPrefix(messages[0].Content, "You are a sub-agent") {
+		if last.Role == "tool" {
+			return providers.LLMResponse{Content: "found " + last.Content}, nil
+		}
+		tc := providers.ToolCall{ID: "s1", Name: "lookup", Arguments: map[string]interface{}{"topic": last.Content}}
+		return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+	}
+	if last.Role == "tool" {
+		return providers.LLMResponse{Content: "tool said: " + last.Content}, nil
+	}
+	var args map[string]interface{}
+	switch {
+	case strings.HasPrefix(last.Content, "[Sub-agent "):
+		return providers.LLMResponse{Content: "relayed: " + last.Content}, nil
+	case strings.HasPrefix(last.Content, "spawn "):
+		args = map[string]interface{}{"task": "moons of Mars", "tools": []interface{}{"lookup"}}
+		if strings.Contains(last.Content, "quietly") {
+			args["deliver"] = false
+		}
+		if strings.Contains(last.Content, "message") {
+			args["tools"] = []interface{}{"message"}
+		}
+	case strings.HasPrefix(last.Content, "wait "):
+		args = map[string]interface{}{"action": "wait", "id": strings.TrimPrefix(last.Content, "wait ")}
+	default:
+		return providers.LLMResponse{Content: "?"}, nil
+	}
+	tc := providers.ToolCall{ID: "p1", Name: "spawn", Arguments: args}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+func (subAgentProvider) GetDefaultModel() string { return "fake" }
+
+// lookupTool answers after a short delay.
+type lookupTool struct{}
+
+func (lookupTool) Name() string                       { return "lookup" }
+func (lookupTool) Description() string                { return "look a topic up" }
+func (lookupTool) Parameters() map[string]interface{} { return nil }
+func (lookupTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	time.Sleep(50 * time.Millisecond)
+	return "Phobos and Deimos", nil
+}
+
+func TestSubAgentReportsBackToChat(t *testing.T) {
+	b := chat.NewHub(20)
+	ag := NewAgentLoop(b, subAgentProvider{}, "fake", 5, t.TempDir(), nil, nil)
+	ag.tools.Register(lookupTool{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	next := func() chat.Outbound {
+		for {
+			select {
+			case out := <-b.Out:
+				if strings.HasPrefix(out.Content, "🤖") || strings.HasPrefix(out.Content, "📢") {
+					continue
+				}
+				return out
+			case <-ctx.Done():
+				t.Fatal("timeout waiting for a reply")
+			}
+		}
+	}
+	send := func(text string) {
+		b.In <- chat.Inbound{Channel: "telegram", SenderID: "alice", ChatID: "42", Content: text}
+	}
+
+	// started in the background, then delivered to the chat and relayed
+	send("spawn research")
+	if got := next().Content; !strings.Contains(got, "its result will be posted to this chat") {
+		t.Fatalf("expected the sub-agent to start, got %q", got)
+	}
+	got := next().Content
+	if !strings.HasPrefix(got, "relayed: [Sub-agent ") || !strings.Contains(got, "found Phobos and Deimos") {
+		t.Fatalf("expected the result to be relayed, got %q", got)
+	}
+	var result session.Message
+	for _, m := range ag.sessions.GetOrCreate("telegram:42").GetHistory() {
+		if strings.HasPrefix(m.Content, "[Sub-agent ") {
+			result = m
+		}
+	}
+	if result.Sender != subAgentSender {
+		t.Fatalf("expected the result to come from %q, not the user, got %+v", subAgentSender, result)
+	}
+
+	// started without delivery, then collected with wait
+	send("spawn quietly")
+	got = next().Content
+	i := strings.Index(got, "started sub-agent ")
+	if i < 0 {
+		t.Fatalf("expected the sub-agent to start, got %q", got)
+	}
+	id := strings.Fields(got[i+len("started sub-agent "):])[0]
+	id = strings.TrimSuffix(id, ";")
+	send("wait " + id)
+	if got := next().Content; !strings.Contains(got, ": done after") || !strings.Contains(got, "found Phobos and Deimos") {
+		t.Fatalf("expected wait to return the result, got %q", got)
+	}
+
+	// sub-agents never get the message tool
+	send("spawn with message")
+	if got := next().Content; !strings.Contains(got, "message is not available to sub-agents") {
+		t.Fatalf("expected the message tool to be refused, got %q", got)
+	}
+
+	// nothing else was delivered
+	select {
+	case out := <-b.Out:
+		if !strings.HasPrefix(out.Content, "🤖") && !strings.HasPrefix(out.Content, "📢") {
+			t.Fatalf("unexpected message %q", out.Content)
+		}
+	case <-time.After(200 * time.Millisecond):
+	}
+}
+
+func TestSubAgentDefaultToolsOnlyRead(t *testing.T) {
+	ag := NewAgentLoop(chat.NewHub(1), subAgentProvider{}, "fake", 5, t.TempDir(), nil, nil)
+	names := ag.subagents.defaultTools()
+	if len(names) == 0 {
+		t.Fatal("expected default sub-agent tools")
+	}
+	for _, name := range names {
+		switch name {
+		case "filesystem", "exec", "write_memory", "edit_memory", "delete_memory", "upsert_fact", "retract_fact":
+			t.Fatalf("default sub-agent tools include %s, which changes things: %v", name, names)
+		}
+	}
+}
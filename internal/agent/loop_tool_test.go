@@ -2,6 +2,7 @@ package agent
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,7 +32,7 @@ func (f *FakeProvider) GetDefaultModel() string { return "fake" }
 func TestAgentExecutesToolCall(t *testing.T) {
 	b := chat.NewHub(10)
 	p := &FakeProvider{}
-	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, "", nil, nil)
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
@@ -59,3 +60,133 @@ func TestAgentExecutesToolCall(t *testing.T) {
 		}
 	}
 }
+
+// replayProvider calls the message tool on the first turn and records what
+// the model is sent on the next one.
+type replayProvider struct {
+	count int
+	seen  []providers.Message
+}
+
+func (p *replayProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	p.count++
+	switch p.count {
+	case 1:
+		return providers.LLMResponse{
+			HasToolCalls: true,
+			ToolCalls:    []providers.ToolCall{{ID: "call-1", Name: "message", Arguments: map[string]interface{}{"content": "hello from tool"}}},
+		}, nil
+	case 2:
+		return providers.LLMResponse{Content: "Sent."}, nil
+	}
+	p.seen = messages
+	return providers.LLMResponse{Content: "ok"}, nil
+}
+func (p *replayProvider) GetDefaultModel() string { return "fake" }
+
+func TestAgentReplaysToolCallsFromHistory(t *testing.T) {
+	b := chat.NewHub(10)
+	p := &replayProvider{}
+	ag := NewAgentLoop(b, p, p.GetDefaultModel(), 3, t.TempDir(), nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "42", ChatID: "1", Content: "say hello", Metadata: map[string]interface{}{"message_id": "7"}}
+	for out := range b.Out {
+		if out.Content == "Sent." {
+			break
+		}
+	}
+	b.In <- chat.Inbound{Channel: "telegram", SenderID: "42", ChatID: "1", Content: "what did you do?"}
+	for out := range b.Out {
+		if out.Content == "ok" {
+			break
+		}
+	}
+
+	var roles []string
+	for _, m := range p.seen[1:] {
+		roles = append(roles, m.Role)
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,tool,assistant,user" {
+		t.Fatalf("expected the tool call to be replayed, got roles %s", got)
+	}
+	if call, res := p.seen[2], p.seen[3]; len(call.ToolCalls) != 1 || call.ToolCalls[0].Name != "message" || res.ToolCallID != "call-1" {
+		t.Fatalf("unexpected replayed tool messages: %+v %+v", call, res)
+	}
+
+	first := ag.sessions.GetOrCreate("telegram:1").GetHistory()[0]
+	if first.Sender != "42" || first.Metadata["message_id"] != "7" {
+		t.Fatalf("expected sender and metadata on the stored user message, got %+v", first)
+	}
+}
+
+// fetchProvider asks for three slow fetches at once, then replies with the
+// tool results in the order it received them.
+type fetchProvider struct{}
+
+func (fetchProvider) Chat(ctx context.Context, messages []providers.Message, tools []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	var results []string
+	for _, m := range messages {
+		if m.Role == "tool" {
+			results = append(results, m.ToolCallID+"="+m.Content)
+		}
+	}
+	if len(results) > 0 {
+		return providers.LLMResponse{Content: strings.Join(results, ",")}, nil
+	}
+	var calls []providers.ToolCall
+	for _, id := range []string{"a", "b", "c"} {
+		calls = append(calls, providers.ToolCall{ID: id, Name: "fetch", Arguments: map[string]interface{}{"url": id}})
+	}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: calls}, nil
+}
+func (fetchProvider) GetDefaultModel() string { return "fake" }
+
+// fetchTool takes 200ms per call, longer for "a" so it finishes last.
+type fetchTool struct{}
+
+func (fetchTool) Name() string                       { return "fetch" }
+func (fetchTool) Description() string                { return "slow fetch" }
+func (fetchTool) Parameters() map[string]interface{} { return nil }
+func (fetchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	url, _ := args["url"].(string)
+	d := 200 * time.Millisecond
+	if url == "a" {
+		d = 300 * time.Millisecond
+	}
+	time.Sleep(d)
+	return "page " + url, nil
+}
+
+func TestAgentRunsToolCallsConcurrently(t *testing.T) {
+	b := chat.NewHub(20)
+	ag := NewAgentLoop(b, fetchProvider{}, "fake", 3, t.TempDir(), nil, nil)
+	ag.tools.Register(fetchTool{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	go ag.Run(ctx)
+
+	start := time.Now()
+	b.In <- chat.Inbound{Channel: "cli", SenderID: "user", ChatID: "one", Content: "fetch three pages"}
+	for {
+		select {
+		case out := <-b.Out:
+			if strings.HasPrefix(out.Content, "🤖") || strings.HasPrefix(out.Content, "📢") {
+				continue
+			}
+			if want := "a=page a,b=page b,c=page c"; out.Content != want {
+				t.Fatalf("got %q, want results in call order %q", out.Content, want)
+			}
+			if elapsed := time.Since(start); elapsed > 550*time.Millisecond {
+				t.Fatalf("three fetches took %s; expected them to overlap", elapsed)
+			}
+			return
+		case <-ctx.Done():
+			t.Fatal("timeout waiting for the reply")
+		}
+	}
+}
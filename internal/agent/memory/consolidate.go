@@ -0,0 +1,376 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/local/picobot/internal/providers"
+)
+
+// ConsolidateOptions controls a consolidation run.
+type ConsolidateOptions struct {
+	Days    int  // how many days of notes to read, including today (default 7)
+	Archive bool // move the consolidated notes (except today's) to memory/archive/
+}
+
+// ConsolidateReport describes what a consolidation run changed.
+type ConsolidateReport struct {
+	Added    []Fact
+	Updated  []Fact // facts that replaced a conflicting entry
+	Skipped  int    // facts already present in MEMORY.md
+	Archived []string
+	Usage    providers.Usage
+}
+
+// Fact is one durable fact extracted from the daily notes.
+type Fact struct {
+	Section  string `json:"section"`
+	Text     string `json:"text"`
+	Replaces string `json:"replaces,omitempty"` // existing entry this one contradicts
+}
+
+// archiveDir holds archived daily notes and the consolidation log.
+const archiveDir = "archive"
+
+// consolidationLog is the diff log written under archiveDir.
+const consolidationLog = "consolidation.log"
+
+const consolidatePrompt = `You maintain a user's long-term memory file. Read the recent daily notes and extract only durable facts worth keeping for months: preferences, people, places, projects, decisions, recurring commitments, important dates. Skip one-off chatter, transient status updates, heartbeat logs and anything already in the current long-term memory.
+
+Group facts under short section names (reuse existing section names where they fit, e.g. "Preferences", "People", "Projects"). Write each fact as one short sentence. If a fact contradicts an entry in the current long-term memory, set "replaces" to that entry's exact text.
+
+Call the tool 'record_facts' with {"facts": [{"section": "...", "text": "...", "replaces": "..."}]}, or reply with that JSON object and nothing else. Return an empty list if nothing is worth keeping.`
+
+// recordFactsTool lets providers answer with a structured tool call.
+var recordFactsTool = providers.ToolDefinition{
+	Name:        "record_facts",
+	Description: "Record durable facts to merge into long-term memory",
+	Parameters: map[string]interface{}{
+		"type":     "object",
+		"required": []string{"facts"},
+		"properties": map[string]interface{}{
+			"facts": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type":     "object",
+					"required": []string{"section", "text"},
+					"properties": map[string]interface{}{
+						"section":  map[string]interface{}{"type": "string"},
+						"text":     map[string]interface{}{"type": "string"},
+						"replaces": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	},
+}
+
+// Consolidate reads the last opts.Days of daily notes, asks the model for the
+// durable facts in them and merges those into sections of MEMORY.md, skipping
+// duplicates and marking entries replaced by newer, conflicting facts. Every
+// change is appended to memory/archive/consolidation.log.
+func Consolidate(ctx context.Context, s *MemoryStore, provider providers.LLMProvider, model string, opts ConsolidateOptions) (*ConsolidateReport, error) {
+	if opts.Days <= 0 {
+		opts.Days = 7
+	}
+	report := &ConsolidateReport{}
+	notes, err := s.GetRecentMemories(opts.Days)
+	if err != nil {
+		return report, err
+	}
+	if strings.TrimSpace(strings.ReplaceAll(notes, "\n---\n", "")) == "" {
+		return report, nil
+	}
+	long, err := s.ReadLongTerm()
+	if err != nil {
+		return report, err
+	}
+
+	facts, usage, err := extractFacts(ctx, provider, model, long, notes)
+	report.Usage = usage
+	if err != nil {
+		return report, err
+	}
+	now := time.Now().UTC()
+	doc := parseMemoryDoc(long)
+	for _, f := range facts {
+		switch doc.merge(f, now) {
+		case mergeAdded:
+			report.Added = append(report.Added, f)
+		case mergeUpdated:
+			report.Updated = append(report.Updated, f)
+		default:
+			report.Skipped++
+		}
+	}
+	if len(report.Added)+len(report.Updated) > 0 {
+		if err := s.WriteLongTerm(doc.String()); err != nil {
+			return report, err
+		}
+	}
+
+	if opts.Archive {
+		// today's note is still being written, so it stays in place
+		for i := 1; i < opts.Days; i++ {
+			name := now.AddDate(0, 0, -i).Format("2006-01-02") + ".md"
+			ok, err := s.ArchiveFile(name)
+			if err != nil {
+				return report, err
+			}
+			if ok {
+				report.Archived = append(report.Archived, name)
+			}
+		}
+	}
+	return report, s.logConsolidation(now, opts.Days, report)
+}
+
+// extractFacts asks the model for facts, accepting either a record_facts
+// tool call or a JSON object in the reply.
+func extractFacts(ctx context.Context, provider providers.LLMProvider, model, long, notes string) ([]Fact, providers.Usage, error) {
+	if provider == nil {
+		return nil, providers.Usage{}, fmt.Errorf("no provider configured")
+	}
+	var sb strings.Builder
+	sb.WriteString("Current long-term memory:\n")
+	if strings.TrimSpace(long) == "" {
+		sb.WriteString("(empty)\n")
+	} else {
+		sb.WriteString(long + "\n")
+	}
+	sb.WriteString("\nRecent daily notes:\n" + notes)
+
+	resp, err := provider.Chat(ctx, []providers.Message{
+		{Role: "system", Content: consolidatePrompt},
+		{Role: "user", Content: sb.String()},
+	}, []providers.ToolDefinition{recordFactsTool}, model)
+	if err != nil {
+		return nil, providers.Usage{}, err
+	}
+
+	var raw []byte
+	for _, tc := range resp.ToolCalls {
+		if tc.Name == recordFactsTool.Name {
+			raw, _ = json.Marshal(tc.Arguments)
+			break
+		}
+	}
+	if raw == nil {
+		body := resp.Content
+		start, end := strings.Index(body, "{"), strings.LastIndex(body, "}")
+		if start < 0 || end <= start {
+			return nil, resp.Usage, fmt.Errorf("no facts in model response: %q", strings.TrimSpace(body))
+		}
+		raw = []byte(body[start : end+1])
+	}
+	var out struct {
+		Facts []Fact `json:"facts"`
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, resp.Usage, fmt.Errorf("parsing facts: %w", err)
+	}
+	facts := out.Facts[:0]
+	for _, f := range out.Facts {
+		f.Section = strings.TrimSpace(strings.TrimLeft(f.Section, "# "))
+		f.Text = strings.TrimSpace(strings.TrimLeft(f.Text, "-* "))
+		f.Replaces = strings.TrimSpace(strings.TrimLeft(f.Replaces, "-* "))
+		if f.Text == "" {
+			continue
+		}
+		if f.Section == "" {
+			f.Section = "Notes"
+		}
+		facts = append(facts, f)
+	}
+	return facts, resp.Usage, nil
+}
+
+// ArchiveFile moves a dated note into memory/archive/. It reports false if the
+// file does not exist.
+func (s *MemoryStore) ArchiveFile(name string) (bool, error) {
+	if name == "MEMORY.md" || !isValidMemoryFile(name) {
+		return false, fmt.Errorf("only dated files (YYYY-MM-DD) can be archived, got %q", name)
+	}
+	src := filepath.Join(s.memoryDir, name)
+	if _, err := os.Stat(src); os.IsNotExist(err) {
+		return false, nil
+	}
+	dir := filepath.Join(s.memoryDir, archiveDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, err
+	}
+	dst := filepath.Join(dir, name)
+	if existing, err := os.ReadFile(dst); err == nil {
+		// archived before and written to again since: keep both parts
+		b, err := os.ReadFile(src)
+		if err != nil {
+			return false, err
+		}
+		if err := os.WriteFile(dst, append(existing, b...), 0o644); err != nil {
+			return false, err
+		}
+		if err := os.Remove(src); err != nil {
+			return false, err
+		}
+	} else if err := os.Rename(src, dst); err != nil {
+		return false, err
+	}
+	s.changed(name)
+	return true, nil
+}
+
+// logConsolidation appends the changes of one run to the diff log.
+func (s *MemoryStore) logConsolidation(at time.Time, days int, r *ConsolidateReport) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "## %s consolidated the last %d day(s): %d added, %d updated, %d already known\n",
+		at.Format(time.RFC3339), days, len(r.Added), len(r.Updated), r.Skipped)
+	for _, f := range r.Added {
+		fmt.Fprintf(&sb, "+ [%s] %s\n", f.Section, f.Text)
+	}
+	for _, f := range r.Updated {
+		fmt.Fprintf(&sb, "- [%s] %s\n+ [%s] %s\n", f.Section, f.Replaces, f.Section, f.Text)
+	}
+	if len(r.Archived) > 0 {
+		fmt.Fprintf(&sb, "archived: %s\n", strings.Join(r.Archived, ", "))
+	}
+	sb.WriteString("\n")
+
+	dir := filepath.Join(s.memoryDir, archiveDir)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(dir, consolidationLog), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(sb.String())
+	return err
+}
+
+// memoryDoc is MEMORY.md split into the text before the first "## " heading
+// and the sections that follow, so facts can be merged section by section.
+type memoryDoc struct {
+	preamble []string
+	sections []*memorySection
+}
+
+type memorySection struct {
+	title string
+	lines []string
+}
+
+type mergeResult int
+
+const (
+	mergeSkipped mergeResult = iota
+	mergeAdded
+	mergeUpdated
+)
+
+func parseMemoryDoc(content string) *memoryDoc {
+	d := &memoryDoc{}
+	content = strings.TrimRight(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if content == "" {
+		return d
+	}
+	var cur *memorySection
+	for _, l := range strings.Split(content, "\n") {
+		if strings.HasPrefix(l, "## ") {
+			cur = &memorySection{title: strings.TrimSpace(l[3:])}
+			d.sections = append(d.sections, cur)
+			continue
+		}
+		if cur == nil {
+			d.preamble = append(d.preamble, l)
+		} else {
+			cur.lines = append(cur.lines, l)
+		}
+	}
+	return d
+}
+
+// merge adds f to its section unless an equivalent entry exists anywhere in
+// the document. When f replaces an existing entry, that entry is rewritten
+// with a note recording the previous value. Only an entry equal to
+// f.Replaces once normalised is replaced, never one that merely contains it;
+// without one f is added as a new entry.
+func (d *memoryDoc) merge(f Fact, now time.Time) mergeResult {
+	key := normalizeFact(f.Text)
+	for _, l := range d.allLines() {
+		if normalizeFact(*l) == key {
+			return mergeSkipped
+		}
+	}
+	if old := normalizeFact(f.Replaces); old != "" {
+		for _, l := range d.allLines() {
+			if normalizeFact(*l) == old {
+				*l = fmt.Sprintf("- %s _(updated %s; was: %s)_", f.Text, now.Format("2006-01-02"), f.Replaces)
+				return mergeUpdated
+			}
+		}
+	}
+	sec := d.section(f.Section)
+	// keep entries together, ahead of trailing blank lines
+	at := len(sec.lines)
+	for at > 0 && strings.TrimSpace(sec.lines[at-1]) == "" {
+		at--
+	}
+	sec.lines = append(sec.lines[:at], append([]string{"- " + f.Text}, sec.lines[at:]...)...)
+	return mergeAdded
+}
+
+func (d *memoryDoc) section(title string) *memorySection {
+	for _, s := range d.sections {
+		if strings.EqualFold(s.title, title) {
+			return s
+		}
+	}
+	s := &memorySection{title: title}
+	d.sections = append(d.sections, s)
+	return s
+}
+
+func (d *memoryDoc) allLines() []*string {
+	var out []*string
+	for i := range d.preamble {
+		out = append(out, &d.preamble[i])
+	}
+	for _, s := range d.sections {
+		for i := range s.lines {
+			out = append(out, &s.lines[i])
+		}
+	}
+	return out
+}
+
+func (d *memoryDoc) String() string {
+	var sb strings.Builder
+	pre := strings.TrimRight(strings.Join(d.preamble, "\n"), "\n ")
+	if pre != "" {
+		sb.WriteString(pre + "\n\n")
+	}
+	for _, s := range d.sections {
+		sb.WriteString("## " + s.title + "\n")
+		body := strings.Trim(strings.Join(s.lines, "\n"), "\n")
+		if body != "" {
+			sb.WriteString(body + "\n")
+		}
+		sb.WriteString("\n")
+	}
+	return strings.TrimRight(sb.String(), "\n") + "\n"
+}
+
+// normalizeFact reduces an entry to lowercase words for duplicate detection,
+// ignoring list markers, punctuation and any "(updated ...)" note.
+func normalizeFact(s string) string {
+	if i := strings.Index(s, " _(updated "); i >= 0 {
+		s = s[:i]
+	}
+	return strings.Join(searchTerms(s), " ")
+}
@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/providers"
+)
+
+func TestConsolidateMergesFacts(t *testing.T) {
+	ws := t.TempDir()
+	ms := NewMemoryStoreWithWorkspace(ws, 10)
+	yesterday := time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02") + ".md"
+	ms.WriteFile(yesterday, "[2026-01-05T09:00:00Z] user switched from coffee to tea\n[2026-01-05T10:00:00Z] sister Ana visits in May\n")
+	ms.AppendToday("started learning Portuguese")
+	ms.WriteLongTerm("# Memory\n\n## Preferences\n- User drinks coffee every morning\n- User likes hiking\n")
+
+	p := &fakeProvider{calls: []providers.ToolCall{{Name: "record_facts", Arguments: map[string]interface{}{
+		"facts": []interface{}{
+			map[string]interface{}{"section": "Preferences", "text": "User drinks tea every morning", "replaces": "User drinks coffee every morning"},
+			map[string]interface{}{"section": "Preferences", "text": "user likes hiking."},
+			map[string]interface{}{"section": "People", "text": "Sister Ana visits in May"},
+			map[string]interface{}{"section": "## Projects", "text": "- Learning Portuguese"},
+		},
+	}}}}
+	rep, err := Consolidate(context.Background(), ms, p, "m", ConsolidateOptions{Days: 3, Archive: true})
+	if err != nil {
+		t.Fatalf("Consolidate: %v", err)
+	}
+	if len(rep.Added) != 2 || len(rep.Updated) != 1 || rep.Skipped != 1 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+
+	long, _ := ms.ReadLongTerm()
+	for _, want := range []string{
+		"# Memory\n\n## Preferences\n- User drinks tea every morning _(updated ",
+		"was: User drinks coffee every morning)_\n- User likes hiking\n\n## People\n- Sister Ana visits in May\n\n## Projects\n- Learning Portuguese\n",
+	} {
+		if !strings.Contains(long, want) {
+			t.Fatalf("expected MEMORY.md to contain %q, got:\n%s", want, long)
+		}
+	}
+
+	// yesterday is archived, today's note stays
+	if rep.Archived == nil || rep.Archived[0] != yesterday {
+		t.Fatalf("expected %s to be archived, got %v", yesterday, rep.Archived)
+	}
+	if got, _ := ms.ReadFile(yesterday); got != "" {
+		t.Fatalf("expected %s to be moved, still has %q", yesterday, got)
+	}
+	if _, err := os.Stat(filepath.Join(ws, "memory", "archive", yesterday)); err != nil {
+		t.Fatalf("expected archived copy: %v", err)
+	}
+	if today, _ := ms.ReadToday(); !strings.Contains(today, "Portuguese") {
+		t.Fatalf("expected today's note to stay, got %q", today)
+	}
+
+	logb, err := os.ReadFile(filepath.Join(ws, "memory", "archive", "consolidation.log"))
+	if err != nil {
+		t.Fatalf("expected a diff log: %v", err)
+	}
+	for _, want := range []string{"2 added, 1 updated, 1 already known", "+ [People] Sister Ana visits in May", "- [Preferences] User drinks coffee every morning\n+ [Preferences] User drinks tea every morning", "archived: " + yesterday} {
+		if !strings.Contains(string(logb), want) {
+			t.Fatalf("expected log to contain %q, got:\n%s", want, logb)
+		}
+	}
+
+	// a second run with the same facts changes nothing
+	before, _ := ms.ReadLongTerm()
+	rep, err = Consolidate(context.Background(), ms, p, "m", ConsolidateOptions{Days: 3})
+	if err != nil {
+		t.Fatalf("Consolidate again: %v", err)
+	}
+	if after, _ := ms.ReadLongTerm(); after != before || len(rep.Added)+len(rep.Updated) != 0 {
+		t.Fatalf("expected no changes on a second run, got %+v\n%s", rep, after)
+	}
+}
+
+func TestMergeReplacesOnlyTheExactEntry(t *testing.T) {
+	d := parseMemoryDoc("## People\n- Ana likes tea\n- Ana likes tea with her sister in Lisbon\n")
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// "Ana" is part of both entries but equal to neither
+	if got := d.merge(Fact{Section: "People", Text: "Bruno likes coffee", Replaces: "Ana"}, now); got != mergeAdded {
+		t.Fatalf("expected a partial match to add the fact, got %v", got)
+	}
+	if got := d.merge(Fact{Section: "People", Text: "Ana likes coffee", Replaces: "ana likes tea."}, now); got != mergeUpdated {
+		t.Fatalf("expected the exact entry to be replaced, got %v", got)
+	}
+	want := "## People\n- Ana likes coffee _(updated 2026-03-01; was: ana likes tea.)_\n- Ana likes tea with her sister in Lisbon\n- Bruno likes coffee\n"
+	if got := d.String(); got != want {
+		t.Fatalf("unexpected document:\n%s", got)
+	}
+}
+
+func TestConsolidateParsesJSONContent(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	ms.AppendToday("user moved to Porto")
+	p := &fakeProvider{resp: "Here you go:\n{\"facts\": [{\"section\": \"\", \"text\": \"User lives in Porto\"}]}"}
+	rep, err := Consolidate(context.Background(), ms, p, "m", ConsolidateOptions{})
+	if err != nil {
+		t.Fatalf("Consolidate: %v", err)
+	}
+	if long, _ := ms.ReadLongTerm(); len(rep.Added) != 1 || long != "## Notes\n- User lives in Porto\n" {
+		t.Fatalf("unexpected result %+v:\n%q", rep, long)
+	}
+
+	if _, err := Consolidate(context.Background(), ms, &fakeProvider{resp: "nothing"}, "m", ConsolidateOptions{}); err == nil {
+		t.Fatal("expected an error for a reply without facts")
+	}
+}
+
+func TestConsolidateSkipsWithoutNotes(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	rep, err := Consolidate(context.Background(), ms, nil, "m", ConsolidateOptions{})
+	if err != nil || len(rep.Added) != 0 {
+		t.Fatalf("expected a no-op without notes, got %+v, %v", rep, err)
+	}
+}
@@ -0,0 +1,441 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
+)
+
+// Embedding calls are made in batches of embedBatchSize texts, each bounded
+// by embedTimeout, so that the first index build over a large memory gets as
+// long as it needs while a stalled request still fails.
+const (
+	embedBatchSize = 96
+	embedTimeout   = 30 * time.Second
+)
+
+// EmbeddingRanker ranks memories by cosine similarity between embedding
+// vectors. Besides the items passed to Rank it searches a vector index over
+// every memory file (MEMORY.md and the dated notes), so a relevant note from
+// weeks ago can surface even though only recent items are passed in.
+//
+// The index is persisted at indexPath and kept current incrementally: the
+// first Rank re-reads every memory file, later calls only re-read the files
+// reported changed through MemoryStore.OnChange. Chunks whose text is
+// unchanged keep their vectors, so only new or edited text is embedded.
+// On any embedding error it falls back to SimpleRanker over the passed items.
+type EmbeddingRanker struct {
+	embedder  providers.Embedder
+	store     *MemoryStore
+	indexPath string
+	fallback  *SimpleRanker
+	parent    *EmbeddingRanker // ranker of the shared tier, for scoped stores
+
+	scopesMu sync.Mutex
+	scopes   map[*MemoryStore]*EmbeddingRanker
+
+	mu     sync.Mutex
+	index  *vectorIndex
+	synced bool                 // all memory files indexed at least once
+	dirty  map[string]bool      // files changed since the last sync
+	cache  map[string][]float32 // vectors of the last call's passed-in items, by text hash
+}
+
+// vectorIndex is the on-disk form of the index.
+type vectorIndex struct {
+	Model  string       `json:"model"`
+	Chunks []indexChunk `json:"chunks"`
+}
+
+// indexChunk is one embedded piece of a memory file: a line of a dated note
+// or a paragraph of MEMORY.md.
+type indexChunk struct {
+	Source string    `json:"source"`
+	Line   int       `json:"line"`
+	Hash   string    `json:"hash"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time,omitempty"`
+	Vector []float32 `json:"vector"`
+}
+
+// NewEmbeddingRanker returns a ranker that embeds with e and indexes the
+// files of store into indexPath.
+func NewEmbeddingRanker(e providers.Embedder, store *MemoryStore, indexPath string) *EmbeddingRanker {
+	r := &EmbeddingRanker{
+		embedder:  e,
+		store:     store,
+		indexPath: indexPath,
+		fallback:  NewSimpleRanker(),
+		dirty:     map[string]bool{},
+		cache:     map[string][]float32{},
+	}
+	store.OnChange(r.markDirty)
+	return r
+}
+
+// For returns the ranker for a scoped store (see MemoryStore.Scope): it
+// indexes the scope's files into index/scopes/ and also searches the shared
+// tier. For the store r was built for it returns r.
+func (r *EmbeddingRanker) For(ms *MemoryStore) *EmbeddingRanker {
+	if r.parent != nil {
+		return r.parent.For(ms)
+	}
+	if ms == nil || ms == r.store || ms.ScopeID() == "" {
+		return r
+	}
+	r.scopesMu.Lock()
+	defer r.scopesMu.Unlock()
+	if c, ok := r.scopes[ms]; ok {
+		return c
+	}
+	path := filepath.Join(filepath.Dir(r.indexPath), scopesDir, session.FileName(ms.ScopeID())+".json")
+	c := NewEmbeddingRanker(r.embedder, ms, path)
+	c.parent = r
+	if r.scopes == nil {
+		r.scopes = map[*MemoryStore]*EmbeddingRanker{}
+	}
+	r.scopes[ms] = c
+	return c
+}
+
+func (r *EmbeddingRanker) markDirty(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.dirty[name] = true
+}
+
+// Rank returns the top items among memories and the indexed file chunks,
+// most similar to query first. File chunks are returned with Kind set to
+// their file name.
+func (r *EmbeddingRanker) Rank(query string, memories []MemoryItem, top int) []MemoryItem {
+	out, err := r.rank(context.Background(), query, memories, top)
+	if err != nil {
+		log.Printf("memory: embedding ranker failed, falling back to keyword ranking: %v", err)
+		return r.fallback.Rank(query, memories, top)
+	}
+	return out
+}
+
+func (r *EmbeddingRanker) rank(ctx context.Context, query string, memories []MemoryItem, top int) ([]MemoryItem, error) {
+	var chunks []indexChunk
+	if r.parent != nil {
+		shared, err := r.parent.chunks(ctx)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, shared...)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.sync(ctx); err != nil {
+		return nil, err
+	}
+	chunks = append(chunks, r.index.Chunks...)
+
+	// embed the query together with any candidate not seen before
+	texts := []string{query}
+	var missing []string
+	for _, m := range memories {
+		h := hashText(m.Text)
+		if _, ok := r.cache[h]; !ok {
+			texts = append(texts, m.Text)
+			missing = append(missing, h)
+			r.cache[h] = nil // placeholder so duplicates are embedded once
+		}
+	}
+	vecs, err := r.embed(ctx, texts)
+	if err != nil {
+		for _, h := range missing {
+			delete(r.cache, h)
+		}
+		return nil, err
+	}
+	q := vecs[0]
+	for i, h := range missing {
+		r.cache[h] = vecs[i+1]
+	}
+	// keep only this call's items: they are mostly the same from one message
+	// to the next, and vectors of items that left the window would pile up
+	cache := make(map[string][]float32, len(memories))
+	for _, m := range memories {
+		h := hashText(m.Text)
+		cache[h] = r.cache[h]
+	}
+	r.cache = cache
+
+	type scored struct {
+		item  MemoryItem
+		score float64
+	}
+	seen := map[string]bool{}
+	var all []scored
+	for _, m := range memories {
+		key := strings.TrimSpace(m.Text)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		all = append(all, scored{m, cosine(q, r.cache[hashText(m.Text)])})
+	}
+	for _, c := range chunks {
+		if seen[c.Text] {
+			continue
+		}
+		seen[c.Text] = true
+		all = append(all, scored{MemoryItem{Kind: c.Source, Text: c.Text, Timestamp: c.Time}, cosine(q, c.Vector)})
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+	if top <= 0 || top > len(all) {
+		top = len(all)
+	}
+	out := make([]MemoryItem, top)
+	for i := range out {
+		out[i] = all[i].item
+	}
+	return out, nil
+}
+
+// chunks returns the indexed chunks after bringing the index up to date.
+func (r *EmbeddingRanker) chunks(ctx context.Context) ([]indexChunk, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.sync(ctx); err != nil {
+		return nil, err
+	}
+	return r.index.Chunks, nil
+}
+
+// sync loads the index on first use and re-indexes every memory file on the
+// first call, or only the dirty ones afterwards. Callers hold r.mu.
+func (r *EmbeddingRanker) sync(ctx context.Context) error {
+	if r.index == nil {
+		r.index = r.load()
+	}
+	var files []string
+	if !r.synced {
+		names, err := r.store.ListFiles()
+		if err != nil {
+			return err
+		}
+		set := map[string]bool{}
+		for _, n := range names {
+			if isValidMemoryFile(n) {
+				set[n] = true
+			}
+		}
+		for _, c := range r.index.Chunks {
+			set[c.Source] = true // pick up files deleted while we were not running
+		}
+		for n := range set {
+			files = append(files, n)
+		}
+	} else {
+		for n := range r.dirty {
+			files = append(files, n)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	sort.Strings(files)
+
+	known := make(map[string][]float32, len(r.index.Chunks))
+	for _, c := range r.index.Chunks {
+		known[c.Hash] = c.Vector
+	}
+	refresh := make(map[string]bool, len(files))
+	var fresh []indexChunk
+	var texts []string
+	var pending []int
+	for _, name := range files {
+		refresh[name] = true
+		content, err := r.store.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		for _, c := range chunkMemoryFile(name, content) {
+			if v, ok := known[c.Hash]; ok {
+				c.Vector = v
+			} else {
+				texts = append(texts, c.Text)
+				pending = append(pending, len(fresh))
+			}
+			fresh = append(fresh, c)
+		}
+	}
+	if len(texts) > 0 {
+		vecs, err := r.embed(ctx, texts)
+		if err != nil {
+			return err
+		}
+		for i, idx := range pending {
+			fresh[idx].Vector = vecs[i]
+		}
+	}
+
+	kept := r.index.Chunks[:0:0]
+	for _, c := range r.index.Chunks {
+		if !refresh[c.Source] {
+			kept = append(kept, c)
+		}
+	}
+	r.index.Chunks = append(kept, fresh...)
+	r.synced = true
+	r.dirty = map[string]bool{}
+	if err := r.save(); err != nil {
+		log.Printf("memory: error saving vector index: %v", err)
+	}
+	return nil
+}
+
+// embed calls the embedder for texts, a batch at a time, and checks it
+// returned one vector per text.
+func (r *EmbeddingRanker) embed(ctx context.Context, texts []string) ([][]float32, error) {
+	vecs := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += embedBatchSize {
+		batch := texts[start:min(start+embedBatchSize, len(texts))]
+		bctx, cancel := context.WithTimeout(ctx, embedTimeout)
+		out, err := r.embedder.Embed(bctx, batch)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if len(out) != len(batch) {
+			return nil, fmt.Errorf("embedder returned %d vectors for %d texts", len(out), len(batch))
+		}
+		vecs = append(vecs, out...)
+	}
+	return vecs, nil
+}
+
+// load reads the persisted index. A missing or damaged index, or one built
+// with a different model, starts out empty and is rebuilt.
+func (r *EmbeddingRanker) load() *vectorIndex {
+	empty := &vectorIndex{Model: r.embedder.Model()}
+	b, err := os.ReadFile(r.indexPath)
+	if err != nil {
+		return empty
+	}
+	var idx vectorIndex
+	if err := json.Unmarshal(b, &idx); err != nil {
+		log.Printf("memory: ignoring damaged vector index %s: %v", r.indexPath, err)
+		return empty
+	}
+	if idx.Model != empty.Model {
+		return empty
+	}
+	return &idx
+}
+
+// save writes the index to disk atomically.
+func (r *EmbeddingRanker) save() error {
+	b, err := json.Marshal(r.index)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(r.indexPath, b)
+}
+
+// writeFileAtomic writes b to a temporary file next to path and renames it
+// into place, so readers never see a partial file.
+func writeFileAtomic(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// chunkMemoryFile splits a memory file into indexable chunks: one per line of
+// a dated note, with the "[timestamp] " prefix moved into Time, and one per
+// paragraph of MEMORY.md. Line is the 1-based line the chunk starts on.
+func chunkMemoryFile(name, content string) []indexChunk {
+	day, _ := time.Parse("2006-01-02", strings.TrimSuffix(name, ".md"))
+	var out []indexChunk
+	add := func(line int, text string) {
+		text = strings.TrimSpace(text)
+		when := day
+		if strings.HasPrefix(text, "[") {
+			if end := strings.Index(text, "] "); end > 0 {
+				if ts, err := time.Parse(time.RFC3339, text[1:end]); err == nil {
+					when, text = ts, strings.TrimSpace(text[end+2:])
+				}
+			}
+		}
+		if text == "" || strings.Trim(text, "#-* ") == "" {
+			return
+		}
+		out = append(out, indexChunk{Source: name, Line: line, Hash: hashText(text), Text: text, Time: when})
+	}
+
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if name != "MEMORY.md" {
+		for i, l := range lines {
+			add(i+1, l)
+		}
+		return out
+	}
+	start := 0
+	var para []string
+	for i, l := range lines {
+		if strings.TrimSpace(l) == "" {
+			add(start+1, strings.Join(para, "\n"))
+			para = nil
+			continue
+		}
+		if para == nil {
+			start = i
+		}
+		para = append(para, l)
+	}
+	add(start+1, strings.Join(para, "\n"))
+	return out
+}
+
+func hashText(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:16])
+}
+
+// cosine returns the cosine similarity of a and b, or 0 when either is empty
+// or their lengths differ.
+func cosine(a, b []float32) float64 {
+	if len(a) == 0 || len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
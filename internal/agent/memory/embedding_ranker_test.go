@@ -0,0 +1,192 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bagEmbedder embeds texts as hashed bags of words and records what it was
+// asked to embed.
+type bagEmbedder struct {
+	model    string
+	embedded []string
+}
+
+func (b *bagEmbedder) Model() string { return b.model }
+
+func (b *bagEmbedder) Embed(_ context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		v := make([]float32, 1024)
+		for _, tok := range tokenize(t) {
+			h := fnv.New32a()
+			h.Write([]byte(tok))
+			v[h.Sum32()%1024]++
+		}
+		out[i] = v
+	}
+	b.embedded = append(b.embedded, texts...)
+	return out, nil
+}
+
+func TestEmbeddingRankerSearchesOldNotes(t *testing.T) {
+	ws := t.TempDir()
+	ms := NewMemoryStoreWithWorkspace(ws, 10)
+	ms.WriteFile("2026-01-05.md", "[2026-01-05T09:00:00Z] dentist appointment moved to friday\n[2026-01-05T10:00:00Z] bought oat milk\n")
+	ms.WriteLongTerm("# Memory\n\nUser is allergic to peanuts.\n\nUser lives in Lisbon.")
+	emb := &bagEmbedder{model: "m1"}
+	indexPath := filepath.Join(ws, "index", "memory.json")
+	r := NewEmbeddingRanker(emb, ms, indexPath)
+
+	got := r.Rank("when is the dentist appointment", []MemoryItem{{Kind: "short", Text: "weather is nice"}}, 2)
+	if len(got) != 2 || got[0].Text != "dentist appointment moved to friday" || got[0].Kind != "2026-01-05.md" {
+		t.Fatalf("expected the old dentist note first, got %+v", got)
+	}
+	if got[0].Timestamp.Hour() != 9 {
+		t.Fatalf("expected the note timestamp to be parsed, got %v", got[0].Timestamp)
+	}
+	if got := r.Rank("peanuts allergy", nil, 1); len(got) != 1 || got[0].Text != "User is allergic to peanuts." {
+		t.Fatalf("expected the MEMORY.md paragraph, got %+v", got)
+	}
+	if _, err := os.Stat(indexPath); err != nil {
+		t.Fatalf("expected the index to be persisted: %v", err)
+	}
+
+	// only the appended line is embedded again
+	emb.embedded = nil
+	ms.AppendToday("picked up the bike from the repair shop")
+	got = r.Rank("bike repair", nil, 1)
+	if len(emb.embedded) != 2 || emb.embedded[0] != "picked up the bike from the repair shop" || emb.embedded[1] != "bike repair" {
+		t.Fatalf("expected only the new line and the query to be embedded, got %q", emb.embedded)
+	}
+	if got[0].Text != "picked up the bike from the repair shop" {
+		t.Fatalf("expected the appended note, got %+v", got)
+	}
+
+	// a fresh ranker reuses the persisted vectors
+	emb2 := &bagEmbedder{model: "m1"}
+	r2 := NewEmbeddingRanker(emb2, ms, indexPath)
+	r2.Rank("lisbon", nil, 1)
+	if len(emb2.embedded) != 1 {
+		t.Fatalf("expected no chunks to be re-embedded, got %q", emb2.embedded)
+	}
+
+	// deleting a file drops its chunks
+	today, _ := ms.ListFiles()
+	for _, name := range today {
+		if name != "MEMORY.md" && name != "2026-01-05.md" {
+			ms.DeleteFile(name)
+		}
+	}
+	for _, m := range r.Rank("bike repair", nil, 0) {
+		if m.Text == "picked up the bike from the repair shop" {
+			t.Fatalf("expected deleted note to be dropped from the index")
+		}
+	}
+}
+
+// batchEmbedder records the size of every Embed call and how long its
+// context leaves it to answer.
+type batchEmbedder struct {
+	bagEmbedder
+	sizes []int
+	left  []time.Duration
+}
+
+func (b *batchEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	deadline, _ := ctx.Deadline()
+	b.sizes = append(b.sizes, len(texts))
+	b.left = append(b.left, time.Until(deadline))
+	time.Sleep(10 * time.Millisecond)
+	return b.bagEmbedder.Embed(ctx, texts)
+}
+
+func TestEmbeddingRankerTimesOutPerBatch(t *testing.T) {
+	ws := t.TempDir()
+	ms := NewMemoryStoreWithWorkspace(ws, 10)
+	var note strings.Builder
+	for i := 0; i < 2*embedBatchSize+10; i++ {
+		fmt.Fprintf(&note, "note number %d\n", i)
+	}
+	ms.WriteFile("2026-01-05.md", note.String())
+	emb := &batchEmbedder{bagEmbedder: bagEmbedder{model: "m"}}
+	r := NewEmbeddingRanker(emb, ms, filepath.Join(ws, "index", "memory.json"))
+
+	if got := r.Rank("note number 7", nil, 1); len(got) != 1 {
+		t.Fatalf("expected a note from the index, got %+v", got)
+	}
+	// three batches for the notes, one for the query
+	if len(emb.sizes) != 4 || emb.sizes[0] != embedBatchSize || emb.sizes[2] != 10 {
+		t.Fatalf("expected the notes to be embedded in batches of %d, got %v", embedBatchSize, emb.sizes)
+	}
+	for i, left := range emb.left {
+		if left < embedTimeout-time.Second {
+			t.Fatalf("batch %d had %s left; expected a fresh %s per batch", i, left, embedTimeout)
+		}
+	}
+}
+
+func TestEmbeddingRankerModelChangeRebuilds(t *testing.T) {
+	ws := t.TempDir()
+	ms := NewMemoryStoreWithWorkspace(ws, 10)
+	ms.WriteLongTerm("User likes tea.")
+	indexPath := filepath.Join(ws, "index", "memory.json")
+	NewEmbeddingRanker(&bagEmbedder{model: "m1"}, ms, indexPath).Rank("tea", nil, 1)
+
+	emb := &bagEmbedder{model: "m2"}
+	NewEmbeddingRanker(emb, ms, indexPath).Rank("tea", nil, 1)
+	if len(emb.embedded) != 2 || emb.embedded[0] != "User likes tea." {
+		t.Fatalf("expected the index to be rebuilt for a new model, got %q", emb.embedded)
+	}
+}
+
+func TestEmbeddingRankerCacheFollowsItems(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	emb := &bagEmbedder{model: "m1"}
+	r := NewEmbeddingRanker(emb, ms, filepath.Join(t.TempDir(), "memory.json"))
+
+	tea, bike := MemoryItem{Kind: "short", Text: "likes tea"}, MemoryItem{Kind: "short", Text: "fixed the bike"}
+	r.Rank("tea", []MemoryItem{tea, bike}, 1)
+	emb.embedded = nil
+	r.Rank("tea", []MemoryItem{bike, {Kind: "short", Text: "moved to Lisbon"}}, 1)
+	if len(emb.embedded) != 2 || emb.embedded[1] != "moved to Lisbon" {
+		t.Fatalf("expected only the query and the new item to be embedded, got %q", emb.embedded)
+	}
+	if len(r.cache) != 2 {
+		t.Fatalf("expected the cache to hold only the last call's items, got %d vectors", len(r.cache))
+	}
+	if _, ok := r.cache[hashText(tea.Text)]; ok {
+		t.Fatal("expected the vector of an item no longer passed in to be dropped")
+	}
+}
+
+func TestEmbeddingRankerScopes(t *testing.T) {
+	ws := t.TempDir()
+	ms := NewMemoryStoreWithWorkspace(ws, 10)
+	ms.WriteLongTerm("The boiler is serviced every october.")
+	alice := ms.Scope("slack:alice")
+	alice.AppendToday("alice prefers window seats on trains")
+
+	r := NewEmbeddingRanker(&bagEmbedder{model: "m"}, ms, filepath.Join(ws, "index", "memory.json"))
+	if r.For(ms) != r || r.For(alice) != r.For(alice) {
+		t.Fatal("expected For to return r for the shared tier and cache scoped rankers")
+	}
+	got := r.For(alice).Rank("window seats trains boiler", nil, 0)
+	if len(got) != 2 {
+		t.Fatalf("expected personal and shared chunks, got %+v", got)
+	}
+	for _, m := range r.Rank("window seats trains", nil, 0) {
+		if strings.Contains(m.Text, "window") {
+			t.Fatalf("expected the shared ranker not to see scoped notes, got %+v", m)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(ws, "index", "scopes", "slack%3Aalice.json")); err != nil {
+		t.Fatalf("expected a separate index for the scope: %v", err)
+	}
+}
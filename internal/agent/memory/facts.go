@@ -0,0 +1,417 @@
+package memory
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Confidence levels of a fact.
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// factsDir holds the fact file of a memory tier; being a directory, it is
+// never listed among the memory files.
+const factsDir = "facts"
+
+// Outcomes of FactStore.Upsert.
+const (
+	FactCreated   = "stored"
+	FactUpdated   = "updated"
+	FactConfirmed = "confirmed"
+)
+
+// maxSourceRunes bounds the message excerpt kept as a fact's provenance.
+const maxSourceRunes = 200
+
+// FactRecord is one structured fact: Subject Predicate Object, e.g. "user"
+// "city" "Lisbon". Key/value facts use the subject "user" and the key as the
+// predicate. A subject holds one value per predicate, so storing a new city
+// replaces the old one instead of contradicting it.
+type FactRecord struct {
+	ID         string    `json:"id"`
+	Subject    string    `json:"subject"`
+	Predicate  string    `json:"predicate"`
+	Object     string    `json:"object"`
+	Tags       []string  `json:"tags,omitempty"`
+	Confidence string    `json:"confidence"`         // ConfidenceLow, ConfidenceMedium or ConfidenceHigh
+	Session    string    `json:"session,omitempty"`  // session the fact was last stated in, e.g. "telegram:123"
+	Message    string    `json:"message,omitempty"`  // excerpt of the message that stated it
+	Previous   string    `json:"previous,omitempty"` // object before the last change
+	Created    time.Time `json:"created"`
+	Confirmed  time.Time `json:"confirmed"` // last time the fact was stated or confirmed
+	Shared     bool      `json:"-"`         // found in the shared tier while querying a scoped store
+}
+
+// FactQuery selects facts. Empty fields match everything; Subject, Predicate
+// and Tag match case-insensitively, Text matches a substring of any field.
+type FactQuery struct {
+	Subject   string
+	Predicate string
+	Tag       string
+	Text      string
+	Limit     int // <= 0 returns every match
+}
+
+// ValidConfidence reports whether c is a known confidence level ("" means
+// medium).
+func ValidConfidence(c string) bool {
+	switch c {
+	case "", ConfidenceLow, ConfidenceMedium, ConfidenceHigh:
+		return true
+	}
+	return false
+}
+
+type sourceKey struct{}
+
+type source struct {
+	session string
+	message string
+}
+
+// WithSource returns a context recording the session and message a request
+// came from, kept as the provenance of the facts stored while handling it.
+func WithSource(ctx context.Context, session, message string) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source{session: session, message: message})
+}
+
+// SourceFrom returns the session and message stored by WithSource.
+func SourceFrom(ctx context.Context) (session, message string) {
+	src, _ := ctx.Value(sourceKey{}).(source)
+	return src.session, src.message
+}
+
+// FactStore keeps the facts of one memory tier in <memory dir>/facts/facts.json.
+// The file is loaded on first use and rewritten atomically on every change.
+type FactStore struct {
+	path   string
+	mu     sync.Mutex
+	loaded bool
+	facts  []FactRecord
+}
+
+// Facts returns the fact store of s's tier (the scope's own facts for a scoped
+// store).
+func (s *MemoryStore) Facts() *FactStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.facts == nil {
+		s.facts = &FactStore{path: filepath.Join(s.memoryDir, factsDir, "facts.json")}
+	}
+	return s.facts
+}
+
+// Upsert stores f under its subject and predicate. An existing fact with the
+// same key is confirmed when the object is unchanged, or updated with the old
+// object kept in Previous. Tags are merged; an empty confidence keeps the old
+// level (medium for a new fact). It returns the stored fact and what happened
+// to it: FactCreated, FactUpdated or FactConfirmed.
+func (fs *FactStore) Upsert(f FactRecord) (FactRecord, string, error) {
+	f.Subject = strings.TrimSpace(f.Subject)
+	f.Predicate = strings.TrimSpace(f.Predicate)
+	f.Object = strings.TrimSpace(f.Object)
+	if f.Subject == "" || f.Predicate == "" || f.Object == "" {
+		return FactRecord{}, "", fmt.Errorf("fact needs a subject, predicate and object")
+	}
+	if !ValidConfidence(f.Confidence) {
+		return FactRecord{}, "", fmt.Errorf("unknown confidence %q (want low, medium or high)", f.Confidence)
+	}
+	f.Message = truncateRunes(strings.Join(strings.Fields(f.Message), " "), maxSourceRunes)
+	now := time.Now().UTC()
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.load(); err != nil {
+		return FactRecord{}, "", err
+	}
+	id := factID(f.Subject, f.Predicate)
+	for i := range fs.facts {
+		old := &fs.facts[i]
+		if old.ID != id {
+			continue
+		}
+		outcome := FactConfirmed
+		if normalizeKey(old.Object) != normalizeKey(f.Object) {
+			old.Previous = old.Object
+			outcome = FactUpdated
+		}
+		old.Subject, old.Predicate, old.Object = f.Subject, f.Predicate, f.Object
+		old.Tags = mergeTags(old.Tags, f.Tags)
+		if f.Confidence != "" {
+			old.Confidence = f.Confidence
+		}
+		if f.Session != "" || f.Message != "" {
+			old.Session, old.Message = f.Session, f.Message
+		}
+		old.Confirmed = now
+		return *old, outcome, fs.save()
+	}
+	f.ID = id
+	f.Tags = mergeTags(nil, f.Tags)
+	if f.Confidence == "" {
+		f.Confidence = ConfidenceMedium
+	}
+	f.Previous = ""
+	f.Created, f.Confirmed = now, now
+	fs.facts = append(fs.facts, f)
+	return f, FactCreated, fs.save()
+}
+
+// Get returns the fact with the given id.
+func (fs *FactStore) Get(id string) (FactRecord, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.load(); err != nil {
+		return FactRecord{}, false, err
+	}
+	for _, f := range fs.facts {
+		if f.ID == id {
+			return f, true, nil
+		}
+	}
+	return FactRecord{}, false, nil
+}
+
+// Lookup returns the fact stored for subject and predicate.
+func (fs *FactStore) Lookup(subject, predicate string) (FactRecord, bool, error) {
+	return fs.Get(factID(subject, predicate))
+}
+
+// Retract removes the fact with the given id, returning it. ok is false if
+// there was no such fact.
+func (fs *FactStore) Retract(id string) (FactRecord, bool, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.load(); err != nil {
+		return FactRecord{}, false, err
+	}
+	for i, f := range fs.facts {
+		if f.ID == id {
+			fs.facts = append(fs.facts[:i], fs.facts[i+1:]...)
+			return f, true, fs.save()
+		}
+	}
+	return FactRecord{}, false, nil
+}
+
+// Query returns the facts matching q, most recently confirmed first.
+func (fs *FactStore) Query(q FactQuery) ([]FactRecord, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if err := fs.load(); err != nil {
+		return nil, err
+	}
+	var out []FactRecord
+	for _, f := range fs.facts {
+		if q.matches(f) {
+			f.Tags = append([]string(nil), f.Tags...)
+			out = append(out, f)
+		}
+	}
+	sortFacts(out)
+	if q.Limit > 0 && len(out) > q.Limit {
+		out = out[:q.Limit]
+	}
+	return out, nil
+}
+
+// load reads the fact file on first use. Callers hold fs.mu.
+func (fs *FactStore) load() error {
+	if fs.loaded {
+		return nil
+	}
+	b, err := os.ReadFile(fs.path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, &fs.facts); err != nil {
+			return fmt.Errorf("reading %s: %w", fs.path, err)
+		}
+	}
+	fs.loaded = true
+	return nil
+}
+
+// save writes the facts to disk. Callers hold fs.mu.
+func (fs *FactStore) save() error {
+	b, err := json.MarshalIndent(fs.facts, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(fs.path, b)
+}
+
+func (q FactQuery) matches(f FactRecord) bool {
+	if q.Subject != "" && normalizeKey(q.Subject) != normalizeKey(f.Subject) {
+		return false
+	}
+	if q.Predicate != "" && normalizeKey(q.Predicate) != normalizeKey(f.Predicate) {
+		return false
+	}
+	if q.Tag != "" {
+		found := false
+		for _, t := range f.Tags {
+			if strings.EqualFold(t, strings.TrimSpace(q.Tag)) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if q.Text != "" {
+		all := strings.ToLower(strings.Join(append([]string{f.Subject, f.Predicate, f.Object}, f.Tags...), " "))
+		if !strings.Contains(all, strings.ToLower(strings.TrimSpace(q.Text))) {
+			return false
+		}
+	}
+	return true
+}
+
+// QueryFacts runs q against the facts of s. A scoped store also queries the
+// shared tier, marking those facts Shared; its own facts win when both hold
+// the same subject and predicate.
+func (s *MemoryStore) QueryFacts(q FactQuery) ([]FactRecord, error) {
+	limit := q.Limit
+	q.Limit = 0
+	out, err := s.Facts().Query(q)
+	if err != nil {
+		return nil, err
+	}
+	if s.parent != nil {
+		shared, err := s.parent.Facts().Query(q)
+		if err != nil {
+			return nil, err
+		}
+		own := make(map[string]bool, len(out))
+		for _, f := range out {
+			own[f.ID] = true
+		}
+		for _, f := range shared {
+			if !own[f.ID] {
+				f.Shared = true
+				out = append(out, f)
+			}
+		}
+		sortFacts(out)
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// FactSheet renders up to n of the most recently confirmed facts visible to s
+// as a compact sheet, one line per subject:
+//
+//	user: city = Lisbon; diet = vegetarian (low confidence)
+//	Ana: birthday = May 3; relation = sister of user
+//
+// It returns "" when there are no facts.
+func (s *MemoryStore) FactSheet(n int) (string, error) {
+	facts, err := s.QueryFacts(FactQuery{})
+	if err != nil || len(facts) == 0 {
+		return "", err
+	}
+	more := 0
+	if n > 0 && len(facts) > n {
+		more = len(facts) - n
+		facts = facts[:n]
+	}
+
+	bySubject := map[string][]FactRecord{}
+	var subjects []string
+	for _, f := range facts {
+		key := normalizeKey(f.Subject)
+		if _, ok := bySubject[key]; !ok {
+			subjects = append(subjects, key)
+		}
+		bySubject[key] = append(bySubject[key], f)
+	}
+	// the user comes first, then subjects in alphabetical order
+	sort.Slice(subjects, func(i, j int) bool {
+		if (subjects[i] == "user") != (subjects[j] == "user") {
+			return subjects[i] == "user"
+		}
+		return subjects[i] < subjects[j]
+	})
+
+	var sb strings.Builder
+	for _, key := range subjects {
+		fs := bySubject[key]
+		sort.Slice(fs, func(i, j int) bool { return normalizeKey(fs[i].Predicate) < normalizeKey(fs[j].Predicate) })
+		parts := make([]string, len(fs))
+		for i, f := range fs {
+			parts[i] = f.Predicate + " = " + f.Object
+			if f.Confidence == ConfidenceLow {
+				parts[i] += " (low confidence)"
+			}
+		}
+		fmt.Fprintf(&sb, "%s: %s\n", fs[0].Subject, strings.Join(parts, "; "))
+	}
+	if more > 0 {
+		fmt.Fprintf(&sb, "(%d older facts not shown; use query_facts)\n", more)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// sortFacts orders facts most recently confirmed first, then by key.
+func sortFacts(fs []FactRecord) {
+	sort.Slice(fs, func(i, j int) bool {
+		if !fs[i].Confirmed.Equal(fs[j].Confirmed) {
+			return fs[i].Confirmed.After(fs[j].Confirmed)
+		}
+		if fs[i].Shared != fs[j].Shared {
+			return !fs[i].Shared
+		}
+		return fs[i].ID < fs[j].ID
+	})
+}
+
+// factID derives a stable id from the normalized subject and predicate, so
+// the same key always maps to the same fact.
+func factID(subject, predicate string) string {
+	sum := sha256.Sum256([]byte(normalizeKey(subject) + "\x00" + normalizeKey(predicate)))
+	return hex.EncodeToString(sum[:4])
+}
+
+// normalizeKey lowercases s and collapses its whitespace.
+func normalizeKey(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// mergeTags returns the union of a and b, lowercased, in first-seen order.
+func mergeTags(a, b []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, t := range append(append([]string(nil), a...), b...) {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t != "" && !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func truncateRunes(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "…"
+}
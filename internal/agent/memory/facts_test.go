@@ -0,0 +1,123 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFactUpsertConfirmsAndUpdates(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	fs := ms.Facts()
+
+	f, outcome, err := fs.Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "Porto", Tags: []string{"Home"}, Session: "telegram:1", Message: "I live in   Porto"})
+	if err != nil || outcome != FactCreated {
+		t.Fatalf("Upsert: %v %q", err, outcome)
+	}
+	if f.Confidence != ConfidenceMedium || f.Message != "I live in Porto" || len(f.Tags) != 1 || f.Tags[0] != "home" {
+		t.Fatalf("unexpected new fact %+v", f)
+	}
+
+	if _, outcome, _ := fs.Upsert(FactRecord{Subject: "User", Predicate: "City", Object: "porto", Confidence: ConfidenceHigh}); outcome != FactConfirmed {
+		t.Fatalf("expected the same value to confirm the fact, got %q", outcome)
+	}
+	f, outcome, err = fs.Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "Lisbon", Tags: []string{"moved"}, Session: "telegram:1", Message: "we moved to Lisbon"})
+	if err != nil || outcome != FactUpdated {
+		t.Fatalf("expected an update, got %q %v", outcome, err)
+	}
+	if f.Object != "Lisbon" || f.Previous != "porto" || f.Confidence != ConfidenceHigh || strings.Join(f.Tags, ",") != "home,moved" || f.Message != "we moved to Lisbon" {
+		t.Fatalf("unexpected updated fact %+v", f)
+	}
+	if all, _ := fs.Query(FactQuery{}); len(all) != 1 {
+		t.Fatalf("expected one fact per subject and predicate, got %+v", all)
+	}
+
+	if _, _, err := fs.Upsert(FactRecord{Subject: "user", Predicate: "city"}); err == nil {
+		t.Fatal("expected a fact without an object to be rejected")
+	}
+	if _, _, err := fs.Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "x", Confidence: "certain"}); err == nil {
+		t.Fatal("expected an unknown confidence to be rejected")
+	}
+
+	// facts survive a restart
+	got, ok, err := NewMemoryStoreWithWorkspace(ms.workspace, 10).Facts().Lookup("user", "city")
+	if err != nil || !ok || got.Object != "Lisbon" || got.Session != "telegram:1" || got.Created.IsZero() {
+		t.Fatalf("expected the fact to be persisted, got %+v %v %v", got, ok, err)
+	}
+}
+
+func TestFactQueryAndRetract(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	fs := ms.Facts()
+	fs.Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "Lisbon"})
+	fs.Upsert(FactRecord{Subject: "Ana", Predicate: "relation", Object: "sister of user", Tags: []string{"family"}})
+	fs.Upsert(FactRecord{Subject: "Ana", Predicate: "birthday", Object: "May 3", Tags: []string{"family", "dates"}})
+
+	if res, _ := fs.Query(FactQuery{Subject: "ana"}); len(res) != 2 {
+		t.Fatalf("expected Ana's two facts, got %+v", res)
+	}
+	if res, _ := fs.Query(FactQuery{Tag: "Dates"}); len(res) != 1 || res[0].Predicate != "birthday" {
+		t.Fatalf("expected the birthday by tag, got %+v", res)
+	}
+	if res, _ := fs.Query(FactQuery{Text: "lisbon"}); len(res) != 1 || res[0].Subject != "user" {
+		t.Fatalf("expected the city by text, got %+v", res)
+	}
+	if res, _ := fs.Query(FactQuery{Limit: 2}); len(res) != 2 {
+		t.Fatalf("expected the limit to apply, got %+v", res)
+	}
+
+	f, _, _ := fs.Lookup("Ana", "birthday")
+	if got, ok, err := fs.Retract(f.ID); err != nil || !ok || got.Object != "May 3" {
+		t.Fatalf("Retract: %+v %v %v", got, ok, err)
+	}
+	if _, ok, _ := fs.Retract(f.ID); ok {
+		t.Fatal("expected a second retract to find nothing")
+	}
+	if res, _ := NewMemoryStoreWithWorkspace(ms.workspace, 10).Facts().Query(FactQuery{}); len(res) != 2 {
+		t.Fatalf("expected the retraction to be persisted, got %+v", res)
+	}
+}
+
+func TestFactSheet(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	if sheet, err := ms.FactSheet(10); err != nil || sheet != "" {
+		t.Fatalf("expected an empty sheet, got %q %v", sheet, err)
+	}
+	fs := ms.Facts()
+	fs.Upsert(FactRecord{Subject: "Ana", Predicate: "relation", Object: "sister of user"})
+	fs.Upsert(FactRecord{Subject: "user", Predicate: "diet", Object: "vegetarian", Confidence: ConfidenceLow})
+	fs.Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "Lisbon"})
+
+	sheet, err := ms.FactSheet(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "user: city = Lisbon; diet = vegetarian (low confidence)\nAna: relation = sister of user"
+	if sheet != want {
+		t.Fatalf("unexpected sheet:\n%s\nwant:\n%s", sheet, want)
+	}
+	if sheet, _ := ms.FactSheet(2); strings.Contains(sheet, "Ana") || !strings.Contains(sheet, "1 older facts not shown") {
+		t.Fatalf("expected the oldest fact to be left out, got:\n%s", sheet)
+	}
+}
+
+func TestScopedFacts(t *testing.T) {
+	root := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	root.Facts().Upsert(FactRecord{Subject: "family", Predicate: "dinner", Object: "Sundays"})
+	root.Facts().Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "Porto"})
+	alice := root.Scope("telegram:alice")
+	alice.Facts().Upsert(FactRecord{Subject: "user", Predicate: "city", Object: "Lisbon"})
+
+	res, err := alice.QueryFacts(FactQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 2 || res[0].Object != "Lisbon" || res[0].Shared || res[1].Object != "Sundays" || !res[1].Shared {
+		t.Fatalf("expected alice's city to override the shared one, got %+v", res)
+	}
+	if sheet, _ := root.Scope("telegram:bob").FactSheet(10); !strings.Contains(sheet, "city = Porto") || strings.Contains(sheet, "Lisbon") {
+		t.Fatalf("bob should only see shared facts, got:\n%s", sheet)
+	}
+	if files, _ := alice.ListFiles(); len(files) != 0 {
+		t.Fatalf("the fact file should not be listed as a memory file, got %v", files)
+	}
+}
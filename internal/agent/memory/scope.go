@@ -0,0 +1,147 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/local/picobot/internal/session"
+)
+
+// Scope modes select how memory is partitioned between the people talking to
+// the agent.
+const (
+	ScopeGlobal = "global" // one memory shared by everyone (default)
+	ScopeSender = "sender" // one memory per user on each channel
+	ScopeChat   = "chat"   // one memory per chat (group or DM) on each channel
+)
+
+// scopesDir holds one subdirectory of memory files per scope.
+const scopesDir = "scopes"
+
+// ValidScopeMode reports whether mode is a known scope mode ("" means global).
+func ValidScopeMode(mode string) bool {
+	switch mode {
+	case "", ScopeGlobal, ScopeSender, ScopeChat:
+		return true
+	}
+	return false
+}
+
+// ScopeID returns the memory scope a message belongs to under mode:
+// "channel:sender" for ScopeSender, "channel:chatID" for ScopeChat and "" (the
+// shared tier) for ScopeGlobal or when the identifying field is empty.
+func ScopeID(mode, channel, senderID, chatID string) string {
+	var id string
+	switch mode {
+	case ScopeSender:
+		id = senderID
+	case ScopeChat:
+		id = chatID
+	}
+	if id == "" {
+		return ""
+	}
+	return channel + ":" + id
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context carrying the memory scope of a request, so the
+// memory tools act on the memory of whoever sent it.
+func WithScope(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, scopeKey{}, id)
+}
+
+// ScopeFrom returns the scope stored by WithScope, or "" (the shared tier).
+func ScopeFrom(ctx context.Context) string {
+	id, _ := ctx.Value(scopeKey{}).(string)
+	return id
+}
+
+// Scope returns the store holding the memory of scope id, kept in
+// memory/scopes/<encoded id>/ with s as its shared tier. The empty id returns
+// s itself. Stores are created once per id and reused.
+func (s *MemoryStore) Scope(id string) *MemoryStore {
+	root := s.Shared()
+	if id == "" {
+		return root
+	}
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	if child, ok := root.scopes[id]; ok {
+		return child
+	}
+	child := &MemoryStore{
+		workspace: root.workspace,
+		memoryDir: filepath.Join(root.memoryDir, scopesDir, session.FileName(id)),
+		limit:     root.limit,
+		short:     make([]MemoryItem, 0, root.limit),
+		long:      make([]MemoryItem, 0),
+		search:    newSearchIndex(),
+		parent:    root,
+		scope:     id,
+	}
+	if root.scopes == nil {
+		root.scopes = map[string]*MemoryStore{}
+	}
+	root.scopes[id] = child
+	return child
+}
+
+// For returns the store for the scope carried by ctx (see WithScope).
+func (s *MemoryStore) For(ctx context.Context) *MemoryStore {
+	return s.Scope(ScopeFrom(ctx))
+}
+
+// Shared returns the shared tier: the store s was scoped from, or s itself.
+func (s *MemoryStore) Shared() *MemoryStore {
+	if s.parent != nil {
+		return s.parent
+	}
+	return s
+}
+
+// ScopeID returns the scope of s, or "" for the shared tier.
+func (s *MemoryStore) ScopeID() string { return s.scope }
+
+// Scopes returns the ids of every scope that has memory files on disk.
+func (s *MemoryStore) Scopes() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(s.Shared().memoryDir, scopesDir))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var ids []string
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		if id, ok := session.DecodeFileName(e.Name()); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// scopedContext joins the shared tier's memory context with the scope's own.
+func (s *MemoryStore) scopedContext(own string) (string, error) {
+	shared, err := s.parent.GetMemoryContext()
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	if shared != "" {
+		parts = append(parts, "Shared memory:\n"+shared)
+	}
+	if own != "" {
+		parts = append(parts, fmt.Sprintf("Memory of %s:\n%s", s.scope, own))
+	}
+	return strings.Join(parts, "\n\n---\n\n"), nil
+}
@@ -0,0 +1,72 @@
+package memory
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScopeID(t *testing.T) {
+	cases := []struct{ mode, want string }{
+		{"", ""},
+		{ScopeGlobal, ""},
+		{ScopeSender, "telegram:u1"},
+		{ScopeChat, "telegram:c9"},
+	}
+	for _, c := range cases {
+		if got := ScopeID(c.mode, "telegram", "u1", "c9"); got != c.want {
+			t.Errorf("ScopeID(%q) = %q, want %q", c.mode, got, c.want)
+		}
+	}
+	if ValidScopeMode("team") {
+		t.Error("expected unknown modes to be rejected")
+	}
+}
+
+func TestScopedStoresAreIsolated(t *testing.T) {
+	ws := t.TempDir()
+	root := NewMemoryStoreWithWorkspace(ws, 10)
+	root.WriteLongTerm("The household wifi is called piconet.")
+	alice := root.Scope("telegram:alice")
+	bob := root.Scope("telegram:bob")
+	if root.Scope("telegram:alice") != alice || alice.Scope("") != root || alice.Shared() != root {
+		t.Fatal("expected scoped stores to be cached and to share the root")
+	}
+
+	alice.AppendToday("password hint is the first cat")
+	if _, err := os.Stat(filepath.Join(ws, "memory", "scopes", "telegram%3Aalice")); err != nil {
+		t.Fatalf("expected alice's memory under memory/scopes: %v", err)
+	}
+
+	ctxA, _ := alice.GetMemoryContext()
+	if !strings.Contains(ctxA, "piconet") || !strings.Contains(ctxA, "first cat") {
+		t.Fatalf("expected shared and personal memory for alice, got %q", ctxA)
+	}
+	ctxB, _ := bob.GetMemoryContext()
+	if strings.Contains(ctxB, "first cat") || !strings.Contains(ctxB, "piconet") {
+		t.Fatalf("expected only shared memory for bob, got %q", ctxB)
+	}
+	if rootCtx, _ := root.GetMemoryContext(); strings.Contains(rootCtx, "first cat") {
+		t.Fatalf("expected scoped notes to stay out of the shared tier, got %q", rootCtx)
+	}
+	if files, _ := root.ListFiles(); len(files) != 1 || files[0] != "MEMORY.md" {
+		t.Fatalf("expected the shared tier to list only its own files, got %v", files)
+	}
+
+	res, _ := alice.Search("wifi hint", 0)
+	if len(res) != 2 || res[0].Shared == res[1].Shared {
+		t.Fatalf("expected one personal and one shared result, got %+v", res)
+	}
+	if res, _ := bob.Search("hint", 0); len(res) != 0 {
+		t.Fatalf("expected bob not to find alice's notes, got %+v", res)
+	}
+
+	if ids, _ := root.Scopes(); len(ids) != 1 || ids[0] != "telegram:alice" {
+		t.Fatalf("expected alice's scope on disk, got %v", ids)
+	}
+	if got := root.For(WithScope(context.Background(), "telegram:alice")); got != alice {
+		t.Fatal("expected For to resolve the scope from the context")
+	}
+}
@@ -0,0 +1,268 @@
+package memory
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// BM25 parameters: k1 controls term-frequency saturation, b how strongly
+// long chunks are penalised.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	snippetRunes = 200
+)
+
+// SearchResult is one match returned by MemoryStore.Search.
+type SearchResult struct {
+	File    string    // memory file name, e.g. "2026-01-05.md" or "MEMORY.md"
+	Line    int       // 1-based line the matching chunk starts on
+	Time    time.Time // note timestamp, or the file's date
+	Score   float64
+	Snippet string
+	Shared  bool // found in the shared tier while searching a scoped store
+}
+
+// searchIndex is an in-memory inverted index over the memory files, built on
+// the first search and updated per file as files change. Documents are the
+// chunks produced by chunkMemoryFile.
+type searchIndex struct {
+	mu       sync.Mutex
+	built    bool
+	dirty    map[string]bool
+	docs     map[string][]searchDoc // by file
+	postings map[string]map[docRef]int
+	totalLen int
+	numDocs  int
+}
+
+type searchDoc struct {
+	chunk indexChunk
+	terms []string
+}
+
+type docRef struct {
+	file string
+	i    int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{dirty: map[string]bool{}}
+}
+
+// invalidate marks name to be re-read before the next search.
+func (x *searchIndex) invalidate(name string) {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.dirty[name] = true
+}
+
+// Search returns up to n chunks of the memory files that best match query,
+// ranked by BM25, with a snippet around the first matching term. n <= 0
+// returns every match. A scoped store also searches the shared tier.
+func (s *MemoryStore) Search(query string, n int) ([]SearchResult, error) {
+	out, err := s.searchOwn(query, n)
+	if err != nil || s.parent == nil {
+		return out, err
+	}
+	shared, err := s.parent.searchOwn(query, n)
+	if err != nil {
+		return nil, err
+	}
+	for i := range shared {
+		shared[i].Shared = true
+	}
+	out = append(out, shared...)
+	sortResults(out)
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out, nil
+}
+
+func (s *MemoryStore) searchOwn(query string, n int) ([]SearchResult, error) {
+	x := s.search
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if err := x.refresh(s); err != nil {
+		return nil, err
+	}
+
+	qTerms := uniqueTerms(searchTerms(query))
+	if len(qTerms) == 0 || x.numDocs == 0 {
+		return nil, nil
+	}
+	avgLen := float64(x.totalLen) / float64(x.numDocs)
+	scores := map[docRef]float64{}
+	for _, t := range qTerms {
+		post := x.postings[t]
+		if len(post) == 0 {
+			continue
+		}
+		df := float64(len(post))
+		idf := math.Log(1 + (float64(x.numDocs)-df+0.5)/(df+0.5))
+		for ref, tf := range post {
+			dl := float64(len(x.docs[ref.file][ref.i].terms))
+			f := float64(tf)
+			scores[ref] += idf * f * (bm25K1 + 1) / (f + bm25K1*(1-bm25B+bm25B*dl/avgLen))
+		}
+	}
+
+	out := make([]SearchResult, 0, len(scores))
+	for ref, score := range scores {
+		c := x.docs[ref.file][ref.i].chunk
+		out = append(out, SearchResult{File: c.Source, Line: c.Line, Time: c.Time, Score: score, Snippet: snippet(c.Text, qTerms)})
+	}
+	sortResults(out)
+	if n > 0 && len(out) > n {
+		out = out[:n]
+	}
+	return out, nil
+}
+
+// sortResults orders results best first, newer first on ties.
+func sortResults(out []SearchResult) {
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		if !out[i].Time.Equal(out[j].Time) {
+			return out[i].Time.After(out[j].Time)
+		}
+		if out[i].Shared != out[j].Shared {
+			return !out[i].Shared
+		}
+		if out[i].File != out[j].File {
+			return out[i].File < out[j].File
+		}
+		return out[i].Line < out[j].Line
+	})
+}
+
+// refresh indexes every memory file on first use, and afterwards re-reads only
+// the files that changed. Callers hold x.mu.
+func (x *searchIndex) refresh(s *MemoryStore) error {
+	var files []string
+	if !x.built {
+		names, err := s.ListFiles()
+		if err != nil {
+			return err
+		}
+		for _, n := range names {
+			if isValidMemoryFile(n) {
+				files = append(files, n)
+			}
+		}
+		x.docs = map[string][]searchDoc{}
+		x.postings = map[string]map[docRef]int{}
+		x.totalLen, x.numDocs = 0, 0
+	} else {
+		for n := range x.dirty {
+			files = append(files, n)
+		}
+	}
+	for _, name := range files {
+		content, err := s.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		x.remove(name)
+		x.add(name, content)
+	}
+	x.built = true
+	x.dirty = map[string]bool{}
+	return nil
+}
+
+func (x *searchIndex) remove(name string) {
+	for i, d := range x.docs[name] {
+		ref := docRef{name, i}
+		for _, t := range uniqueTerms(d.terms) {
+			delete(x.postings[t], ref)
+			if len(x.postings[t]) == 0 {
+				delete(x.postings, t)
+			}
+		}
+		x.totalLen -= len(d.terms)
+		x.numDocs--
+	}
+	delete(x.docs, name)
+}
+
+func (x *searchIndex) add(name, content string) {
+	chunks := chunkMemoryFile(name, content)
+	if len(chunks) == 0 {
+		return
+	}
+	docs := make([]searchDoc, len(chunks))
+	for i, c := range chunks {
+		terms := searchTerms(c.Text)
+		docs[i] = searchDoc{chunk: c, terms: terms}
+		ref := docRef{name, i}
+		for _, t := range terms {
+			if x.postings[t] == nil {
+				x.postings[t] = map[docRef]int{}
+			}
+			x.postings[t][ref]++
+		}
+		x.totalLen += len(terms)
+		x.numDocs++
+	}
+	x.docs[name] = docs
+}
+
+// searchTerms lowercases s and splits it into letter/digit runs, keeping
+// non-ASCII words intact.
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := make(map[string]bool, len(terms))
+	out := terms[:0:0]
+	for _, t := range terms {
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// snippet returns text on one line, cut to about snippetRunes runes around
+// the first occurrence of any query term.
+func snippet(text string, terms []string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	r := []rune(text)
+	if len(r) <= snippetRunes {
+		return text
+	}
+	lower := strings.ToLower(text)
+	at := -1
+	for _, t := range terms {
+		if i := strings.Index(lower, t); i >= 0 {
+			if n := utf8.RuneCountInString(lower[:i]); at < 0 || n < at {
+				at = n
+			}
+		}
+	}
+	start := max(0, at-snippetRunes/3)
+	end := min(len(r), start+snippetRunes)
+	start = max(0, end-snippetRunes)
+	out := strings.TrimSpace(string(r[start:end]))
+	if start > 0 {
+		out = "…" + out
+	}
+	if end < len(r) {
+		out += "…"
+	}
+	return out
+}
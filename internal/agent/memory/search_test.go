@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSearchRanksByBM25(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	ms.WriteFile("2026-01-05.md", "[2026-01-05T09:00:00Z] dentist appointment moved to friday\n[2026-01-05T10:00:00Z] the weather was nice and the tea was nice\n")
+	ms.WriteFile("2026-02-01.md", "[2026-02-01T08:00:00Z] asked the dentist about a whitening treatment, the dentist said no\n")
+	ms.WriteLongTerm("# Memory\n\nUser lives in Lisbon.\nWorks at a bakery.\n\nUser is allergic to peanuts.")
+
+	res, err := ms.Search("dentist", 0)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(res) != 2 || res[0].File != "2026-02-01.md" || res[1].File != "2026-01-05.md" {
+		t.Fatalf("expected both dentist notes, the one mentioning it twice first, got %+v", res)
+	}
+	if res, _ := ms.Search("bakery", 1); len(res) != 1 || res[0].File != "MEMORY.md" || res[0].Line != 3 || res[0].Snippet != "User lives in Lisbon. Works at a bakery." {
+		t.Fatalf("expected the MEMORY.md paragraph starting on line 3, got %+v", res)
+	}
+	// rare terms outweigh common ones
+	if res, _ := ms.Search("the friday", 1); len(res) != 1 || !strings.Contains(res[0].Snippet, "friday") {
+		t.Fatalf("expected the friday note first, got %+v", res)
+	}
+}
+
+func TestSearchFollowsFileChanges(t *testing.T) {
+	ms := NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	ms.WriteFile("2026-01-05.md", "dentist on friday\n")
+	if res, _ := ms.Search("dentist", 0); len(res) != 1 {
+		t.Fatalf("expected 1 result, got %+v", res)
+	}
+
+	ms.WriteFile("2026-01-05.md", "dentist moved to monday\n")
+	ms.AppendToday("call the dentist back")
+	res, _ := ms.Search("dentist", 0)
+	if len(res) != 2 {
+		t.Fatalf("expected 2 results after edits, got %+v", res)
+	}
+	if res, _ := ms.Search("friday", 0); len(res) != 0 {
+		t.Fatalf("expected edited text to be gone, got %+v", res)
+	}
+
+	ms.DeleteFile("2026-01-05.md")
+	if res, _ := ms.Search("monday", 0); len(res) != 0 {
+		t.Fatalf("expected deleted file to be gone, got %+v", res)
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("filler words here ", 30) + "the dentist is on friday " + strings.Repeat("more filler ", 30)
+	s := snippet(long, []string{"dentist"})
+	if !strings.Contains(s, "dentist") || !strings.HasPrefix(s, "…") || !strings.HasSuffix(s, "…") {
+		t.Fatalf("expected an elided snippet around the match, got %q", s)
+	}
+	if got := snippet("short\n text", []string{"x"}); got != "short text" {
+		t.Fatalf("expected whitespace to be collapsed, got %q", got)
+	}
+}
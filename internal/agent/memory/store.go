@@ -2,6 +2,7 @@ package memory
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
@@ -27,9 +28,24 @@ type MemoryStore struct {
 	limit     int    // max short-term items to keep
 	long      []MemoryItem
 	short     []MemoryItem
+	items     ItemStore // optional persistence for short/long items
+	onChange  []func(name string)
+	search    *searchIndex
+	parent    *MemoryStore            // shared tier of a scoped store
+	scope     string                  // scope id; "" for the shared tier
+	scopes    map[string]*MemoryStore // scoped stores, on the shared tier
+	facts     *FactStore
 	mu        sync.RWMutex
 }
 
+// ItemStore persists short- and long-term memory items.
+type ItemStore interface {
+	AddMemoryItem(it MemoryItem) error
+	// MemoryItems returns the newest n items of kind, oldest first. n <= 0
+	// returns all of them.
+	MemoryItems(kind string, n int) ([]MemoryItem, error)
+}
+
 // NewMemoryStore creates an in-memory store with short-term limit (e.g., 100).
 // Kept for tests and simple use-cases.
 func NewMemoryStore(limit int) *MemoryStore {
@@ -47,12 +63,49 @@ func NewMemoryStoreWithWorkspace(workspace string, limit int) *MemoryStore {
 		short:     make([]MemoryItem, 0, limit),
 		long:      make([]MemoryItem, 0),
 		limit:     limit,
+		search:    newSearchIndex(),
 	}
 	// ensure memory directory exists
 	_ = os.MkdirAll(ms.memoryDir, 0o755)
 	return ms
 }
 
+// UseItemStore loads the items kept in st and persists every item added
+// afterwards to it.
+func (s *MemoryStore) UseItemStore(st ItemStore) error {
+	short, err := st.MemoryItems("short", s.limit)
+	if err != nil {
+		return err
+	}
+	long, err := st.MemoryItems("long", 0)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.short, s.long, s.items = short, long, st
+	return nil
+}
+
+// OnChange registers fn to be called with the file name whenever a memory
+// file is written, appended to or deleted.
+func (s *MemoryStore) OnChange(fn func(name string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = append(s.onChange, fn)
+}
+
+// changed notifies the OnChange callbacks that name was modified.
+func (s *MemoryStore) changed(name string) {
+	s.search.invalidate(name)
+	s.mu.RLock()
+	fns := s.onChange
+	s.mu.RUnlock()
+	for _, fn := range fns {
+		fn(name)
+	}
+}
+
 // AddShort adds a short-term memory entry.
 func (s *MemoryStore) AddShort(text string) {
 	s.mu.Lock()
@@ -63,6 +116,7 @@ func (s *MemoryStore) AddShort(text string) {
 	if len(s.short) > s.limit {
 		s.short = s.short[len(s.short)-s.limit:]
 	}
+	s.persist(it)
 }
 
 // AddLong adds a long-term memory entry.
@@ -71,6 +125,17 @@ func (s *MemoryStore) AddLong(text string) {
 	defer s.mu.Unlock()
 	it := MemoryItem{Timestamp: time.Now().UTC(), Text: text, Kind: "long"}
 	s.long = append(s.long, it)
+	s.persist(it)
+}
+
+// persist writes it to the item store, if any. Callers hold s.mu.
+func (s *MemoryStore) persist(it MemoryItem) {
+	if s.items == nil {
+		return
+	}
+	if err := s.items.AddMemoryItem(it); err != nil {
+		log.Printf("memory: error storing item: %v", err)
+	}
 }
 
 // Recent returns up to n most recent memory items, combining short and long (short first).
@@ -137,7 +202,11 @@ func (s *MemoryStore) WriteLongTerm(content string) error {
 		return err
 	}
 	path := filepath.Join(s.memoryDir, "MEMORY.md")
-	return os.WriteFile(path, []byte(content), 0o644)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		return err
+	}
+	s.changed("MEMORY.md")
+	return nil
 }
 
 // ReadToday reads today's memory note file (YYYY-MM-DD.md)
@@ -166,8 +235,11 @@ func (s *MemoryStore) AppendToday(text string) error {
 		return err
 	}
 	defer func() { _ = f.Close() }()
-	_, err = fmt.Fprintf(f, "[%s] %s\n", time.Now().UTC().Format(time.RFC3339), text)
-	return err
+	if _, err := fmt.Fprintf(f, "[%s] %s\n", time.Now().UTC().Format(time.RFC3339), text); err != nil {
+		return err
+	}
+	s.changed(name)
+	return nil
 }
 
 // GetRecentMemories reads last N days' files and joins them with separators.
@@ -250,7 +322,11 @@ func (s *MemoryStore) WriteFile(name, content string) error {
 	if err := os.MkdirAll(s.memoryDir, 0o755); err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(s.memoryDir, name), []byte(content), 0o644)
+	if err := os.WriteFile(filepath.Join(s.memoryDir, name), []byte(content), 0o644); err != nil {
+		return err
+	}
+	s.changed(name)
+	return nil
 }
 
 // DeleteFile deletes a dated memory file (YYYY-MM-DD.md only).
@@ -269,11 +345,24 @@ func (s *MemoryStore) DeleteFile(name string) error {
 		}
 		return err
 	}
+	s.changed(name)
 	return nil
 }
 
 // GetMemoryContext returns combined long-term memory + today's notes for the system prompt.
+// For a scoped store (see Scope) the shared tier's context comes first.
 func (s *MemoryStore) GetMemoryContext() (string, error) {
+	own, err := s.ownContext()
+	if err != nil {
+		return "", err
+	}
+	if s.parent != nil {
+		return s.scopedContext(own)
+	}
+	return own, nil
+}
+
+func (s *MemoryStore) ownContext() (string, error) {
 	lt, err := s.ReadLongTerm()
 	if err != nil {
 		return "", err
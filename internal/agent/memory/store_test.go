@@ -50,3 +50,47 @@ func TestQueryByKeyword(t *testing.T) {
 		t.Fatalf("unexpected query order: %v", res)
 	}
 }
+
+// sliceItems is an in-memory ItemStore for tests.
+type sliceItems struct {
+	items []MemoryItem
+}
+
+func (s *sliceItems) AddMemoryItem(it MemoryItem) error {
+	s.items = append(s.items, it)
+	return nil
+}
+
+func (s *sliceItems) MemoryItems(kind string, n int) ([]MemoryItem, error) {
+	var out []MemoryItem
+	for _, it := range s.items {
+		if it.Kind == kind {
+			out = append(out, it)
+		}
+	}
+	if n > 0 && len(out) > n {
+		out = out[len(out)-n:]
+	}
+	return out, nil
+}
+
+func TestUseItemStore(t *testing.T) {
+	st := &sliceItems{items: []MemoryItem{
+		{Kind: "long", Text: "likes tea"},
+		{Kind: "short", Text: "old"},
+		{Kind: "short", Text: "recent"},
+	}}
+	s := NewMemoryStore(1)
+	if err := s.UseItemStore(st); err != nil {
+		t.Fatalf("UseItemStore: %v", err)
+	}
+	res := s.Recent(10)
+	if len(res) != 2 || res[0].Text != "recent" || res[1].Text != "likes tea" {
+		t.Fatalf("expected stored items within the short limit, got %v", res)
+	}
+
+	s.AddLong("lives in Lisbon")
+	if n := len(st.items); n != 4 || st.items[3].Text != "lives in Lisbon" {
+		t.Fatalf("expected the new item to be persisted, got %v", st.items)
+	}
+}
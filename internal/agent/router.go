@@ -0,0 +1,170 @@
+package agent
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/config"
+)
+
+// Router runs one AgentLoop per agent profile on a shared hub. Each loop gets
+// a hub of its own: the router hands every inbound message to the loop its
+// route selects and forwards the loops' outbound messages to the shared hub.
+type Router struct {
+	hub    *chat.Hub
+	routes []config.AgentRoute
+	hubs   map[string]*chat.Hub
+	loops  map[string]*AgentLoop
+}
+
+// NewRouter creates a router for hub dispatching by routes. Messages that no
+// route matches go to config.DefaultAgent.
+func NewRouter(hub *chat.Hub, routes []config.AgentRoute) *Router {
+	return &Router{hub: hub, routes: routes, hubs: make(map[string]*chat.Hub), loops: make(map[string]*AgentLoop)}
+}
+
+// Hub returns the hub to build the loop of agent name with. Its commands fall
+// back to those registered on the shared hub, e.g. by channels.
+func (r *Router) Hub(name string) *chat.Hub {
+	h, ok := r.hubs[name]
+	if !ok {
+		h = chat.NewHub(cap(r.hub.In))
+		h.Commands = r.hub.Commands.Child()
+		r.hubs[name] = h
+	}
+	return h
+}
+
+// Add registers the loop of agent name, built on r.Hub(name).
+func (r *Router) Add(name string, a *AgentLoop) {
+	a.name = name
+	r.loops[name] = a
+}
+
+// Deliver hands msg straight to the loop of agent name instead of routing it,
+// e.g. for a reminder that must be answered by the profile that scheduled it.
+// Messages for an unknown or empty name are routed as usual.
+func (r *Router) Deliver(name string, msg chat.Inbound) {
+	if _, ok := r.loops[name]; ok {
+		r.hubs[name].In <- msg
+		return
+	}
+	r.hub.In <- msg
+}
+
+// Route returns the name of the agent that answers msg.
+func (r *Router) Route(msg chat.Inbound) string {
+	for _, rt := range r.routes {
+		if (rt.Channel == "" || rt.Channel == msg.Channel) &&
+			(rt.ChatID == "" || rt.ChatID == msg.ChatID) &&
+			(rt.SenderID == "" || rt.SenderID == msg.SenderID) {
+			return rt.Agent
+		}
+	}
+	return config.DefaultAgent
+}
+
+// target returns the agent msg is handed to: the one it routes to, except
+// that /stop goes to whichever agent is running a turn in the chat. In a group
+// chat that can be the profile another sender is routed to.
+func (r *Router) target(msg chat.Inbound) string {
+	name := r.Route(msg)
+	if cmd, _, ok := chat.Parse(msg.Content); !ok || cmd != "stop" {
+		return name
+	}
+	key := msg.Channel + ":" + msg.ChatID
+	if a, ok := r.loops[name]; ok && a.hasTurn(key) {
+		return name
+	}
+	for other, a := range r.loops {
+		if a.hasTurn(key) {
+			return other
+		}
+	}
+	return name
+}
+
+// Run starts every loop and routes messages until ctx is cancelled, then
+// waits for the loops to finish.
+func (r *Router) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	queues := make(map[string]chan chat.Inbound, len(r.loops))
+	for name, a := range r.loops {
+		h := r.Hub(name)
+		q := make(chan chat.Inbound, cap(r.hub.In))
+		queues[name] = q
+		wg.Add(3)
+		go func() {
+			defer wg.Done()
+			a.Run(ctx)
+		}()
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case out := <-h.Out:
+					select {
+					case r.hub.Out <- out:
+					case <-ctx.Done():
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			forward(ctx, q, h.In)
+		}()
+	}
+
+	for {
+		select {
+		case msg := <-r.hub.In:
+			name := r.target(msg)
+			if _, ok := r.loops[name]; !ok {
+				log.Printf("router: no agent %q for message from %s:%s, using %s", name, msg.Channel, msg.ChatID, config.DefaultAgent)
+				name = config.DefaultAgent
+			}
+			select {
+			case queues[name] <- msg:
+			case <-ctx.Done():
+			}
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+	}
+}
+
+// Close closes every loop.
+func (r *Router) Close() {
+	for _, a := range r.loops {
+		a.Close()
+	}
+}
+
+// forward passes the messages from in on to out in order. Messages wait in an
+// unbounded queue while out is full, so that a busy agent never holds up the
+// router and the agents behind it.
+func forward(ctx context.Context, in <-chan chat.Inbound, out chan<- chat.Inbound) {
+	var pending []chat.Inbound
+	for {
+		var send chan<- chat.Inbound
+		var next chat.Inbound
+		if len(pending) > 0 {
+			send, next = out, pending[0]
+		}
+		select {
+		case msg := <-in:
+			pending = append(pending, msg)
+		case send <- next:
+			pending = pending[1:]
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/config"
+	"github.com/local/picobot/internal/cron"
+	"github.com/local/picobot/internal/providers"
+)
+
+// nameProvider answers every message with its name and the tools it was
+// offered.
+type nameProvider struct{ name string }
+
+func (p nameProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	return providers.LLMResponse{Content: p.name + " with " + toolNames(defs)}, nil
+}
+func (p nameProvider) GetDefaultModel() string { return "fake" }
+
+func toolNames(defs []providers.ToolDefinition) string {
+	var names []string
+	for _, d := range defs {
+		names = append(names, d.Name)
+	}
+	if len(names) == 1 {
+		return names[0]
+	}
+	return "many tools"
+}
+
+func TestRouterSendsMessagesToTheirProfile(t *testing.T) {
+	hub := chat.NewHub(20)
+	r := NewRouter(hub, []config.AgentRoute{
+		{Channel: "slack", Agent: "ops"},
+		{Channel: "telegram", ChatID: "family", Agent: "family"},
+		{SenderID: "grandma", Agent: "family"},
+	})
+	for _, name := range []string{config.DefaultAgent, "ops", "family"} {
+		ag := NewAgentLoop(r.Hub(name), nameProvider{name}, "fake", 3, t.TempDir(), nil, nil)
+		if name == "ops" {
+			if err := ag.SetTools([]string{"exec", "cron"}); err != nil {
+				t.Fatal(err)
+			}
+		}
+		r.Add(name, ag)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go r.Run(ctx)
+
+	cases := []struct {
+		msg  chat.Inbound
+		want string
+	}{
+		{chat.Inbound{Channel: "slack", SenderID: "U1", ChatID: "C1"}, "ops with exec"},
+		{chat.Inbound{Channel: "telegram", SenderID: "dad", ChatID: "family"}, "family with many tools"},
+		{chat.Inbound{Channel: "whatsapp", SenderID: "grandma", ChatID: "g"}, "family with many tools"},
+		{chat.Inbound{Channel: "telegram", SenderID: "dad", ChatID: "work"}, "default with many tools"},
+	}
+	for _, c := range cases {
+		c.msg.Content = "hello"
+		hub.In <- c.msg
+		select {
+		case out := <-hub.Out:
+			if out.Content != c.want || out.Channel != c.msg.Channel || out.ChatID != c.msg.ChatID {
+				t.Fatalf("message from %s:%s answered with %+v, want %q", c.msg.Channel, c.msg.SenderID, out, c.want)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no answer for %s:%s", c.msg.Channel, c.msg.SenderID)
+		}
+	}
+}
+
+func TestRouterAnswersChannelCommands(t *testing.T) {
+	hub := chat.NewHub(20)
+	r := NewRouter(hub, []config.AgentRoute{{Channel: "telegram", Agent: "family"}})
+	for _, name := range []string{config.DefaultAgent, "family"} {
+		r.Add(name, NewAgentLoop(r.Hub(name), nameProvider{name}, "fake", 3, t.TempDir(), nil, nil))
+	}
+	// registered by a channel on the shared hub after the agents were built
+	hub.Commands.Register(chat.Command{Name: "start", Description: "Say hello", Handler: func(ctx context.Context, msg chat.Inbound, args string) string {
+		return "hello from the channel"
+	}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go r.Run(ctx)
+
+	for _, msg := range []chat.Inbound{
+		{Channel: "telegram", SenderID: "dad", ChatID: "family", Content: "/start"},
+		{Channel: "discord", SenderID: "dad", ChatID: "d", Content: "/start"},
+	} {
+		hub.In <- msg
+		select {
+		case out := <-hub.Out:
+			if out.Content != "hello from the channel" {
+				t.Fatalf("/start from %s answered with %q", msg.Channel, out.Content)
+			}
+		case <-ctx.Done():
+			t.Fatalf("no answer to /start from %s", msg.Channel)
+		}
+	}
+	if cmds := r.Hub("family").Commands.List(); !hasCommand(cmds, "start") || !hasCommand(cmds, "reset") {
+		t.Fatalf("expected /help to list the agent's and the channel's commands, got %+v", cmds)
+	}
+}
+
+func hasCommand(cmds []chat.Command, name string) bool {
+	for _, c := range cmds {
+		if c.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestForwardDoesNotBlockTheSender(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	in := make(chan chat.Inbound, 1)
+	out := make(chan chat.Inbound, 1)
+	go forward(ctx, in, out)
+
+	// nobody reads out yet: the sender still gets all messages through
+	for i := 0; i < 10; i++ {
+		select {
+		case in <- chat.Inbound{Content: fmt.Sprint(i)}:
+		case <-ctx.Done():
+			t.Fatalf("send %d blocked", i)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if got := <-out; got.Content != fmt.Sprint(i) {
+			t.Fatalf("message %d out of order: %q", i, got.Content)
+		}
+	}
+}
+
+// reminderProvider schedules a reminder when asked to and otherwise answers
+// with its name and the message.
+type reminderProvider struct{ name string }
+
+func (p reminderProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	last := messages[len(messages)-1]
+	switch {
+	case last.Role == "tool":
+		return providers.LLMResponse{Content: "scheduled"}, nil
+	case last.Content == "remind me":
+		return providers.LLMResponse{
+			HasToolCalls: true,
+			ToolCalls:    []providers.ToolCall{{ID: "c1", Name: "cron", Arguments: map[string]interface{}{"action": "add", "message": "stretch", "delay": "1h"}}},
+		}, nil
+	}
+	return providers.LLMResponse{Content: p.name + ": " + last.Content}, nil
+}
+func (p reminderProvider) GetDefaultModel() string { return "fake" }
+
+func TestRouterDeliversRemindersToTheProfileThatScheduledThem(t *testing.T) {
+	hub := chat.NewHub(20)
+	r := NewRouter(hub, []config.AgentRoute{{SenderID: "boss", Agent: "work"}})
+	s := cron.NewScheduler(nil)
+	for _, name := range []string{config.DefaultAgent, "work"} {
+		r.Add(name, NewAgentLoop(r.Hub(name), reminderProvider{name}, "fake", 3, t.TempDir(), s, nil))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go r.Run(ctx)
+
+	next := func() string {
+		for {
+			out := nextReply(t, hub).Content
+			if !strings.HasPrefix(out, "🤖") && !strings.HasPrefix(out, "📢") { // skip tool notifications
+				return out
+			}
+		}
+	}
+
+	hub.In <- chat.Inbound{Channel: "telegram", SenderID: "boss", ChatID: "team", Content: "remind me"}
+	if got := next(); got != "scheduled" {
+		t.Fatalf("expected the reminder to be scheduled, got %q", got)
+	}
+	jobs := s.List()
+	if len(jobs) != 1 || jobs[0].Agent != "work" {
+		t.Fatalf("expected one job tagged with the work profile, got %+v", jobs)
+	}
+
+	// the reminder's "cron" sender would route to the default profile
+	r.Deliver(jobs[0].Agent, chat.Inbound{Channel: jobs[0].Channel, SenderID: "cron", ChatID: jobs[0].ChatID, Content: jobs[0].Message})
+	if got := next(); got != "work: stretch" {
+		t.Fatalf("expected the work profile to answer the reminder, got %q", got)
+	}
+	r.Deliver("", chat.Inbound{Channel: "telegram", SenderID: "cron", ChatID: "team", Content: "untagged"})
+	if got := next(); got != "default: untagged" {
+		t.Fatalf("expected an untagged reminder to be routed, got %q", got)
+	}
+}
+
+func TestRouterStopsTheTurnOfAnotherProfile(t *testing.T) {
+	hub := chat.NewHub(20)
+	r := NewRouter(hub, []config.AgentRoute{{SenderID: "boss", Agent: "work"}})
+	p := &gatedProvider{release: make(chan struct{})}
+	for _, name := range []string{config.DefaultAgent, "work"} {
+		r.Add(name, NewAgentLoop(r.Hub(name), p, "fake", 3, t.TempDir(), nil, nil))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	go r.Run(ctx)
+
+	hub.In <- chat.Inbound{Channel: "slack", SenderID: "boss", ChatID: "C1", Content: "slow report"}
+	for !r.loops["work"].hasTurn("slack:C1") {
+		if ctx.Err() != nil {
+			t.Fatal("turn never started")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// dad is routed to the default profile, which has nothing running
+	hub.In <- chat.Inbound{Channel: "slack", SenderID: "dad", ChatID: "C1", Content: "/stop"}
+	select {
+	case out := <-hub.Out:
+		if out.Content != "Stopped." {
+			t.Fatalf("expected the work profile's turn to be stopped, got %q", out.Content)
+		}
+	case <-ctx.Done():
+		t.Fatal("no answer to /stop")
+	}
+}
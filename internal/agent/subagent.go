@@ -0,0 +1,367 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/local/picobot/internal/agent/tools"
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// Sub-agent budgets and limits.
+const (
+	defaultSubAgentIterations = 10
+	defaultSubAgentTimeout    = 5 * time.Minute
+	maxSubAgentTimeout        = 30 * time.Minute
+	maxRunningSubAgents       = 4  // at once, across all chats
+	maxFinishedSubAgents      = 20 // kept for status after they finish
+)
+
+// defaultSubAgentTools are given to a sub-agent when the parent names none:
+// the tools needed to research something. None of them changes anything, so
+// a task that writes files or memory needs its tools named explicitly.
+var defaultSubAgentTools = []string{
+	"web", "web_search",
+	"list_memory", "read_memory", "search_memory", "query_facts",
+	"list_skills", "read_skill",
+}
+
+// subAgentSender is the sender of the results sub-agents deliver to a chat.
+const subAgentSender = "subagent"
+
+// subAgentDeniedTools are never given to a sub-agent: it cannot start
+// sub-agents of its own, and its result reaches the chat through the parent.
+var subAgentDeniedTools = map[string]bool{"spawn": true, "message": true}
+
+const subAgentPrompt = `You are a sub-agent of picobot, working alone on one task in the background. ` +
+	`Nobody can answer questions: use your tools to do the task, then reply with the result. ` +
+	`Make the reply complete and self-contained, as it is passed on as it is. Current time: %s.`
+
+type requestKey struct{}
+
+// withRequest returns a context carrying the message a turn answers, so that
+// work started during the turn (sub-agents) can report back to its sender.
+func withRequest(ctx context.Context, msg chat.Inbound) context.Context {
+	return context.WithValue(ctx, requestKey{}, msg)
+}
+
+// requestFrom returns the message stored by withRequest.
+func requestFrom(ctx context.Context) (chat.Inbound, bool) {
+	msg, ok := ctx.Value(requestKey{}).(chat.Inbound)
+	return msg, ok
+}
+
+// randomID returns a short random hex id.
+func randomID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// subAgent is one background task.
+type subAgent struct {
+	status  tools.SubAgentStatus
+	origin  chat.Inbound // message whose turn started it
+	deliver bool
+	cancel  context.CancelFunc
+	done    chan struct{} // closed when it finishes
+	waiters int           // wait calls blocked on it
+}
+
+// subAgents runs the loop's sub-agents; it implements tools.Spawner.
+type subAgents struct {
+	a      *AgentLoop
+	mu     sync.Mutex
+	agents map[string]*subAgent // by id
+}
+
+// chatOf returns the chat key of the request in ctx.
+func chatOf(ctx context.Context) string {
+	channel, chatID, _ := tools.OriginFrom(ctx)
+	return channel + ":" + chatID
+}
+
+// get returns sub-agent id if it was started from the chat in ctx. The caller
+// holds s.mu.
+func (s *subAgents) get(ctx context.Context, id string) *subAgent {
+	sa := s.agents[id]
+	if sa == nil || sa.origin.Channel+":"+sa.origin.ChatID != chatOf(ctx) {
+		return nil
+	}
+	return sa
+}
+
+func (s *subAgents) Spawn(ctx context.Context, req tools.SubAgentRequest) (tools.SubAgentStatus, error) {
+	channel, chatID, ok := tools.OriginFrom(ctx)
+	if !ok {
+		return tools.SubAgentStatus{}, fmt.Errorf("no chat to report to")
+	}
+	origin, ok := requestFrom(ctx)
+	if !ok {
+		origin = chat.Inbound{Channel: channel, ChatID: chatID}
+	}
+
+	names := req.Tools
+	if len(names) == 0 {
+		names = s.defaultTools()
+	}
+	for _, name := range names {
+		if subAgentDeniedTools[name] {
+			return tools.SubAgentStatus{}, fmt.Errorf("%s is not available to sub-agents", name)
+		}
+	}
+	reg, err := s.a.tools.Subset(names)
+	if err != nil {
+		return tools.SubAgentStatus{}, err
+	}
+
+	iterations := req.MaxIterations
+	if iterations <= 0 {
+		iterations = defaultSubAgentIterations
+	}
+	iterations = min(iterations, s.a.maxIterations)
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultSubAgentTimeout
+	}
+	timeout = min(timeout, maxSubAgentTimeout)
+
+	id, err := randomID()
+	if err != nil {
+		return tools.SubAgentStatus{}, err
+	}
+	// the sub-agent keeps the turn's origin, memory scope and approver but
+	// outlives the turn
+	runCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	sa := &subAgent{
+		status: tools.SubAgentStatus{
+			ID:      id,
+			Name:    req.Name,
+			Task:    req.Task,
+			State:   tools.SubAgentRunning,
+			Started: time.Now(),
+		},
+		origin:  origin,
+		deliver: req.Deliver,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+
+	s.mu.Lock()
+	running := 0
+	for _, other := range s.agents {
+		if other.status.State == tools.SubAgentRunning {
+			running++
+		}
+	}
+	if running >= maxRunningSubAgents {
+		s.mu.Unlock()
+		cancel()
+		return tools.SubAgentStatus{}, fmt.Errorf("%d sub-agents are already running; wait for one to finish or cancel one", running)
+	}
+	if s.agents == nil {
+		s.agents = make(map[string]*subAgent)
+	}
+	s.agents[id] = sa
+	s.prune()
+	st := sa.status
+	s.mu.Unlock()
+
+	model := s.a.modelFor(origin.Channel + ":" + origin.ChatID)
+	log.Printf("sub-agent %s started with %d tools: %s", id, len(names), truncateRunes(req.Task, 80))
+	go func() {
+		result, err := s.a.runSubAgent(runCtx, reg, req.Task, iterations, model, origin, func() {
+			s.mu.Lock()
+			sa.status.Iterations++
+			s.mu.Unlock()
+		})
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("ran out of time after %s", timeout)
+		}
+		s.finish(sa, result, err)
+	}()
+	return st, nil
+}
+
+// defaultTools returns the defaultSubAgentTools the loop has.
+func (s *subAgents) defaultTools() []string {
+	var names []string
+	for _, name := range defaultSubAgentTools {
+		if s.a.tools.Get(name) != nil {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// prune forgets the oldest finished sub-agents beyond maxFinishedSubAgents.
+// The caller holds s.mu.
+func (s *subAgents) prune() {
+	var finished []*subAgent
+	for _, sa := range s.agents {
+		if sa.status.State != tools.SubAgentRunning {
+			finished = append(finished, sa)
+		}
+	}
+	if len(finished) <= maxFinishedSubAgents {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool { return finished[i].status.Finished.Before(finished[j].status.Finished) })
+	for _, sa := range finished[:len(finished)-maxFinishedSubAgents] {
+		delete(s.agents, sa.status.ID)
+	}
+}
+
+// finish records the outcome of sa and, unless the parent is waiting for it
+// or it was cancelled, delivers it to the chat it was started from.
+func (s *subAgents) finish(sa *subAgent, result string, err error) {
+	s.mu.Lock()
+	st := &sa.status
+	st.Finished = time.Now()
+	switch {
+	case err == nil:
+		st.State, st.Result = tools.SubAgentDone, result
+	case errors.Is(err, context.Canceled):
+		st.State = tools.SubAgentCancelled
+	default:
+		st.State, st.Error = tools.SubAgentFailed, err.Error()
+	}
+	deliver := sa.deliver && sa.waiters == 0 && st.State != tools.SubAgentCancelled
+	status := *st
+	sa.cancel()
+	close(sa.done)
+	s.mu.Unlock()
+
+	log.Printf("sub-agent %s %s after %s", status.ID, status.State, status.Finished.Sub(status.Started).Round(time.Millisecond))
+	if deliver {
+		s.a.deliverSubAgent(sa.origin, status)
+	}
+}
+
+func (s *subAgents) Status(ctx context.Context, id string) (tools.SubAgentStatus, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sa := s.get(ctx, id)
+	if sa == nil {
+		return tools.SubAgentStatus{}, false
+	}
+	return sa.status, true
+}
+
+func (s *subAgents) Wait(ctx context.Context, id string) (tools.SubAgentStatus, error) {
+	s.mu.Lock()
+	sa := s.get(ctx, id)
+	if sa == nil {
+		s.mu.Unlock()
+		return tools.SubAgentStatus{}, fmt.Errorf("no sub-agent %q", id)
+	}
+	sa.waiters++
+	s.mu.Unlock()
+
+	var err error
+	select {
+	case <-sa.done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sa.waiters--
+	return sa.status, err
+}
+
+func (s *subAgents) List(ctx context.Context) []tools.SubAgentStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var list []tools.SubAgentStatus
+	for id := range s.agents {
+		if sa := s.get(ctx, id); sa != nil {
+			list = append(list, sa.status)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Started.Before(list[j].Started) })
+	return list
+}
+
+func (s *subAgents) Cancel(ctx context.Context, id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sa := s.get(ctx, id)
+	if sa == nil || sa.status.State != tools.SubAgentRunning {
+		return false
+	}
+	sa.cancel()
+	return true
+}
+
+// cancelAll stops every running sub-agent.
+func (s *subAgents) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, sa := range s.agents {
+		sa.cancel()
+	}
+}
+
+// runSubAgent works on task with the tools in reg, in an ephemeral
+// conversation of its own, for at most iterations model calls. progress is
+// called before each call.
+func (a *AgentLoop) runSubAgent(ctx context.Context, reg *tools.Registry, task string, iterations int, model string, origin chat.Inbound, progress func()) (string, error) {
+	messages := []providers.Message{
+		{Role: "system", Content: fmt.Sprintf(subAgentPrompt, time.Now().Format("2006-01-02 15:04 (Monday)"))},
+		{Role: "user", Content: task},
+	}
+	t := a.newTurn(origin, model)
+	t.Tools = reg
+	t.MaxIterations = iterations
+	t.Hooks = append(t.Hooks, TurnHooks{BeforeLLM: func(context.Context, int, []providers.Message) { progress() }})
+	res := t.Run(ctx, messages)
+	switch {
+	case res.Cancelled:
+		return "", ctx.Err()
+	case res.Err != nil:
+		return "", res.Err
+	case res.Stopped != nil:
+		return "", res.Stopped
+	case res.Exhausted:
+		return "", fmt.Errorf("no result after %d model calls", iterations)
+	}
+	return res.Content, nil
+}
+
+// deliverSubAgent hands the outcome of a sub-agent to the agent as a message
+// from subAgentSender in the chat it was started from, so it can pass it on
+// to the user. Nothing is delivered when the loop is not running (one-shot
+// CLI) or the chat is a system channel.
+func (a *AgentLoop) deliverSubAgent(origin chat.Inbound, st tools.SubAgentStatus) {
+	if !a.running || isSystemChannel(origin.Channel) {
+		return
+	}
+	content := fmt.Sprintf("[Sub-agent %s finished] Task: %s\n\nResult:\n%s\n\nPlease pass this on to the user.", st.ID, st.Task, st.Result)
+	if st.State == tools.SubAgentFailed {
+		content = fmt.Sprintf("[Sub-agent %s failed] Task: %s\n\nError: %s\n\nPlease let the user know.", st.ID, st.Task, st.Error)
+	}
+	in := chat.Inbound{
+		Channel:   origin.Channel,
+		SenderID:  subAgentSender,
+		ChatID:    origin.ChatID,
+		Content:   content,
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"subagent": st.ID},
+	}
+	select {
+	case a.hub.In <- in:
+	default:
+		log.Printf("sub-agent %s: inbound channel full, dropping its result", st.ID)
+	}
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultApprovalTimeout is how long a tool call waits for an answer before it
+// is treated as denied.
+const DefaultApprovalTimeout = 2 * time.Minute
+
+// ErrNotApproved is returned by Registry.Execute for a call that needed
+// approval and was denied, timed out or could not be asked about.
+var ErrNotApproved = errors.New("not approved")
+
+// ApprovalRule selects tool calls that need approval. Tool is a tool name or a
+// prefix ending in "*" (e.g. "mcp_*"). Args optionally narrows the rule to
+// calls whose arguments match: every named argument's value, as text, must
+// match its regular expression.
+type ApprovalRule struct {
+	Tool string
+	Args map[string]string
+}
+
+// DefaultApprovalRules covers the tools that change things outside the
+// conversation: shell commands, file writes, deleting memory and MCP tools.
+var DefaultApprovalRules = []ApprovalRule{
+	{Tool: "exec"},
+	{Tool: "filesystem", Args: map[string]string{"action": "^write$"}},
+	{Tool: "delete_memory"},
+	{Tool: "mcp_*"},
+}
+
+// ApprovalPolicy decides which tool calls must be approved before they run.
+type ApprovalPolicy struct {
+	Timeout time.Duration
+	rules   []compiledRule
+}
+
+type compiledRule struct {
+	tool string
+	args map[string]*regexp.Regexp
+}
+
+// NewApprovalPolicy compiles rules into a policy; timeout <= 0 uses
+// DefaultApprovalTimeout.
+func NewApprovalPolicy(rules []ApprovalRule, timeout time.Duration) (*ApprovalPolicy, error) {
+	if timeout <= 0 {
+		timeout = DefaultApprovalTimeout
+	}
+	p := &ApprovalPolicy{Timeout: timeout}
+	for _, r := range rules {
+		if r.Tool == "" {
+			return nil, fmt.Errorf("approval rule without a tool name")
+		}
+		c := compiledRule{tool: r.Tool, args: map[string]*regexp.Regexp{}}
+		for name, pattern := range r.Args {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("approval rule for %s: argument %s: %w", r.Tool, name, err)
+			}
+			c.args[name] = re
+		}
+		p.rules = append(p.rules, c)
+	}
+	return p, nil
+}
+
+// Requires reports whether a call of tool name with args needs approval.
+func (p *ApprovalPolicy) Requires(name string, args map[string]interface{}) bool {
+	for _, r := range p.rules {
+		if r.matches(name, args) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r compiledRule) matches(name string, args map[string]interface{}) bool {
+	if prefix, ok := strings.CutSuffix(r.tool, "*"); ok {
+		if !strings.HasPrefix(name, prefix) {
+			return false
+		}
+	} else if name != r.tool {
+		return false
+	}
+	for arg, re := range r.args {
+		v, ok := args[arg]
+		if !ok || !re.MatchString(fmt.Sprint(v)) {
+			return false
+		}
+	}
+	return true
+}
+
+// ApprovalRequest describes a tool call waiting for approval.
+type ApprovalRequest struct {
+	Tool string
+	Args map[string]interface{}
+}
+
+// Approver asks a person whether a tool call may run. Approve blocks until
+// they answer or ctx is done, in which case it returns ctx.Err().
+type Approver interface {
+	Approve(ctx context.Context, req ApprovalRequest) (bool, error)
+}
+
+type approverKey struct{}
+
+// WithApprover returns a context carrying the approver for a request's tool
+// calls, typically one that asks in the chat the request came from.
+func WithApprover(ctx context.Context, ap Approver) context.Context {
+	return context.WithValue(ctx, approverKey{}, ap)
+}
+
+// ApproverFrom returns the approver stored by WithApprover, or nil.
+func ApproverFrom(ctx context.Context) Approver {
+	ap, _ := ctx.Value(approverKey{}).(Approver)
+	return ap
+}
+
+// approve asks for approval of a call that policy p requires. It returns nil
+// when the call may run, an error wrapping ErrNotApproved when it may not,
+// and ctx.Err() when the request itself was cancelled meanwhile.
+func (p *ApprovalPolicy) approve(ctx context.Context, name string, args map[string]interface{}) error {
+	ap := ApproverFrom(ctx)
+	if ap == nil {
+		return fmt.Errorf("%s %w: it needs approval and there is no one to ask here", name, ErrNotApproved)
+	}
+	actx, cancel := context.WithTimeout(ctx, p.Timeout)
+	defer cancel()
+	ok, err := ap.Approve(actx, ApprovalRequest{Tool: name, Args: args})
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Errorf("%s %w: no answer within %s", name, ErrNotApproved, p.Timeout)
+	case err != nil:
+		return fmt.Errorf("%s %w: %v", name, ErrNotApproved, err)
+	case !ok:
+		return fmt.Errorf("%s %w: denied by the user", name, ErrNotApproved)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// countingTool counts its executions.
+type countingTool struct{ runs int }
+
+func (t *countingTool) Name() string                       { return "exec" }
+func (t *countingTool) Description() string                { return "test tool" }
+func (t *countingTool) Parameters() map[string]interface{} { return nil }
+func (t *countingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	t.runs++
+	return "ran", nil
+}
+
+// answerApprover answers every request with ok, or never answers when wait
+// is set.
+type answerApprover struct {
+	ok    bool
+	wait  bool
+	asked []ApprovalRequest
+}
+
+func (a *answerApprover) Approve(ctx context.Context, req ApprovalRequest) (bool, error) {
+	a.asked = append(a.asked, req)
+	if a.wait {
+		<-ctx.Done()
+		return false, ctx.Err()
+	}
+	return a.ok, nil
+}
+
+func TestApprovalPolicyRequires(t *testing.T) {
+	p, err := NewApprovalPolicy(DefaultApprovalRules, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Timeout != DefaultApprovalTimeout {
+		t.Fatalf("expected the default timeout, got %s", p.Timeout)
+	}
+	cases := []struct {
+		tool string
+		args map[string]interface{}
+		want bool
+	}{
+		{"exec", map[string]interface{}{"cmd": []interface{}{"ls"}}, true},
+		{"filesystem", map[string]interface{}{"action": "write", "path": "a.txt"}, true},
+		{"filesystem", map[string]interface{}{"action": "read", "path": "a.txt"}, false},
+		{"filesystem", nil, false},
+		{"delete_memory", map[string]interface{}{"target": "2026-01-01"}, true},
+		{"mcp_github_create_issue", nil, true},
+		{"write_memory", nil, false},
+	}
+	for _, c := range cases {
+		if got := p.Requires(c.tool, c.args); got != c.want {
+			t.Errorf("Requires(%s, %v) = %v, want %v", c.tool, c.args, got, c.want)
+		}
+	}
+
+	if _, err := NewApprovalPolicy([]ApprovalRule{{Tool: "exec", Args: map[string]string{"cmd": "("}}}, 0); err == nil {
+		t.Fatal("expected an invalid pattern to be rejected")
+	}
+	if _, err := NewApprovalPolicy([]ApprovalRule{{Args: map[string]string{"cmd": "rm"}}}, 0); err == nil {
+		t.Fatal("expected a rule without a tool to be rejected")
+	}
+}
+
+func TestRegistryExecuteAsksForApproval(t *testing.T) {
+	tool := &countingTool{}
+	r := NewRegistry()
+	r.Register(tool)
+	p, _ := NewApprovalPolicy([]ApprovalRule{{Tool: "exec", Args: map[string]string{"cmd": "rm"}}}, 50*time.Millisecond)
+	r.SetApprovalPolicy(p)
+	rm := map[string]interface{}{"cmd": []interface{}{"rm", "-rf", "tmp"}}
+
+	// calls outside the rules run without asking
+	if res, err := r.Execute(context.Background(), "exec", map[string]interface{}{"cmd": []interface{}{"ls"}}); err != nil || res != "ran" {
+		t.Fatalf("expected ls to run, got %q %v", res, err)
+	}
+
+	if _, err := r.Execute(context.Background(), "exec", rm); !errors.Is(err, ErrNotApproved) || !strings.Contains(err.Error(), "no one to ask") {
+		t.Fatalf("expected a denial without an approver, got %v", err)
+	}
+
+	yes := &answerApprover{ok: true}
+	if res, err := r.Execute(WithApprover(context.Background(), yes), "exec", rm); err != nil || res != "ran" {
+		t.Fatalf("expected the approved call to run, got %q %v", res, err)
+	}
+	if len(yes.asked) != 1 || yes.asked[0].Tool != "exec" {
+		t.Fatalf("unexpected approval requests %+v", yes.asked)
+	}
+
+	if _, err := r.Execute(WithApprover(context.Background(), &answerApprover{}), "exec", rm); !errors.Is(err, ErrNotApproved) || !strings.Contains(err.Error(), "denied") {
+		t.Fatalf("expected a denial, got %v", err)
+	}
+	if _, err := r.Execute(WithApprover(context.Background(), &answerApprover{wait: true}), "exec", rm); !errors.Is(err, ErrNotApproved) || !strings.Contains(err.Error(), "no answer within") {
+		t.Fatalf("expected a timeout to deny, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := r.Execute(WithApprover(ctx, &answerApprover{wait: true}), "exec", rm); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected a cancelled request to report cancellation, got %v", err)
+	}
+	if tool.runs != 2 {
+		t.Fatalf("expected only ls and the approved call to run, got %d runs", tool.runs)
+	}
+}
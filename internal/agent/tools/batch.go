@@ -0,0 +1,121 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/local/picobot/internal/providers"
+)
+
+// DefaultParallelCalls is how many tool calls of one batch run at once when
+// no limit is given.
+const DefaultParallelCalls = 4
+
+// SerialTool is implemented by tools whose calls must not overlap. Calls in
+// one batch that return the same non-empty key run one at a time, in the
+// order the model made them; "" lets a call run alongside any other.
+type SerialTool interface {
+	SerialKey(args map[string]interface{}) string
+}
+
+// approvalKey serialises the calls of a batch that need approval, so the
+// user is asked one question at a time and a refusal can stop the rest.
+const approvalKey = "\x00approval"
+
+// CallResult is the outcome of one call of a batch.
+type CallResult struct {
+	Result  string
+	Err     error
+	Elapsed time.Duration
+	// Ran is false when the tool was never executed: it was not approved,
+	// the batch was cancelled first, or an earlier call was not approved.
+	Ran bool
+}
+
+// serialKeys returns the keys a call waits on before it starts.
+func (r *Registry) serialKeys(name string, args map[string]interface{}) []string {
+	r.mu.RLock()
+	t := r.tools[name]
+	policy := r.policy
+	r.mu.RUnlock()
+	var keys []string
+	if s, ok := t.(SerialTool); ok {
+		if k := s.SerialKey(args); k != "" {
+			keys = append(keys, k)
+		}
+	}
+	if policy != nil && policy.Requires(name, args) {
+		keys = append(keys, approvalKey)
+	}
+	return keys
+}
+
+// ExecuteBatch runs the tool calls of one model response, at most limit at
+// a time (limit < 1 selects DefaultParallelCalls), and returns their results
+// in the order of calls. Calls sharing a SerialTool key run in order. Once a
+// call is not approved, the calls that have not started yet are skipped.
+// start and done, when non-nil, are called from the call's goroutine around
+// each call that is attempted.
+func (r *Registry) ExecuteBatch(ctx context.Context, calls []providers.ToolCall, limit int,
+	start func(tc providers.ToolCall), done func(tc providers.ToolCall, res CallResult)) []CallResult {
+	if limit < 1 {
+		limit = DefaultParallelCalls
+	}
+	results := make([]CallResult, len(calls))
+	finished := make([]chan struct{}, len(calls))
+	last := make(map[string]chan struct{}) // latest call holding each key
+	sem := make(chan struct{}, limit)
+	var refused atomic.Bool
+	var wg sync.WaitGroup
+	for i, tc := range calls {
+		finished[i] = make(chan struct{})
+		var after []chan struct{}
+		for _, k := range r.serialKeys(tc.Name, tc.Arguments) {
+			if prev, ok := last[k]; ok {
+				after = append(after, prev)
+			}
+			last[k] = finished[i]
+		}
+		wg.Add(1)
+		go func(i int, tc providers.ToolCall, after []chan struct{}) {
+			defer wg.Done()
+			defer close(finished[i])
+			for _, prev := range after {
+				select {
+				case <-prev:
+				case <-ctx.Done():
+				}
+			}
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+			}
+			switch {
+			case ctx.Err() != nil:
+				results[i] = CallResult{Err: ctx.Err()}
+				return
+			case refused.Load():
+				return // skipped: an earlier call was not approved
+			}
+			if start != nil {
+				start(tc)
+			}
+			t0 := time.Now()
+			res, err := r.Execute(ctx, tc.Name, tc.Arguments)
+			results[i] = CallResult{Result: res, Err: err, Elapsed: time.Since(t0).Round(time.Millisecond), Ran: true}
+			if errors.Is(err, ErrNotApproved) {
+				refused.Store(true)
+				results[i].Ran = false
+			}
+			if done != nil {
+				done(tc, results[i])
+			}
+		}(i, tc, after)
+	}
+	wg.Wait()
+	return results
+}
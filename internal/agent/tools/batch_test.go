@@ -0,0 +1,136 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/providers"
+)
+
+// sleepTool takes delay per call and records the order calls finish in. With
+// serial set, all its calls share one serial key.
+type sleepTool struct {
+	name   string
+	delay  time.Duration
+	serial bool
+
+	mu   sync.Mutex
+	done []string
+}
+
+func (t *sleepTool) Name() string                       { return t.name }
+func (t *sleepTool) Description() string                { return "sleeps" }
+func (t *sleepTool) Parameters() map[string]interface{} { return nil }
+func (t *sleepTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	d := t.delay
+	if id == "first" {
+		d *= 2 // finish last unless calls are kept in order
+	}
+	time.Sleep(d)
+	t.mu.Lock()
+	t.done = append(t.done, id)
+	t.mu.Unlock()
+	return t.name + ":" + id, nil
+}
+
+func (t *sleepTool) SerialKey(args map[string]interface{}) string {
+	if t.serial {
+		return t.name
+	}
+	return ""
+}
+
+func calls(name string, ids ...string) []providers.ToolCall {
+	var cs []providers.ToolCall
+	for i, id := range ids {
+		cs = append(cs, providers.ToolCall{ID: fmt.Sprint(name, i), Name: name, Arguments: map[string]interface{}{"id": id}})
+	}
+	return cs
+}
+
+func TestExecuteBatchRunsIndependentCallsConcurrently(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&sleepTool{name: "web", delay: 100 * time.Millisecond})
+
+	start := time.Now()
+	results := r.ExecuteBatch(context.Background(), calls("web", "first", "b", "c"), 0, nil, nil)
+	if elapsed := time.Since(start); elapsed > 350*time.Millisecond {
+		t.Fatalf("three calls of 100-200ms took %s; expected them to overlap", elapsed)
+	}
+	for i, want := range []string{"web:first", "web:b", "web:c"} {
+		if results[i].Result != want || !results[i].Ran {
+			t.Fatalf("result %d = %+v, want %q in call order", i, results[i], want)
+		}
+	}
+
+	start = time.Now()
+	r.ExecuteBatch(context.Background(), calls("web", "a", "b", "c"), 1, nil, nil)
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Fatalf("limit 1 took %s; expected the calls to run one at a time", elapsed)
+	}
+}
+
+func TestExecuteBatchKeepsSerialCallsInOrder(t *testing.T) {
+	r := NewRegistry()
+	fs := &sleepTool{name: "fs", delay: 20 * time.Millisecond, serial: true}
+	r.Register(fs)
+
+	r.ExecuteBatch(context.Background(), calls("fs", "first", "b", "c"), 0, nil, nil)
+	if got := strings.Join(fs.done, ","); got != "first,b,c" {
+		t.Fatalf("serial calls finished as %s, want first,b,c", got)
+	}
+}
+
+func TestExecuteBatchKeepsMemoryChangesInOrder(t *testing.T) {
+	mem := memory.NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	r := NewRegistry()
+	for _, tool := range []Tool{NewWriteMemoryTool(mem), NewEditMemoryTool(mem), NewDeleteMemoryTool(mem)} {
+		r.Register(tool)
+		if keys := r.serialKeys(tool.Name(), nil); len(keys) != 1 || keys[0] != "memory" {
+			t.Fatalf("%s: expected the memory serial key, got %q", tool.Name(), keys)
+		}
+	}
+
+	// the edit only succeeds if it runs after the write
+	results := r.ExecuteBatch(context.Background(), []providers.ToolCall{
+		{ID: "a", Name: "write_memory", Arguments: map[string]interface{}{"target": "long", "content": "likes tea", "append": false}},
+		{ID: "b", Name: "edit_memory", Arguments: map[string]interface{}{"target": "long", "old_text": "tea", "new_text": "coffee"}},
+	}, 0, nil, nil)
+	for _, res := range results {
+		if res.Err != nil {
+			t.Fatalf("unexpected error: %v", res.Err)
+		}
+	}
+	if got, _ := mem.ReadLongTerm(); got != "likes coffee" {
+		t.Fatalf("expected the edit to apply to the write, got %q", got)
+	}
+}
+
+func TestExecuteBatchSkipsCallsAfterRefusal(t *testing.T) {
+	r := NewRegistry()
+	exec := &countingTool{}
+	r.Register(exec)
+	p, err := NewApprovalPolicy([]ApprovalRule{{Tool: "exec"}}, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetApprovalPolicy(p)
+	ap := &answerApprover{ok: false}
+
+	results := r.ExecuteBatch(WithApprover(context.Background(), ap), calls("exec", "a", "b"), 0, nil, nil)
+	if exec.runs != 0 || len(ap.asked) != 1 {
+		t.Fatalf("expected one question and no runs, got %d questions and %d runs", len(ap.asked), exec.runs)
+	}
+	if results[0].Ran || results[0].Err == nil {
+		t.Fatalf("expected the first call to be refused, got %+v", results[0])
+	}
+	if results[1].Ran || results[1].Err != nil {
+		t.Fatalf("expected the second call to be skipped, got %+v", results[1])
+	}
+}
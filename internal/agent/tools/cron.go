@@ -10,12 +10,11 @@ import (
 )
 
 // CronTool schedules delayed/recurring tasks via the cron scheduler.
-// It holds a channel/chatID context (set per-incoming-message) so fired jobs
-// know where to send their notification.
+// Jobs are tied to the request's origin (see WithOrigin) and agent profile
+// (see WithAgent) so fired jobs know where to send their notification and who
+// answers it.
 type CronTool struct {
 	scheduler *cron.Scheduler
-	channel   string
-	chatID    string
 }
 
 func NewCronTool(scheduler *cron.Scheduler) *CronTool {
@@ -61,11 +60,8 @@ func (t *CronTool) Parameters() map[string]interface{} {
 	}
 }
 
-// SetContext sets the originating channel and chat for scheduled jobs.
-func (t *CronTool) SetContext(channel, chatID string) {
-	t.channel = channel
-	t.chatID = chatID
-}
+// SerialKey applies adds and cancels in order.
+func (t *CronTool) SerialKey(args map[string]interface{}) string { return "cron" }
 
 func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	action, _ := args["action"].(string)
@@ -96,6 +92,9 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) (st
 			return "", fmt.Errorf("cron add: delay must be positive")
 		}
 
+		channel, chatID, _ := OriginFrom(ctx)
+		agent := AgentFrom(ctx)
+
 		// Handle recurring jobs
 		if recurring {
 			if intervalStr == "" {
@@ -109,12 +108,12 @@ func (t *CronTool) Execute(ctx context.Context, args map[string]interface{}) (st
 			if interval < 2*time.Minute {
 				return "", fmt.Errorf("cron add: recurring interval must be at least 2m (got %v)", interval)
 			}
-			id := t.scheduler.AddRecurring(name, message, interval, t.channel, t.chatID)
+			id := t.scheduler.AddRecurring(name, message, interval, channel, chatID, agent)
 			return fmt.Sprintf("Scheduled recurring job %q (id: %s). Will fire in %v, then repeat every %v.", name, id, delay, interval), nil
 		}
 
 		// One-time job
-		id := t.scheduler.Add(name, message, delay, t.channel, t.chatID)
+		id := t.scheduler.Add(name, message, delay, channel, chatID, agent)
 		return fmt.Sprintf("Scheduled job %q (id: %s). Will fire in %v.", name, id, delay), nil
 
 	case "list":
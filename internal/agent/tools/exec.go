@@ -77,6 +77,10 @@ func hasUnsafeArg(s string) bool {
 	return false
 }
 
+// SerialKey runs commands one at a time: they often depend on each other's
+// effects on the workspace.
+func (t *ExecTool) SerialKey(args map[string]interface{}) string { return "exec" }
+
 func (t *ExecTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	cmdRaw, ok := args["cmd"]
 	if !ok {
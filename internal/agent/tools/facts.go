@@ -0,0 +1,259 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/local/picobot/internal/agent/memory"
+)
+
+// stringList reads an array of strings (or a comma-separated string) from a
+// tool argument.
+func stringList(v interface{}) []string {
+	var out []string
+	switch v := v.(type) {
+	case []interface{}:
+		for _, x := range v {
+			if s, ok := x.(string); ok {
+				out = append(out, s)
+			}
+		}
+	case string:
+		out = strings.Split(v, ",")
+	}
+	return out
+}
+
+// formatFact renders one fact with its provenance for tool output.
+func formatFact(f memory.FactRecord) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "[%s] %s: %s = %s (%s confidence", f.ID, f.Subject, f.Predicate, f.Object, f.Confidence)
+	if len(f.Tags) > 0 {
+		fmt.Fprintf(&sb, "; tags %s", strings.Join(f.Tags, ", "))
+	}
+	fmt.Fprintf(&sb, "; confirmed %s", f.Confirmed.Format("2006-01-02"))
+	if f.Session != "" {
+		fmt.Fprintf(&sb, " in %s", f.Session)
+	}
+	if f.Previous != "" {
+		fmt.Fprintf(&sb, "; was %s", f.Previous)
+	}
+	sb.WriteString(")")
+	if f.Shared {
+		sb.WriteString(" [shared]")
+	}
+	return sb.String()
+}
+
+// ─── upsert_fact ────
+
+// UpsertFactTool stores or updates a structured fact.
+type UpsertFactTool struct {
+	mem *memory.MemoryStore
+}
+
+func NewUpsertFactTool(mem *memory.MemoryStore) *UpsertFactTool {
+	return &UpsertFactTool{mem: mem}
+}
+
+func (t *UpsertFactTool) Name() string { return "upsert_fact" }
+func (t *UpsertFactTool) Description() string {
+	return "Store a structured fact as subject/predicate/object, e.g. subject 'user', predicate 'city', object 'Lisbon'. A subject has one value per predicate: storing it again updates the old value instead of adding a contradicting one, and storing the same value confirms it."
+}
+func (t *UpsertFactTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subject": map[string]interface{}{
+				"type":        "string",
+				"description": "Who or what the fact is about: 'user' for the person you are talking to, or a name such as 'Ana' or 'project picobot'",
+			},
+			"predicate": map[string]interface{}{
+				"type":        "string",
+				"description": "The attribute or relation, e.g. 'city', 'birthday', 'sister'",
+			},
+			"object": map[string]interface{}{
+				"type":        "string",
+				"description": "The value, e.g. 'Lisbon'",
+			},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Optional tags for finding the fact later, e.g. ['family']",
+			},
+			"confidence": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{memory.ConfidenceLow, memory.ConfidenceMedium, memory.ConfidenceHigh},
+				"description": "How sure you are: 'high' when the user said so directly, 'low' when inferred (default medium)",
+			},
+			"shared": sharedParam,
+		},
+		"required": []string{"subject", "predicate", "object"},
+	}
+}
+
+// SerialKey applies fact changes in order.
+func (t *UpsertFactTool) SerialKey(args map[string]interface{}) string { return "facts" }
+
+func (t *UpsertFactTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	f := memory.FactRecord{Tags: stringList(args["tags"])}
+	f.Subject, _ = args["subject"].(string)
+	f.Predicate, _ = args["predicate"].(string)
+	f.Object, _ = args["object"].(string)
+	f.Confidence, _ = args["confidence"].(string)
+	f.Session, f.Message = memory.SourceFrom(ctx)
+	if strings.TrimSpace(f.Subject) == "" || strings.TrimSpace(f.Predicate) == "" || strings.TrimSpace(f.Object) == "" {
+		return "", fmt.Errorf("upsert_fact: 'subject', 'predicate' and 'object' arguments required")
+	}
+	if isHeartbeatContent(f.Object) {
+		return "", nil // skip silently, like write_memory
+	}
+	mem, err := writableMemoryFor(ctx, t.mem, args)
+	if err != nil {
+		return "", fmt.Errorf("upsert_fact: %w", err)
+	}
+	stored, outcome, err := mem.Facts().Upsert(f)
+	if err != nil {
+		return "", fmt.Errorf("upsert_fact: %w", err)
+	}
+	return outcome + " " + formatFact(stored), nil
+}
+
+// ─── query_facts ────
+
+// QueryFactsTool looks up structured facts.
+type QueryFactsTool struct {
+	mem *memory.MemoryStore
+}
+
+func NewQueryFactsTool(mem *memory.MemoryStore) *QueryFactsTool {
+	return &QueryFactsTool{mem: mem}
+}
+
+func (t *QueryFactsTool) Name() string { return "query_facts" }
+func (t *QueryFactsTool) Description() string {
+	return "Look up structured facts by subject, predicate, tag or free text (including shared facts). With no filters, lists every fact. Shows each fact's id, confidence, tags and where it came from."
+}
+func (t *QueryFactsTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"subject": map[string]interface{}{
+				"type":        "string",
+				"description": "Only facts about this subject, e.g. 'user'",
+			},
+			"predicate": map[string]interface{}{
+				"type":        "string",
+				"description": "Only facts with this predicate, e.g. 'city'",
+			},
+			"tag": map[string]interface{}{
+				"type":        "string",
+				"description": "Only facts with this tag",
+			},
+			"text": map[string]interface{}{
+				"type":        "string",
+				"description": "Only facts containing this text in any field",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of facts (default 20, max 100)",
+			},
+		},
+	}
+}
+
+func (t *QueryFactsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	q := memory.FactQuery{Limit: 20}
+	q.Subject, _ = args["subject"].(string)
+	q.Predicate, _ = args["predicate"].(string)
+	q.Tag, _ = args["tag"].(string)
+	q.Text, _ = args["text"].(string)
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		q.Limit = min(int(l), 100)
+	}
+	facts, err := t.mem.For(ctx).QueryFacts(q)
+	if err != nil {
+		return "", err
+	}
+	if len(facts) == 0 {
+		return "No matching facts.", nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Facts (%d):\n", len(facts))
+	for _, f := range facts {
+		fmt.Fprintf(&sb, "- %s\n", formatFact(f))
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
+// ─── retract_fact ────
+
+// RetractFactTool removes a fact that no longer holds.
+type RetractFactTool struct {
+	mem *memory.MemoryStore
+}
+
+func NewRetractFactTool(mem *memory.MemoryStore) *RetractFactTool {
+	return &RetractFactTool{mem: mem}
+}
+
+func (t *RetractFactTool) Name() string { return "retract_fact" }
+func (t *RetractFactTool) Description() string {
+	return "Remove a fact that is wrong or no longer true, by id or by subject and predicate. To change a value, use upsert_fact instead."
+}
+func (t *RetractFactTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Fact id as shown by query_facts",
+			},
+			"subject": map[string]interface{}{
+				"type":        "string",
+				"description": "Subject of the fact (when no id is given)",
+			},
+			"predicate": map[string]interface{}{
+				"type":        "string",
+				"description": "Predicate of the fact (when no id is given)",
+			},
+			"shared": sharedParam,
+		},
+	}
+}
+
+// SerialKey applies fact changes in order.
+func (t *RetractFactTool) SerialKey(args map[string]interface{}) string { return "facts" }
+
+func (t *RetractFactTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	id, _ := args["id"].(string)
+	subject, _ := args["subject"].(string)
+	predicate, _ := args["predicate"].(string)
+	mem, err := writableMemoryFor(ctx, t.mem, args)
+	if err != nil {
+		return "", fmt.Errorf("retract_fact: %w", err)
+	}
+	fs := mem.Facts()
+	if id == "" {
+		if strings.TrimSpace(subject) == "" || strings.TrimSpace(predicate) == "" {
+			return "", fmt.Errorf("retract_fact: 'id' or 'subject' and 'predicate' arguments required")
+		}
+		f, ok, err := fs.Lookup(subject, predicate)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return "", fmt.Errorf("retract_fact: no fact %s: %s", subject, predicate)
+		}
+		id = f.ID
+	}
+	f, ok, err := fs.Retract(id)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", fmt.Errorf("retract_fact: no fact with id %q", id)
+	}
+	return fmt.Sprintf("retracted %s: %s = %s", f.Subject, f.Predicate, f.Object), nil
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/local/picobot/internal/agent/memory"
+)
+
+func TestFactTools(t *testing.T) {
+	mem := memory.NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	upsert, query, retract := NewUpsertFactTool(mem), NewQueryFactsTool(mem), NewRetractFactTool(mem)
+	ctx := memory.WithSource(context.Background(), "telegram:42", "I moved to Lisbon last week")
+
+	out, err := upsert.Execute(ctx, map[string]interface{}{"subject": "user", "predicate": "city", "object": "Porto"})
+	if err != nil || !strings.HasPrefix(out, "stored ") {
+		t.Fatalf("upsert_fact: %q %v", out, err)
+	}
+	out, err = upsert.Execute(ctx, map[string]interface{}{"subject": "user", "predicate": "city", "object": "Lisbon", "tags": []interface{}{"home"}, "confidence": "high"})
+	if err != nil || !strings.HasPrefix(out, "updated ") || !strings.Contains(out, "was Porto") {
+		t.Fatalf("expected the city to be updated, got %q %v", out, err)
+	}
+	if _, err := upsert.Execute(ctx, map[string]interface{}{"subject": "user", "object": "x"}); err == nil {
+		t.Fatal("expected a missing predicate to be rejected")
+	}
+
+	f, _, _ := mem.Facts().Lookup("user", "city")
+	if f.Session != "telegram:42" || f.Message != "I moved to Lisbon last week" {
+		t.Fatalf("expected the provenance to come from the context, got %+v", f)
+	}
+
+	out, err = query.Execute(ctx, map[string]interface{}{"tag": "home"})
+	if err != nil || !strings.Contains(out, "user: city = Lisbon (high confidence; tags home;") || !strings.Contains(out, "in telegram:42") {
+		t.Fatalf("query_facts: %q %v", out, err)
+	}
+	if out, _ := query.Execute(ctx, map[string]interface{}{"subject": "Ana"}); out != "No matching facts." {
+		t.Fatalf("expected no facts about Ana, got %q", out)
+	}
+
+	if _, err := retract.Execute(ctx, map[string]interface{}{"subject": "user"}); err == nil {
+		t.Fatal("expected retract_fact without a predicate or id to fail")
+	}
+	out, err = retract.Execute(ctx, map[string]interface{}{"subject": "User", "predicate": "city"})
+	if err != nil || out != "retracted user: city = Lisbon" {
+		t.Fatalf("retract_fact: %q %v", out, err)
+	}
+	if _, err := retract.Execute(ctx, map[string]interface{}{"id": f.ID}); err == nil {
+		t.Fatal("expected retracting a missing fact to fail")
+	}
+}
+
+func TestFactToolsUseRequestScope(t *testing.T) {
+	mem := memory.NewMemoryStoreWithWorkspace(t.TempDir(), 10)
+	upsert, query := NewUpsertFactTool(mem), NewQueryFactsTool(mem)
+	alice := memory.WithScope(context.Background(), "telegram:alice")
+
+	upsert.Execute(alice, map[string]interface{}{"subject": "user", "predicate": "pet", "object": "cat"})
+	upsert.Execute(context.Background(), map[string]interface{}{"subject": "house", "predicate": "wifi", "object": "guest123", "shared": true})
+	if _, err := upsert.Execute(alice, map[string]interface{}{"subject": "house", "predicate": "wifi", "object": "hacked", "shared": true}); err == nil {
+		t.Fatal("expected a scoped sender not to change shared facts")
+	}
+	if _, err := NewRetractFactTool(mem).Execute(alice, map[string]interface{}{"subject": "house", "predicate": "wifi", "shared": true}); err == nil {
+		t.Fatal("expected a scoped sender not to retract shared facts")
+	}
+
+	out, _ := query.Execute(alice, map[string]interface{}{})
+	if !strings.Contains(out, "pet = cat") || !strings.Contains(out, "wifi = guest123") || !strings.Contains(out, "[shared]") {
+		t.Fatalf("alice should see her own and the shared facts, got %q", out)
+	}
+	out, _ = query.Execute(memory.WithScope(context.Background(), "telegram:bob"), map[string]interface{}{})
+	if strings.Contains(out, "pet = cat") || !strings.Contains(out, "wifi = guest123") {
+		t.Fatalf("bob should see only the shared facts, got %q", out)
+	}
+}
@@ -59,6 +59,16 @@ func (t *FilesystemTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SerialKey keeps calls on the same path in order, so a read issued after a
+// write sees it.
+func (t *FilesystemTool) SerialKey(args map[string]interface{}) string {
+	p, _ := args["path"].(string)
+	if p == "" {
+		p = "."
+	}
+	return "filesystem:" + filepath.Clean(p)
+}
+
 func (t *FilesystemTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	actionRaw, ok := args["action"]
 	if !ok {
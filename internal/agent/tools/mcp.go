@@ -35,6 +35,10 @@ func (t *MCPTool) Parameters() map[string]interface{} {
 	return t.tool.InputSchema
 }
 
+// SerialKey runs calls to one server in order; MCP tools may change state
+// the next call depends on.
+func (t *MCPTool) SerialKey(args map[string]interface{}) string { return "mcp:" + t.serverName }
+
 func (t *MCPTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	return t.client.CallTool(ctx, t.tool.Name, args)
 }
@@ -25,6 +25,34 @@ func resolveMemoryTarget(target string) (string, error) {
 	}
 }
 
+// sharedParam is the optional argument that points a memory tool at the
+// memory shared by everyone instead of the requester's own.
+var sharedParam = map[string]interface{}{
+	"type":        "boolean",
+	"description": "true to use the memory shared by all users instead of this user's own (only differs when memory is scoped per user or chat, where it is read-only)",
+}
+
+// memoryFor returns the store a memory tool call acts on: the shared tier
+// when args["shared"] is true, otherwise the requester's scope from ctx.
+func memoryFor(ctx context.Context, mem *memory.MemoryStore, args map[string]interface{}) *memory.MemoryStore {
+	if shared, _ := args["shared"].(bool); shared {
+		return mem.Shared()
+	}
+	return mem.For(ctx)
+}
+
+// writableMemoryFor is memoryFor for tools that change memory. The shared
+// tier goes into every user's prompt, so a request from a scoped sender (the
+// scope in ctx, set from the message rather than the model's arguments) may
+// only read it; the CLI, system triggers and unscoped memory write it.
+func writableMemoryFor(ctx context.Context, mem *memory.MemoryStore, args map[string]interface{}) (*memory.MemoryStore, error) {
+	target := memoryFor(ctx, mem, args)
+	if target.ScopeID() == "" && memory.ScopeFrom(ctx) != "" {
+		return nil, fmt.Errorf("shared memory is read-only in this chat; leave out 'shared' to change this user's own memory")
+	}
+	return target, nil
+}
+
 // ─── list_memory ────
 
 // ListMemoryTool lists all files in the agent's memory directory.
@@ -40,10 +68,17 @@ func (t *ListMemoryTool) Name() string { return "list_memory" }
 func (t *ListMemoryTool) Description() string {
 	return "List all memory files (daily notes and long-term memory)"
 }
-func (t *ListMemoryTool) Parameters() map[string]interface{} { return nil }
+func (t *ListMemoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"shared": sharedParam,
+		},
+	}
+}
 
 func (t *ListMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	files, err := t.mem.ListFiles()
+	files, err := memoryFor(ctx, t.mem, args).ListFiles()
 	if err != nil {
 		return "", err
 	}
@@ -66,6 +101,69 @@ func (t *ListMemoryTool) Execute(ctx context.Context, args map[string]interface{
 	return strings.TrimRight(sb.String(), "\n"), nil
 }
 
+// ─── search_memory ────
+
+// SearchMemoryTool runs a full-text search over every memory file.
+type SearchMemoryTool struct {
+	mem *memory.MemoryStore
+}
+
+func NewSearchMemoryTool(mem *memory.MemoryStore) *SearchMemoryTool {
+	return &SearchMemoryTool{mem: mem}
+}
+
+func (t *SearchMemoryTool) Name() string { return "search_memory" }
+func (t *SearchMemoryTool) Description() string {
+	return "Search all memory files (long-term memory and every daily note, including shared memory) for relevant notes. Returns the best matching lines with their file and line number; use read_memory for the full file."
+}
+func (t *SearchMemoryTool) Parameters() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"query": map[string]interface{}{
+				"type":        "string",
+				"description": "Words to search for, e.g. 'dentist appointment'",
+			},
+			"limit": map[string]interface{}{
+				"type":        "integer",
+				"description": "Maximum number of results (default 5, max 20)",
+			},
+		},
+		"required": []string{"query"},
+	}
+}
+
+func (t *SearchMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	query, ok := args["query"].(string)
+	if !ok || strings.TrimSpace(query) == "" {
+		return "", fmt.Errorf("search_memory: 'query' argument required")
+	}
+	limit := 5
+	if l, ok := args["limit"].(float64); ok && l > 0 {
+		limit = min(int(l), 20)
+	}
+	results, err := t.mem.For(ctx).Search(query, limit)
+	if err != nil {
+		return "", err
+	}
+	if len(results) == 0 {
+		return fmt.Sprintf("No memories match %q.", query), nil
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Found %d matches for %q:\n", len(results), query)
+	for _, r := range results {
+		fmt.Fprintf(&sb, "- %s:%d", r.File, r.Line)
+		if r.Shared {
+			sb.WriteString(" [shared]")
+		}
+		if !r.Time.IsZero() {
+			fmt.Fprintf(&sb, " (%s)", r.Time.Format("2006-01-02 15:04"))
+		}
+		fmt.Fprintf(&sb, " %s\n", r.Snippet)
+	}
+	return strings.TrimRight(sb.String(), "\n"), nil
+}
+
 // ─── read_memory ────
 
 // ReadMemoryTool reads the contents of a specific memory file.
@@ -87,6 +185,7 @@ func (t *ReadMemoryTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "'today' for today's note, 'long' for long-term memory, or a date 'YYYY-MM-DD'",
 			},
+			"shared": sharedParam,
 		},
 		"required": []string{"target"},
 	}
@@ -101,7 +200,7 @@ func (t *ReadMemoryTool) Execute(ctx context.Context, args map[string]interface{
 	if err != nil {
 		return "", err
 	}
-	content, err := t.mem.ReadFile(name)
+	content, err := memoryFor(ctx, t.mem, args).ReadFile(name)
 	if err != nil {
 		return "", err
 	}
@@ -140,11 +239,15 @@ func (t *EditMemoryTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Replacement text (omit or set to empty string to delete the matched text)",
 			},
+			"shared": sharedParam,
 		},
 		"required": []string{"target", "old_text"},
 	}
 }
 
+// SerialKey applies memory changes in order.
+func (t *EditMemoryTool) SerialKey(args map[string]interface{}) string { return "memory" }
+
 func (t *EditMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	target, ok := args["target"].(string)
 	if !ok || target == "" {
@@ -164,7 +267,11 @@ func (t *EditMemoryTool) Execute(ctx context.Context, args map[string]interface{
 	if err != nil {
 		return "", err
 	}
-	content, err := t.mem.ReadFile(name)
+	mem, err := writableMemoryFor(ctx, t.mem, args)
+	if err != nil {
+		return "", fmt.Errorf("edit_memory: %w", err)
+	}
+	content, err := mem.ReadFile(name)
 	if err != nil {
 		return "", err
 	}
@@ -172,7 +279,7 @@ func (t *EditMemoryTool) Execute(ctx context.Context, args map[string]interface{
 		return "", fmt.Errorf("edit_memory: text not found in %s", name)
 	}
 	updated := strings.ReplaceAll(content, oldText, newText)
-	if err := t.mem.WriteFile(name, updated); err != nil {
+	if err := mem.WriteFile(name, updated); err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("edited %s", name), nil
@@ -202,11 +309,15 @@ func (t *DeleteMemoryTool) Parameters() map[string]interface{} {
 				"type":        "string",
 				"description": "Date of the daily note to delete, in 'YYYY-MM-DD' format",
 			},
+			"shared": sharedParam,
 		},
 		"required": []string{"target"},
 	}
 }
 
+// SerialKey applies memory changes in order.
+func (t *DeleteMemoryTool) SerialKey(args map[string]interface{}) string { return "memory" }
+
 func (t *DeleteMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	target, ok := args["target"].(string)
 	if !ok || target == "" {
@@ -216,7 +327,11 @@ func (t *DeleteMemoryTool) Execute(ctx context.Context, args map[string]interfac
 	if _, err := time.Parse("2006-01-02", target); err != nil {
 		return "", fmt.Errorf("delete_memory: target must be a date in YYYY-MM-DD format, got %q", target)
 	}
-	if err := t.mem.DeleteFile(target + ".md"); err != nil {
+	mem, err := writableMemoryFor(ctx, t.mem, args)
+	if err != nil {
+		return "", fmt.Errorf("delete_memory: %w", err)
+	}
+	if err := mem.DeleteFile(target + ".md"); err != nil {
 		return "", err
 	}
 	return fmt.Sprintf("deleted %s.md", target), nil
@@ -9,11 +9,9 @@ import (
 )
 
 // MessageTool sends messages to a channel via the chat Hub.
-// It holds a context (channel + chatID) which should be set per-incoming-message.
+// The destination is the request's origin, read from the context (see WithOrigin).
 type MessageTool struct {
-	hub     *chat.Hub
-	channel string
-	chatID  string
+	hub *chat.Hub
 }
 
 func NewMessageTool(b *chat.Hub) *MessageTool {
@@ -36,11 +34,8 @@ func (m *MessageTool) Parameters() map[string]interface{} {
 	}
 }
 
-// SetContext sets the current channel and chat id for outgoing messages.
-func (m *MessageTool) SetContext(channel, chatID string) {
-	m.channel = channel
-	m.chatID = chatID
-}
+// SerialKey keeps messages in the order the model sent them.
+func (m *MessageTool) SerialKey(args map[string]interface{}) string { return "message" }
 
 // Expected args: {"content": "..."}
 func (m *MessageTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
@@ -57,10 +52,14 @@ func (m *MessageTool) Execute(ctx context.Context, args map[string]interface{})
 	if content == "" {
 		return "", fmt.Errorf("message tool: 'content' argument required")
 	}
+	channel, chatID, ok := OriginFrom(ctx)
+	if !ok {
+		return "", fmt.Errorf("message tool: no originating chat for this request")
+	}
 	// Publish outbound message to hub
 	out := chat.Outbound{
-		Channel: m.channel,
-		ChatID:  m.chatID,
+		Channel: channel,
+		ChatID:  chatID,
 		Content: content,
 	}
 	select {
@@ -0,0 +1,39 @@
+package tools
+
+import "context"
+
+type originKey struct{}
+
+// origin is the channel and chat a request came from.
+type origin struct {
+	channel string
+	chatID  string
+}
+
+// WithOrigin returns a context carrying the originating channel and chat of a
+// request. Tools that reply or schedule work (message, cron) read it with
+// OriginFrom, so concurrent requests never share this state.
+func WithOrigin(ctx context.Context, channel, chatID string) context.Context {
+	return context.WithValue(ctx, originKey{}, origin{channel: channel, chatID: chatID})
+}
+
+// OriginFrom returns the channel and chat stored by WithOrigin. ok is false if
+// the context carries no origin.
+func OriginFrom(ctx context.Context) (channel, chatID string, ok bool) {
+	o, ok := ctx.Value(originKey{}).(origin)
+	return o.channel, o.chatID, ok
+}
+
+type agentKey struct{}
+
+// WithAgent returns a context carrying the name of the agent profile handling
+// a request, so that the cron tool can hand its reminders back to it.
+func WithAgent(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, agentKey{}, name)
+}
+
+// AgentFrom returns the profile stored by WithAgent, or "" if there is none.
+func AgentFrom(ctx context.Context) string {
+	name, _ := ctx.Value(agentKey{}).(string)
+	return name
+}
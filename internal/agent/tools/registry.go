@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"sync"
 	"time"
@@ -23,8 +24,9 @@ type Tool interface {
 
 // Registry holds registered tools.
 type Registry struct {
-	mu    sync.RWMutex
-	tools map[string]Tool
+	mu     sync.RWMutex
+	tools  map[string]Tool
+	policy *ApprovalPolicy
 }
 
 // NewRegistry constructs a new tool registry.
@@ -46,6 +48,31 @@ func (r *Registry) Get(name string) Tool {
 	return r.tools[name]
 }
 
+// Subset returns a registry holding only the named tools, with the same
+// approval policy.
+func (r *Registry) Subset(names []string) (*Registry, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	sub := &Registry{tools: make(map[string]Tool, len(names)), policy: r.policy}
+	for _, name := range names {
+		t, ok := r.tools[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown tool %q", name)
+		}
+		sub.tools[name] = t
+	}
+	return sub, nil
+}
+
+// SetApprovalPolicy makes calls matching p wait for approval from the
+// request's Approver (see WithApprover) before they run. nil runs every call
+// immediately.
+func (r *Registry) SetApprovalPolicy(p *ApprovalPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+}
+
 // Definitions returns the list of tool definitions to expose to the model.
 func (r *Registry) Definitions() []providers.ToolDefinition {
 	r.mu.RLock()
@@ -68,17 +95,42 @@ func (r *Registry) Execute(ctx context.Context, name string, args map[string]int
 	}
 	r.mu.RLock()
 	t, ok := r.tools[name]
+	policy := r.policy
 	r.mu.RUnlock()
 	if !ok {
 		return "", errors.New("tool not found")
 	}
+	if policy != nil && policy.Requires(name, args) {
+		if err := policy.approve(ctx, name, args); err != nil {
+			log.Printf("[tool] ✗ %s not run: %v", name, err)
+			return "", err
+		}
+	}
 
 	// Log tool execution start
 	argsJSON, _ := json.Marshal(args)
 	log.Printf("[tool] → %s %s", name, argsJSON)
 	start := time.Now()
 
-	result, err := t.Execute(ctx, args)
+	// Run the tool in its own goroutine so a cancelled turn returns at once,
+	// even if the tool (e.g. an MCP call) does not honour ctx itself.
+	type outcome struct {
+		result string
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := t.Execute(ctx, args)
+		done <- outcome{result, err}
+	}()
+	var result string
+	var err error
+	select {
+	case o := <-done:
+		result, err = o.result, o.err
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
 	elapsed := time.Since(start).Round(time.Millisecond)
 
 	if err != nil {
@@ -11,11 +11,10 @@ import (
 func TestMessageToolPublishesOutbound(t *testing.T) {
 	b := chat.NewHub(10)
 	mt := NewMessageTool(b)
-	mt.SetContext("cli", "test-chat")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
-	res, err := mt.Execute(ctx, map[string]interface{}{"content": "hello world"})
+	res, err := mt.Execute(WithOrigin(ctx, "cli", "test-chat"), map[string]interface{}{"content": "hello world"})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -25,10 +24,45 @@ func TestMessageToolPublishesOutbound(t *testing.T) {
 
 	select {
 	case out := <-b.Out:
-		if out.Content != "hello world" {
-			t.Fatalf("unexpected content: %s", out.Content)
+		if out.Content != "hello world" || out.Channel != "cli" || out.ChatID != "test-chat" {
+			t.Fatalf("unexpected outbound: %+v", out)
 		}
 	default:
 		t.Fatalf("no outbound message published")
 	}
 }
+
+func TestMessageToolRequiresOrigin(t *testing.T) {
+	mt := NewMessageTool(chat.NewHub(10))
+	if _, err := mt.Execute(context.Background(), map[string]interface{}{"content": "hi"}); err == nil {
+		t.Fatalf("expected error without an originating chat")
+	}
+}
+
+// stuckTool ignores cancellation, like a tool blocked on a remote call.
+type stuckTool struct{ release chan struct{} }
+
+func (s stuckTool) Name() string                       { return "stuck" }
+func (s stuckTool) Description() string                { return "never returns on its own" }
+func (s stuckTool) Parameters() map[string]interface{} { return nil }
+func (s stuckTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	<-s.release
+	return "late", nil
+}
+
+func TestRegistryExecuteReturnsOnCancel(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	r := NewRegistry()
+	r.Register(stuckTool{release: release})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	start := time.Now()
+	if _, err := r.Execute(ctx, "stuck", nil); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatalf("Execute did not return promptly after cancellation")
+	}
+}
@@ -172,6 +172,9 @@ func (t *CreateSkillTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SerialKey applies skill changes in order.
+func (t *CreateSkillTool) SerialKey(args map[string]interface{}) string { return "skills" }
+
 func (t *CreateSkillTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	name, ok := args["name"].(string)
 	if !ok {
@@ -293,6 +296,9 @@ func (t *DeleteSkillTool) Parameters() map[string]interface{} {
 	}
 }
 
+// SerialKey applies skill changes in order.
+func (t *DeleteSkillTool) SerialKey(args map[string]interface{}) string { return "skills" }
+
 func (t *DeleteSkillTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
 	name, ok := args["name"].(string)
 	if !ok {
@@ -3,29 +3,113 @@ package tools
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 )
 
-// SpawnTool creates a background subagent; stubbed for v0.
-// Args: {"agent": "name", "task": "..."}
+// Sub-agent states reported in SubAgentStatus.State.
+const (
+	SubAgentRunning   = "running"
+	SubAgentDone      = "done"
+	SubAgentFailed    = "failed"
+	SubAgentCancelled = "cancelled"
+)
+
+// defaultSpawnWait is how long a wait action blocks when no timeout is given.
+const defaultSpawnWait = 2 * time.Minute
+
+// SubAgentRequest describes a task handed to a background sub-agent.
+type SubAgentRequest struct {
+	Name          string        // short label shown in status reports
+	Task          string        // what the sub-agent should do
+	Tools         []string      // tools it may use; nil selects the default set
+	MaxIterations int           // model calls it may make; 0 selects the default
+	Timeout       time.Duration // time it may take; 0 selects the default
+	// Deliver posts the result to the originating chat when the sub-agent
+	// finishes, unless the parent is waiting for it at that moment.
+	Deliver bool
+}
 
-type SpawnTool struct{}
+// SubAgentStatus reports a sub-agent's progress and, once it has finished,
+// its result or error.
+type SubAgentStatus struct {
+	ID         string
+	Name       string
+	Task       string
+	State      string
+	Result     string
+	Error      string
+	Iterations int
+	Started    time.Time
+	Finished   time.Time
+}
+
+// Spawner runs sub-agents in the background. Every method except Spawn only
+// sees the sub-agents started from the chat in ctx (see WithOrigin).
+type Spawner interface {
+	Spawn(ctx context.Context, req SubAgentRequest) (SubAgentStatus, error)
+	Status(ctx context.Context, id string) (SubAgentStatus, bool)
+	// Wait blocks until sub-agent id finishes or ctx is done, and returns
+	// its latest status either way.
+	Wait(ctx context.Context, id string) (SubAgentStatus, error)
+	List(ctx context.Context) []SubAgentStatus
+	Cancel(ctx context.Context, id string) bool
+}
 
-func NewSpawnTool() *SpawnTool { return &SpawnTool{} }
+// SpawnTool starts sub-agents that work on a task in the background and lets
+// the agent check on them, wait for their results or cancel them.
+// Args: {"action": "start", "task": "...", "agent": "name", "tools": [...]}
+// or {"action": "status"|"wait"|"cancel", "id": "..."} or {"action": "list"}.
+type SpawnTool struct {
+	spawner Spawner
+}
 
-func (t *SpawnTool) Name() string        { return "spawn" }
-func (t *SpawnTool) Description() string { return "Spawn a background subagent (stub)" }
+func NewSpawnTool(s Spawner) *SpawnTool { return &SpawnTool{spawner: s} }
+
+func (t *SpawnTool) Name() string { return "spawn" }
+func (t *SpawnTool) Description() string {
+	return "Run a task in the background with a sub-agent that has its own tools and budget, so the conversation can go on meanwhile. " +
+		"Actions: start (returns an id; the result is posted to this chat when done), status, wait (block until done), list, cancel. " +
+		"Use wait when you need the result for your current reply."
+}
 
 func (t *SpawnTool) Parameters() map[string]interface{} {
 	return map[string]interface{}{
 		"type": "object",
 		"properties": map[string]interface{}{
+			"action": map[string]interface{}{
+				"type":        "string",
+				"enum":        []string{"start", "status", "wait", "list", "cancel"},
+				"description": "What to do (default start)",
+			},
 			"agent": map[string]interface{}{
 				"type":        "string",
-				"description": "The name of the agent to spawn",
+				"description": "Short name for the sub-agent, e.g. 'research' (start)",
 			},
 			"task": map[string]interface{}{
 				"type":        "string",
-				"description": "The task description for the spawned agent",
+				"description": "Complete, self-contained instructions: the sub-agent sees nothing of this conversation (start)",
+			},
+			"tools": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string"},
+				"description": "Tools the sub-agent may use (start; default: the read-only tools web, web_search and the memory and skill reading tools)",
+			},
+			"max_iterations": map[string]interface{}{
+				"type":        "integer",
+				"description": "Model calls the sub-agent may make (start; default 10)",
+			},
+			"timeout_s": map[string]interface{}{
+				"type":        "integer",
+				"description": "Seconds the sub-agent may run (start; default 300), or to wait (wait; default 120)",
+			},
+			"deliver": map[string]interface{}{
+				"type":        "boolean",
+				"description": "Post the result to this chat when done (start; default true)",
+			},
+			"id": map[string]interface{}{
+				"type":        "string",
+				"description": "Sub-agent id (status, wait, cancel)",
 			},
 		},
 		"required": []string{},
@@ -33,11 +117,112 @@ func (t *SpawnTool) Parameters() map[string]interface{} {
 }
 
 func (t *SpawnTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
-	agentName, _ := args["agent"].(string)
-	task, _ := args["task"].(string)
-	if agentName == "" && task == "" {
-		return "", fmt.Errorf("spawn: 'agent' or 'task' required")
+	if t.spawner == nil {
+		return "", fmt.Errorf("spawn: sub-agents are not available")
+	}
+	action, _ := args["action"].(string)
+	if action == "" {
+		action = "start"
+	}
+	id, _ := args["id"].(string)
+	secs, _ := args["timeout_s"].(float64)
+	timeout := time.Duration(secs) * time.Second
+
+	switch action {
+	case "start":
+		task, _ := args["task"].(string)
+		if strings.TrimSpace(task) == "" {
+			return "", fmt.Errorf("spawn: 'task' is required")
+		}
+		iterations, _ := args["max_iterations"].(float64)
+		req := SubAgentRequest{
+			Task:          task,
+			Tools:         stringList(args["tools"]),
+			MaxIterations: int(iterations),
+			Timeout:       timeout,
+			Deliver:       true,
+		}
+		req.Name, _ = args["agent"].(string)
+		if d, ok := args["deliver"].(bool); ok {
+			req.Deliver = d
+		}
+		st, err := t.spawner.Spawn(ctx, req)
+		if err != nil {
+			return "", fmt.Errorf("spawn: %w", err)
+		}
+		if req.Deliver {
+			return fmt.Sprintf("started sub-agent %s; its result will be posted to this chat", st.ID), nil
+		}
+		return fmt.Sprintf("started sub-agent %s; use wait or status to get its result", st.ID), nil
+	case "status":
+		st, ok := t.spawner.Status(ctx, id)
+		if !ok {
+			return "", fmt.Errorf("spawn: no sub-agent %q", id)
+		}
+		return formatSubAgent(st, true), nil
+	case "wait":
+		if _, ok := t.spawner.Status(ctx, id); !ok {
+			return "", fmt.Errorf("spawn: no sub-agent %q", id)
+		}
+		if timeout <= 0 {
+			timeout = defaultSpawnWait
+		}
+		wctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		st, err := t.spawner.Wait(wctx, id)
+		if err != nil && ctx.Err() != nil {
+			return "", err
+		}
+		return formatSubAgent(st, true), nil
+	case "list":
+		list := t.spawner.List(ctx)
+		if len(list) == 0 {
+			return "No sub-agents.", nil
+		}
+		lines := make([]string, len(list))
+		for i, st := range list {
+			lines[i] = formatSubAgent(st, false)
+		}
+		return strings.Join(lines, "\n"), nil
+	case "cancel":
+		if !t.spawner.Cancel(ctx, id) {
+			return "", fmt.Errorf("spawn: no running sub-agent %q", id)
+		}
+		return fmt.Sprintf("cancelled sub-agent %s", id), nil
+	}
+	return "", fmt.Errorf("spawn: unknown action %s", action)
+}
+
+// formatSubAgent renders a status line, followed by the result or error when
+// full is set.
+func formatSubAgent(st SubAgentStatus, full bool) string {
+	label := st.ID
+	if st.Name != "" {
+		label += " (" + st.Name + ")"
+	}
+	var line string
+	switch st.State {
+	case SubAgentRunning:
+		line = fmt.Sprintf("%s: running for %s, %d model call(s) so far", label, time.Since(st.Started).Round(time.Second), st.Iterations)
+	default:
+		line = fmt.Sprintf("%s: %s after %s", label, st.State, st.Finished.Sub(st.Started).Round(time.Second))
+	}
+	if !full {
+		return line + " - " + truncate(st.Task, 80)
+	}
+	switch {
+	case st.Error != "":
+		return line + "\nerror: " + st.Error
+	case st.State == SubAgentDone:
+		return line + "\n" + st.Result
+	}
+	return line
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
 	}
-	// For v0 we simply return an acknowledgement
-	return fmt.Sprintf("spawned: agent=%s task=%s", agentName, task), nil
+	return string(r[:n]) + "…"
 }
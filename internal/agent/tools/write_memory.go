@@ -64,11 +64,15 @@ func (w *WriteMemoryTool) Parameters() map[string]interface{} {
 				"description": "If true, append to existing content; if false, overwrite",
 				"default":     true,
 			},
+			"shared": sharedParam,
 		},
 		"required": []string{"target", "content"},
 	}
 }
 
+// SerialKey applies memory changes in order.
+func (w *WriteMemoryTool) SerialKey(args map[string]interface{}) string { return "memory" }
+
 // Expected args:
 // {"target": "today"|"long", "content": "...", "append": true|false }
 func (w *WriteMemoryTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
@@ -98,25 +102,29 @@ func (w *WriteMemoryTool) Execute(ctx context.Context, args map[string]interface
 		}
 	}
 
+	mem, err := writableMemoryFor(ctx, w.mem, args)
+	if err != nil {
+		return "", fmt.Errorf("write_memory: %w", err)
+	}
 	switch target {
 	case "today":
-		if err := w.mem.AppendToday(content); err != nil {
+		if err := mem.AppendToday(content); err != nil {
 			return "", err
 		}
 		return "appended to today", nil
 	case "long":
 		if appendFlag {
-			prev, err := w.mem.ReadLongTerm()
+			prev, err := mem.ReadLongTerm()
 			if err != nil {
 				return "", err
 			}
 			new := prev + "\n" + content
-			if err := w.mem.WriteLongTerm(new); err != nil {
+			if err := mem.WriteLongTerm(new); err != nil {
 				return "", err
 			}
 			return "appended to long-term memory", nil
 		}
-		if err := w.mem.WriteLongTerm(content); err != nil {
+		if err := mem.WriteLongTerm(content); err != nil {
 			return "", err
 		}
 		return "wrote long-term memory", nil
@@ -0,0 +1,194 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/local/picobot/internal/agent/tools"
+	"github.com/local/picobot/internal/providers"
+)
+
+// noReply is the reply of a turn that ended without any text to show.
+const noReply = "I've completed processing but have no response to give."
+
+// TurnHooks observe the steps of a turn. Every hook is optional.
+type TurnHooks struct {
+	// BeforeLLM is called before each model call with the prompt about to
+	// be sent. iteration counts model calls from 1.
+	BeforeLLM func(ctx context.Context, iteration int, messages []providers.Message)
+	// AfterLLM is called after each model call, also when it failed or was
+	// cancelled.
+	AfterLLM func(ctx context.Context, iteration int, resp providers.LLMResponse, err error)
+	// BeforeTool is called when a tool call starts. Independent tool calls
+	// run concurrently, so it may be called from several goroutines at once.
+	BeforeTool func(ctx context.Context, call providers.ToolCall)
+	// AfterTool is called when a tool call finishes or is refused, from the
+	// goroutine that ran it.
+	AfterTool func(ctx context.Context, call providers.ToolCall, result tools.CallResult)
+	// Final is called once with the outcome of the turn.
+	Final func(ctx context.Context, result *TurnResult)
+}
+
+// TurnResult is the outcome of a turn.
+type TurnResult struct {
+	// Content is the reply: the model's final text, or when there is none
+	// the last tool result or a stock sentence. It is empty when the turn
+	// failed or was cancelled.
+	Content string
+	// Messages are the assistant and tool messages the turn added to the
+	// conversation, in order. Every tool call has a result, also when the
+	// turn was cancelled, so they can be replayed as history.
+	Messages   []providers.Message
+	Iterations int
+	// Exhausted is set when MaxIterations model calls were made without a
+	// final reply.
+	Exhausted bool
+	// Err is set when a model call failed.
+	Err error
+	// Stopped is set when a tool call was not approved. The turn ends there,
+	// and Content says why, so the model cannot carry on around a refusal.
+	Stopped error
+	// Cancelled is set when ctx was cancelled during the turn.
+	Cancelled bool
+}
+
+// Turn answers one request: it calls the model, runs the tool calls it asks
+// for and feeds their results back, until the model replies without tool
+// calls or MaxIterations model calls were made.
+type Turn struct {
+	Provider        providers.LLMProvider
+	Model           string
+	Tools           *tools.Registry
+	MaxIterations   int
+	ToolConcurrency int // see tools.Registry.ExecuteBatch
+	// Chat, if set, makes the model calls instead of Provider.Chat, e.g. to
+	// stream the reply.
+	Chat  func(ctx context.Context, iteration int, messages []providers.Message, defs []providers.ToolDefinition) (providers.LLMResponse, error)
+	Hooks []TurnHooks // called in order
+}
+
+// Run runs the turn on the prompt messages.
+func (t *Turn) Run(ctx context.Context, messages []providers.Message) *TurnResult {
+	res := &TurnResult{}
+	defs := t.Tools.Definitions()
+	lastToolResult := ""
+	replied := false
+	for res.Iterations < t.MaxIterations {
+		res.Iterations++
+		for _, h := range t.Hooks {
+			if h.BeforeLLM != nil {
+				h.BeforeLLM(ctx, res.Iterations, messages)
+			}
+		}
+		resp, err := t.chat(ctx, res.Iterations, messages, defs)
+		for _, h := range t.Hooks {
+			if h.AfterLLM != nil {
+				h.AfterLLM(ctx, res.Iterations, resp, err)
+			}
+		}
+		if ctx.Err() != nil {
+			res.Cancelled = true
+			return t.final(ctx, res)
+		}
+		if err != nil {
+			res.Err = err
+			return t.final(ctx, res)
+		}
+		if !resp.HasToolCalls {
+			res.Content = resp.Content
+			replied = true
+			break
+		}
+
+		call := providers.Message{Role: "assistant", Content: resp.Content, ToolCalls: resp.ToolCalls}
+		messages = append(messages, call)
+		res.Messages = append(res.Messages, call)
+		// independent tool calls run concurrently; results go back in the
+		// order the calls were made
+		results := t.Tools.ExecuteBatch(ctx, resp.ToolCalls, t.ToolConcurrency, t.beforeTool(ctx), t.afterTool(ctx))
+		if ctx.Err() != nil {
+			for i, tc := range resp.ToolCalls {
+				content := cancelledMarker
+				if r := results[i]; r.Ran && r.Err == nil {
+					content = r.Result
+				}
+				res.Messages = append(res.Messages, providers.Message{Role: "tool", Content: content, ToolCallID: tc.ID})
+			}
+			res.Cancelled = true
+			return t.final(ctx, res)
+		}
+		for i, tc := range resp.ToolCalls {
+			content := toolResult(results[i])
+			if err := results[i].Err; errors.Is(err, tools.ErrNotApproved) && res.Stopped == nil {
+				res.Stopped = err
+			}
+			lastToolResult = content
+			m := providers.Message{Role: "tool", Content: content, ToolCallID: tc.ID}
+			messages = append(messages, m)
+			res.Messages = append(res.Messages, m)
+		}
+		if res.Stopped != nil {
+			res.Content = fmt.Sprintf("Stopped: %v.", res.Stopped)
+			replied = true
+			break
+		}
+	}
+	res.Exhausted = !replied
+
+	if res.Content == "" {
+		res.Content = lastToolResult
+	}
+	if res.Content == "" {
+		res.Content = noReply
+	}
+	return t.final(ctx, res)
+}
+
+func (t *Turn) chat(ctx context.Context, iteration int, messages []providers.Message, defs []providers.ToolDefinition) (providers.LLMResponse, error) {
+	if t.Chat != nil {
+		return t.Chat(ctx, iteration, messages, defs)
+	}
+	return t.Provider.Chat(ctx, messages, defs, t.Model)
+}
+
+func (t *Turn) beforeTool(ctx context.Context) func(providers.ToolCall) {
+	return func(tc providers.ToolCall) {
+		for _, h := range t.Hooks {
+			if h.BeforeTool != nil {
+				h.BeforeTool(ctx, tc)
+			}
+		}
+	}
+}
+
+func (t *Turn) afterTool(ctx context.Context) func(providers.ToolCall, tools.CallResult) {
+	return func(tc providers.ToolCall, r tools.CallResult) {
+		for _, h := range t.Hooks {
+			if h.AfterTool != nil {
+				h.AfterTool(ctx, tc, r)
+			}
+		}
+	}
+}
+
+func (t *Turn) final(ctx context.Context, res *TurnResult) *TurnResult {
+	for _, h := range t.Hooks {
+		if h.Final != nil {
+			h.Final(ctx, res)
+		}
+	}
+	return res
+}
+
+// toolResult returns the content reporting a tool call's outcome to the
+// model.
+func toolResult(r tools.CallResult) string {
+	switch {
+	case r.Err != nil:
+		return "(tool error) " + r.Err.Error()
+	case !r.Ran:
+		return "[not run: another tool call was not approved]"
+	}
+	return r.Result
+}
@@ -0,0 +1,124 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/agent/tools"
+	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/providers"
+)
+
+// lookupLoopProvider calls the lookup tool until it has seen `rounds` tool
+// results, then replies with the last one. With rounds < 0 it never stops.
+type lookupLoopProvider struct{ rounds int }
+
+func (p lookupLoopProvider) Chat(ctx context.Context, messages []providers.Message, defs []providers.ToolDefinition, model string) (providers.LLMResponse, error) {
+	seen := 0
+	for _, m := range messages {
+		if m.Role == "tool" {
+			seen++
+		}
+	}
+	if p.rounds >= 0 && seen >= p.rounds {
+		return providers.LLMResponse{Content: "answer: " + messages[len(messages)-1].Content}, nil
+	}
+	tc := providers.ToolCall{ID: fmt.Sprint("c", seen), Name: "lookup", Arguments: map[string]interface{}{"topic": "moons"}}
+	return providers.LLMResponse{HasToolCalls: true, ToolCalls: []providers.ToolCall{tc}}, nil
+}
+func (lookupLoopProvider) GetDefaultModel() string { return "fake" }
+
+func TestTurnCallsHooksInOrder(t *testing.T) {
+	reg := tools.NewRegistry()
+	reg.Register(lookupTool{})
+	var mu sync.Mutex
+	var events []string
+	add := func(e string) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+	turn := &Turn{
+		Provider:      lookupLoopProvider{rounds: 1},
+		Model:         "fake",
+		Tools:         reg,
+		MaxIterations: 5,
+		Hooks: []TurnHooks{{
+			BeforeLLM: func(ctx context.Context, i int, _ []providers.Message) { add(fmt.Sprint("before-llm ", i)) },
+			AfterLLM: func(ctx context.Context, i int, _ providers.LLMResponse, err error) {
+				add(fmt.Sprint("after-llm ", i, " ", err))
+			},
+			BeforeTool: func(ctx context.Context, tc providers.ToolCall) { add("before-tool " + tc.Name) },
+			AfterTool: func(ctx context.Context, tc providers.ToolCall, r tools.CallResult) {
+				add("after-tool " + tc.Name + " " + r.Result)
+			},
+			Final: func(ctx context.Context, res *TurnResult) { add("final " + res.Content) },
+		}},
+	}
+
+	res := turn.Run(context.Background(), []providers.Message{{Role: "user", Content: "moons of Mars?"}})
+	if res.Content != "answer: Phobos and Deimos" || res.Iterations != 2 || res.Exhausted {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(res.Messages) != 2 || res.Messages[0].Role != "assistant" || res.Messages[1].Role != "tool" {
+		t.Fatalf("expected the tool call and its result, got %+v", res.Messages)
+	}
+	want := []string{
+		"before-llm 1", "after-llm 1 <nil>",
+		"before-tool lookup", "after-tool lookup Phobos and Deimos",
+		"before-llm 2", "after-llm 2 <nil>",
+		"final answer: Phobos and Deimos",
+	}
+	if strings.Join(events, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected hook order:\n%s", strings.Join(events, "\n"))
+	}
+}
+
+func TestTurnFallsBackToLastToolResult(t *testing.T) {
+	reg := tools.NewRegistry()
+	reg.Register(lookupTool{})
+	turn := &Turn{Provider: lookupLoopProvider{rounds: -1}, Model: "fake", Tools: reg, MaxIterations: 2}
+
+	res := turn.Run(context.Background(), []providers.Message{{Role: "user", Content: "moons of Mars?"}})
+	if !res.Exhausted || res.Iterations != 2 || res.Content != "Phobos and Deimos" {
+		t.Fatalf("expected the last tool result after two calls, got %+v", res)
+	}
+}
+
+func TestProcessDirectMatchesRun(t *testing.T) {
+	ag := NewAgentLoop(chat.NewHub(10), lookupLoopProvider{rounds: -1}, "fake", 2, t.TempDir(), nil, nil)
+	ag.tools.Register(lookupTool{})
+
+	// out of iterations: the same fallback as in chats
+	got, err := ag.ProcessDirect("moons of Mars?", 2*time.Second)
+	if err != nil || got != "Phobos and Deimos" {
+		t.Fatalf("expected the last tool result, got %q (%v)", got, err)
+	}
+
+	// hooks see the tool calls
+	var ran []string
+	var mu sync.Mutex
+	hooks := TurnHooks{BeforeTool: func(ctx context.Context, tc providers.ToolCall) {
+		mu.Lock()
+		ran = append(ran, tc.Name)
+		mu.Unlock()
+	}}
+	if _, err := ag.ProcessDirect("moons of Mars?", 2*time.Second, hooks); err != nil {
+		t.Fatal(err)
+	}
+	if len(ran) != 2 {
+		t.Fatalf("expected two lookups, got %v", ran)
+	}
+
+	// the remember shortcut and commands answer without the model
+	if got, _ := ag.ProcessDirect("remember that Phobos is the larger moon", time.Second); got != "OK, I've remembered that." {
+		t.Fatalf("expected the remember shortcut, got %q", got)
+	}
+	if got, _ := ag.ProcessDirect("/nope", time.Second); !strings.Contains(got, "Unknown command /nope") {
+		t.Fatalf("expected the unknown command hint, got %q", got)
+	}
+}
@@ -10,13 +10,17 @@ import (
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/media"
 )
 
 // discordSender is the subset of *discordgo.Session used for outbound operations.
 // It exists to enable testing without a live Discord WebSocket connection.
 type discordSender interface {
 	ChannelMessageSend(channelID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, options ...discordgo.RequestOption) (*discordgo.Message, error)
+	ChannelMessageEdit(channelID, messageID, content string, options ...discordgo.RequestOption) (*discordgo.Message, error)
 	ChannelTyping(channelID string, options ...discordgo.RequestOption) error
+	InteractionRespond(interaction *discordgo.Interaction, resp *discordgo.InteractionResponse, options ...discordgo.RequestOption) error
 }
 
 // StartDiscord starts a Discord bot using the discordgo library.
@@ -34,7 +38,9 @@ func StartDiscord(ctx context.Context, hub *chat.Hub, token string, allowFrom []
 	session.Identify.Intents = discordgo.IntentsGuilds |
 		discordgo.IntentsGuildMessages |
 		discordgo.IntentsDirectMessages |
-		discordgo.IntentsMessageContent
+		discordgo.IntentsMessageContent |
+		discordgo.IntentsGuildMessageReactions |
+		discordgo.IntentsDirectMessageReactions
 
 	if err := session.Open(); err != nil {
 		return fmt.Errorf("failed to open discord connection: %w", err)
@@ -51,6 +57,8 @@ func StartDiscord(ctx context.Context, hub *chat.Hub, token string, allowFrom []
 
 	client := newDiscordClient(ctx, session, hub, botUser.ID, allowFrom)
 	session.AddHandler(client.handleMessage)
+	session.AddHandler(client.handleReaction)
+	session.AddHandler(client.handleInteraction)
 	go client.runOutbound()
 	go func() {
 		<-ctx.Done()
@@ -74,6 +82,7 @@ type discordClient struct {
 	ctx        context.Context
 	typingMu   sync.Mutex
 	typingStop map[string]chan struct{}
+	streams    map[string]string // StreamID -> ID of the message being edited
 }
 
 // newDiscordClient constructs a discordClient and registers it as the hub's
@@ -91,6 +100,7 @@ func newDiscordClient(ctx context.Context, sender discordSender, hub *chat.Hub,
 		allowed:    allowed,
 		ctx:        ctx,
 		typingStop: make(map[string]chan struct{}),
+		streams:    make(map[string]string),
 	}
 }
 
@@ -137,12 +147,19 @@ func (c *discordClient) handleMessage(_ *discordgo.Session, m *discordgo.Message
 	}
 	content = strings.TrimSpace(content)
 
-	// Append file attachment URLs as inline references.
+	// Images are passed as media for vision models; other attachments are
+	// appended as inline URL references.
+	var mediaURLs []string
 	for _, att := range m.Attachments {
+		if media.IsImage(att.ContentType) && att.Size <= media.MaxImageBytes {
+			mediaURLs = append(mediaURLs, att.URL)
+			continue
+		}
 		content += fmt.Sprintf("\n[attachment: %s]", att.URL)
 	}
+	content = strings.TrimSpace(content)
 
-	if content == "" {
+	if content == "" && len(mediaURLs) == 0 {
 		return
 	}
 
@@ -157,6 +174,7 @@ func (c *discordClient) handleMessage(_ *discordgo.Session, m *discordgo.Message
 		ChatID:    m.ChannelID,
 		Content:   content,
 		Timestamp: time.Now(),
+		Media:     mediaURLs,
 		Metadata: map[string]interface{}{
 			"username":   senderName,
 			"guild_id":   m.GuildID,
@@ -166,6 +184,82 @@ func (c *discordClient) handleMessage(_ *discordgo.Session, m *discordgo.Message
 	}
 }
 
+// handleReaction is the discordgo MessageReactionAdd event handler. A stop
+// reaction (🛑 or ⛔) from an allowed user cancels the reply being generated
+// in that channel.
+func (c *discordClient) handleReaction(_ *discordgo.Session, r *discordgo.MessageReactionAdd) {
+	if r.MessageReaction == nil || r.UserID == c.botID || !isStopReaction(r.Emoji.Name) {
+		return
+	}
+	if len(c.allowed) > 0 {
+		if _, ok := c.allowed[r.UserID]; !ok {
+			return
+		}
+	}
+	log.Printf("discord: stop reaction from %s in %s", r.UserID, r.ChannelID)
+	c.stopTyping(r.ChannelID)
+	c.hub.In <- stopInbound("discord", r.UserID, r.ChannelID, r.Emoji.Name)
+}
+
+// handleInteraction is the discordgo InteractionCreate event handler. A
+// pressed button from an allowed user removes the buttons from its message
+// and delivers the button's data as an inbound message.
+func (c *discordClient) handleInteraction(_ *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Interaction == nil || i.Type != discordgo.InteractionMessageComponent {
+		return
+	}
+	user := i.User
+	if i.Member != nil && i.Member.User != nil {
+		user = i.Member.User
+	}
+	if user == nil {
+		return
+	}
+	if len(c.allowed) > 0 {
+		if _, ok := c.allowed[user.ID]; !ok {
+			log.Printf("discord: dropped button press from unauthorised user %s (%s)", user.Username, user.ID)
+			return
+		}
+	}
+	content := ""
+	if i.Message != nil {
+		content = i.Message.Content
+	}
+	err := c.sender.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseUpdateMessage,
+		Data: &discordgo.InteractionResponseData{Content: content, Components: []discordgo.MessageComponent{}},
+	})
+	if err != nil {
+		log.Printf("discord: interaction response error: %v", err)
+	}
+	data := i.MessageComponentData().CustomID
+	if data == "" {
+		return
+	}
+	c.hub.In <- chat.Inbound{
+		Channel:   "discord",
+		SenderID:  user.ID,
+		ChatID:    i.ChannelID,
+		Content:   data,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"username": senderDisplayName(user),
+			"guild_id": i.GuildID,
+			"button":   true,
+		},
+	}
+}
+
+// discordButtons renders buttons as one action row; the custom ID carries the
+// button's data back in the interaction.
+func discordButtons(buttons []chat.Button) []discordgo.MessageComponent {
+	row := discordgo.ActionsRow{}
+	for _, b := range buttons {
+		row.Components = append(row.Components, discordgo.Button{Label: b.Text, Style: discordgo.SecondaryButton, CustomID: b.Data})
+	}
+	return []discordgo.MessageComponent{row}
+}
+
 // runOutbound reads replies from the hub's discord subscription and sends them.
 func (c *discordClient) runOutbound() {
 	for {
@@ -174,8 +268,18 @@ func (c *discordClient) runOutbound() {
 			return
 		case out := <-c.outCh:
 			c.stopTyping(out.ChatID)
-			for _, chunk := range splitMessage(out.Content, 2000) {
-				if _, err := c.sender.ChannelMessageSend(out.ChatID, chunk); err != nil {
+			chunks := splitMessage(out.Content, 2000)
+			if out.StreamID != "" {
+				chunks = c.updateStream(out, chunks)
+			}
+			for i, chunk := range chunks {
+				var err error
+				if i == len(chunks)-1 && len(out.Buttons) > 0 {
+					_, err = c.sender.ChannelMessageSendComplex(out.ChatID, &discordgo.MessageSend{Content: chunk, Components: discordButtons(out.Buttons)})
+				} else {
+					_, err = c.sender.ChannelMessageSend(out.ChatID, chunk)
+				}
+				if err != nil {
 					log.Printf("discord: send error: %v", err)
 				}
 			}
@@ -183,6 +287,35 @@ func (c *discordClient) runOutbound() {
 	}
 }
 
+// updateStream renders a streamed reply by sending its first update and
+// editing that message afterwards. It returns the chunks that still need to be
+// sent as new messages (overflow of a final update).
+func (c *discordClient) updateStream(out chat.Outbound, chunks []string) []string {
+	msgID, ok := c.streams[out.StreamID]
+	if !out.Partial {
+		delete(c.streams, out.StreamID)
+	}
+	if !ok {
+		if !out.Partial {
+			return chunks
+		}
+		m, err := c.sender.ChannelMessageSend(out.ChatID, chunks[0])
+		if err != nil {
+			log.Printf("discord: send error: %v", err)
+		} else if m != nil {
+			c.streams[out.StreamID] = m.ID
+		}
+		return nil
+	}
+	if _, err := c.sender.ChannelMessageEdit(out.ChatID, msgID, chunks[0]); err != nil {
+		log.Printf("discord: edit error: %v", err)
+	}
+	if out.Partial {
+		return nil
+	}
+	return chunks[1:]
+}
+
 // startTyping begins (or resets) a continuous typing indicator for a channel.
 // It stops automatically after 5 minutes or when stopTyping / stopAllTyping is called.
 func (c *discordClient) startTyping(channelID string) {
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bwmarrin/discordgo"
 	"github.com/local/picobot/internal/chat"
 )
 
@@ -312,3 +313,152 @@ func TestDiscordClient_NewlineSplit(t *testing.T) {
 		t.Error("second chunk should start with 'b'")
 	}
 }
+
+// mockDiscordSender is a no-op discordSender for exercising handleMessage.
+type mockDiscordSender struct{}
+
+func (mockDiscordSender) ChannelMessageSend(channelID, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return &discordgo.Message{ID: "m1", ChannelID: channelID, Content: content}, nil
+}
+
+func (mockDiscordSender) ChannelMessageEdit(channelID, messageID, content string, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return &discordgo.Message{ID: messageID, ChannelID: channelID, Content: content}, nil
+}
+
+func (mockDiscordSender) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	return &discordgo.Message{ID: "m1"}, nil
+}
+
+func (mockDiscordSender) ChannelTyping(string, ...discordgo.RequestOption) error { return nil }
+
+func (mockDiscordSender) InteractionRespond(*discordgo.Interaction, *discordgo.InteractionResponse, ...discordgo.RequestOption) error {
+	return nil
+}
+
+// TestDiscordImageAttachmentsBecomeMedia verifies that image attachments are
+// forwarded as media while other files stay inline references.
+func TestDiscordImageAttachmentsBecomeMedia(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := chat.NewHub(10)
+	c := newDiscordClient(ctx, mockDiscordSender{}, hub, "BOT", nil)
+
+	c.handleMessage(nil, &discordgo.MessageCreate{Message: &discordgo.Message{
+		ChannelID: "C1",
+		Author:    &discordgo.User{ID: "U1", Username: "alice"},
+		Attachments: []*discordgo.MessageAttachment{
+			{URL: "https://cdn.example/receipt.png", ContentType: "image/png", Size: 1000},
+			{URL: "https://cdn.example/notes.pdf", ContentType: "application/pdf", Size: 1000},
+		},
+	}})
+
+	select {
+	case msg := <-hub.In:
+		if len(msg.Media) != 1 || msg.Media[0] != "https://cdn.example/receipt.png" {
+			t.Fatalf("expected image in Media, got %v", msg.Media)
+		}
+		if !strings.Contains(msg.Content, "notes.pdf") || strings.Contains(msg.Content, "receipt.png") {
+			t.Fatalf("unexpected content: %q", msg.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+	c.stopAllTyping()
+}
+
+// TestDiscordStopReaction verifies that a stop reaction from an allowed user is
+// forwarded as /stop and that other reactions are ignored.
+func TestDiscordStopReaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := chat.NewHub(10)
+	c := newDiscordClient(ctx, mockDiscordSender{}, hub, "BOT", []string{"U1"})
+
+	react := func(user, emoji string) {
+		c.handleReaction(nil, &discordgo.MessageReactionAdd{MessageReaction: &discordgo.MessageReaction{
+			UserID: user, ChannelID: "C1", MessageID: "M1", Emoji: discordgo.Emoji{Name: emoji},
+		}})
+	}
+	react("U1", "👍")
+	react("U2", "🛑") // not in allowFrom
+	react("U1", "🛑")
+
+	select {
+	case msg := <-hub.In:
+		if msg.Content != "/stop" || msg.ChatID != "C1" || msg.SenderID != "U1" || msg.Metadata["reaction"] != "🛑" {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+	if len(hub.In) != 0 {
+		t.Fatalf("expected only the allowed stop reaction to be forwarded")
+	}
+}
+
+// buttonDiscordSender records the messages sent with components and the
+// interaction responses.
+type buttonDiscordSender struct {
+	mockDiscordSender
+	complex   chan *discordgo.MessageSend
+	responses chan *discordgo.InteractionResponse
+}
+
+func (s buttonDiscordSender) ChannelMessageSendComplex(channelID string, data *discordgo.MessageSend, _ ...discordgo.RequestOption) (*discordgo.Message, error) {
+	s.complex <- data
+	return &discordgo.Message{ID: "m2", ChannelID: channelID}, nil
+}
+
+func (s buttonDiscordSender) InteractionRespond(_ *discordgo.Interaction, resp *discordgo.InteractionResponse, _ ...discordgo.RequestOption) error {
+	s.responses <- resp
+	return nil
+}
+
+// TestDiscordButtons verifies that outbound buttons become a component row
+// and that a press from an allowed user clears them and is forwarded.
+func TestDiscordButtons(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := chat.NewHub(10)
+	sender := buttonDiscordSender{complex: make(chan *discordgo.MessageSend, 1), responses: make(chan *discordgo.InteractionResponse, 2)}
+	c := newDiscordClient(ctx, sender, hub, "BOT", []string{"U1"})
+	hub.StartRouter(ctx)
+	go c.runOutbound()
+
+	hub.Out <- chat.Outbound{Channel: "discord", ChatID: "C1", Content: "Allow exec?", Buttons: []chat.Button{{Text: "Approve", Data: "/approve ab12"}, {Text: "Deny", Data: "/deny ab12"}}}
+	select {
+	case m := <-sender.complex:
+		row, ok := m.Components[0].(discordgo.ActionsRow)
+		if m.Content != "Allow exec?" || !ok || len(row.Components) != 2 || row.Components[1].(discordgo.Button).CustomID != "/deny ab12" {
+			t.Fatalf("unexpected message %+v", m)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the message with buttons")
+	}
+
+	press := func(user string) {
+		c.handleInteraction(nil, &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+			Type:      discordgo.InteractionMessageComponent,
+			ChannelID: "C1",
+			User:      &discordgo.User{ID: user, Username: user},
+			Message:   &discordgo.Message{Content: "Allow exec?"},
+			Data:      discordgo.MessageComponentInteractionData{CustomID: "/approve ab12"},
+		}})
+	}
+	press("U2") // not in allowFrom
+	press("U1")
+	select {
+	case msg := <-hub.In:
+		if msg.Content != "/approve ab12" || msg.SenderID != "U1" || msg.ChatID != "C1" {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the button press")
+	}
+	if resp := <-sender.responses; resp.Type != discordgo.InteractionResponseUpdateMessage || len(resp.Data.Components) != 0 {
+		t.Fatalf("expected the buttons to be removed, got %+v", resp)
+	}
+	if len(sender.responses) != 0 {
+		t.Fatal("the press from a user not in allowFrom should be ignored")
+	}
+}
@@ -0,0 +1,36 @@
+package channels
+
+import (
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+)
+
+// stopReactions are the emoji that stop the agent's running reply when a user
+// adds them to a message in the chat. Discord reports the Unicode emoji, Slack
+// its short name.
+var stopReactions = map[string]struct{}{
+	"🛑":              {},
+	"⛔":              {},
+	"octagonal_sign": {},
+	"no_entry":       {},
+}
+
+// isStopReaction reports whether emoji is one of stopReactions.
+func isStopReaction(emoji string) bool {
+	_, ok := stopReactions[emoji]
+	return ok
+}
+
+// stopInbound is the /stop command a stop reaction is turned into, so that it
+// goes through the same path as a typed /stop.
+func stopInbound(channel, senderID, chatID, emoji string) chat.Inbound {
+	return chat.Inbound{
+		Channel:   channel,
+		SenderID:  senderID,
+		ChatID:    chatID,
+		Content:   "/stop",
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"reaction": emoji},
+	}
+}
@@ -1,13 +1,18 @@
 package channels
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/media"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
@@ -18,6 +23,13 @@ import (
 // discordSender pattern used by the Discord channel.
 type slackPoster interface {
 	PostMessageContext(ctx context.Context, channelID string, options ...slack.MsgOption) (string, string, error)
+	UpdateMessageContext(ctx context.Context, channelID, timestamp string, options ...slack.MsgOption) (string, string, string, error)
+}
+
+// slackFileGetter downloads private Slack files using the bot token.
+// *slack.Client implements it.
+type slackFileGetter interface {
+	GetFileContext(ctx context.Context, downloadURL string, writer io.Writer) error
 }
 
 // StartSlack starts a Slack bot using Socket Mode.
@@ -73,12 +85,19 @@ func StartSlack(ctx context.Context, hub *chat.Hub, appToken, botToken string, a
 type slackClient struct {
 	socket       *socketmode.Client
 	poster       slackPoster
+	files        slackFileGetter // nil disables image download
 	hub          *chat.Hub
 	outCh        <-chan chat.Outbound
 	botID        string
 	allowedUsers map[string]struct{}
 	allowedChans map[string]struct{}
 	ctx          context.Context
+	streams      map[string]string // StreamID -> ts of the message being edited
+
+	// chats maps the ts of recent messages to their chat ID, so a reaction
+	// (which only names the message) can be traced back to its thread.
+	chatsMu sync.Mutex
+	chats   map[string]string
 }
 
 func newSlackClient(ctx context.Context, socket *socketmode.Client, poster slackPoster, hub *chat.Hub, botID string, allowUsers, allowChannels []string) *slackClient {
@@ -91,9 +110,11 @@ func newSlackClient(ctx context.Context, socket *socketmode.Client, poster slack
 		allowedChans[id] = struct{}{}
 	}
 
+	files, _ := poster.(slackFileGetter)
 	return &slackClient{
 		socket:       socket,
 		poster:       poster,
+		files:        files,
 		hub:          hub,
 		outCh:        hub.Subscribe("slack"),
 		botID:        botID,
@@ -123,7 +144,15 @@ func (c *slackClient) runEvents() {
 				if eventsAPIEvent.Type != slackevents.CallbackEvent {
 					continue
 				}
-				c.handleCallbackEvent(eventsAPIEvent.InnerEvent)
+				c.handleCallbackEvent(eventsAPIEvent)
+			case socketmode.EventTypeInteractive:
+				cb, ok := evt.Data.(slack.InteractionCallback)
+				if !ok {
+					log.Printf("slack: unexpected interaction data: %T", evt.Data)
+					continue
+				}
+				c.socket.Ack(*evt.Request)
+				c.handleInteraction(cb)
 			case socketmode.EventTypeInvalidAuth:
 				log.Println("slack: invalid auth")
 				return
@@ -132,16 +161,35 @@ func (c *slackClient) runEvents() {
 	}
 }
 
-func (c *slackClient) handleCallbackEvent(inner slackevents.EventsAPIInnerEvent) {
-	switch ev := inner.Data.(type) {
+func (c *slackClient) handleCallbackEvent(event slackevents.EventsAPIEvent) {
+	switch ev := event.InnerEvent.Data.(type) {
 	case *slackevents.AppMentionEvent:
-		c.handleMention(ev)
+		c.handleMention(ev, mentionFiles(event))
 	case *slackevents.MessageEvent:
 		c.handleMessage(ev)
+	case *slackevents.ReactionAddedEvent:
+		c.handleReaction(ev)
 	}
 }
 
-func (c *slackClient) handleMention(ev *slackevents.AppMentionEvent) {
+// mentionFiles returns the files shared with a mention. AppMentionEvent has
+// no field for them, so they are read from the raw event.
+func mentionFiles(event slackevents.EventsAPIEvent) []slackevents.File {
+	cb, ok := event.Data.(*slackevents.EventsAPICallbackEvent)
+	if !ok || cb.InnerEvent == nil {
+		return nil
+	}
+	var raw struct {
+		Files []slackevents.File `json:"files"`
+	}
+	if err := json.Unmarshal(*cb.InnerEvent, &raw); err != nil {
+		log.Printf("slack: mention files: %v", err)
+		return nil
+	}
+	return raw.Files
+}
+
+func (c *slackClient) handleMention(ev *slackevents.AppMentionEvent, files []slackevents.File) {
 	if ev.User == "" || ev.User == c.botID || ev.BotID != "" {
 		return
 	}
@@ -151,9 +199,10 @@ func (c *slackClient) handleMention(ev *slackevents.AppMentionEvent) {
 		return
 	}
 
-	content := stripSlackMention(ev.Text, c.botID)
-	content = strings.TrimSpace(content)
-	if content == "" {
+	content := strings.TrimSpace(stripSlackMention(ev.Text, c.botID))
+	images, others := c.downloadImages(files)
+	content = strings.TrimSpace(appendSlackAttachments(content, others))
+	if content == "" && len(images) == 0 {
 		return
 	}
 
@@ -162,6 +211,7 @@ func (c *slackClient) handleMention(ev *slackevents.AppMentionEvent) {
 	teamID := firstNonEmpty(ev.SourceTeam, ev.UserTeam)
 
 	log.Printf("slack: mention from %s in %s: %s", ev.User, ev.Channel, truncate(content, 50))
+	c.rememberChat(ev.TimeStamp, chatID)
 
 	c.hub.In <- chat.Inbound{
 		Channel:   "slack",
@@ -169,6 +219,7 @@ func (c *slackClient) handleMention(ev *slackevents.AppMentionEvent) {
 		ChatID:    chatID,
 		Content:   content,
 		Timestamp: time.Now(),
+		Media:     images,
 		Metadata: map[string]interface{}{
 			"channel_id": ev.Channel,
 			"team_id":    teamID,
@@ -197,9 +248,10 @@ func (c *slackClient) handleMessage(ev *slackevents.MessageEvent) {
 	}
 
 	content := strings.TrimSpace(ev.Text)
-	content = appendSlackAttachments(content, ev.Files)
+	images, others := c.downloadImages(ev.Files)
+	content = appendSlackAttachments(content, others)
 	content = strings.TrimSpace(content)
-	if content == "" {
+	if content == "" && len(images) == 0 {
 		return
 	}
 
@@ -208,6 +260,7 @@ func (c *slackClient) handleMessage(ev *slackevents.MessageEvent) {
 	teamID := firstNonEmpty(ev.SourceTeam, ev.UserTeam)
 
 	log.Printf("slack: message from %s in %s: %s", ev.User, ev.Channel, truncate(content, 50))
+	c.rememberChat(ev.TimeStamp, chatID)
 
 	c.hub.In <- chat.Inbound{
 		Channel:   "slack",
@@ -215,6 +268,7 @@ func (c *slackClient) handleMessage(ev *slackevents.MessageEvent) {
 		ChatID:    chatID,
 		Content:   content,
 		Timestamp: time.Now(),
+		Media:     images,
 		Metadata: map[string]interface{}{
 			"channel_id": ev.Channel,
 			"team_id":    teamID,
@@ -236,12 +290,16 @@ func (c *slackClient) runOutbound() {
 				log.Printf("slack: invalid chat ID %q", out.ChatID)
 				continue
 			}
-			for _, chunk := range splitMessage(out.Content, 4000) {
-				opts := []slack.MsgOption{slack.MsgOptionText(chunk, false)}
-				if threadTS != "" {
-					opts = append(opts, slack.MsgOptionTS(threadTS))
+			chunks := splitMessage(out.Content, 4000)
+			if out.StreamID != "" {
+				chunks = c.updateStream(channelID, threadTS, out, chunks)
+			}
+			for i, chunk := range chunks {
+				var extra []slack.MsgOption
+				if i == len(chunks)-1 && len(out.Buttons) > 0 {
+					extra = append(extra, slackButtons(chunk, out.Buttons))
 				}
-				if _, _, err := c.poster.PostMessageContext(c.ctx, channelID, opts...); err != nil {
+				if _, err := c.post(channelID, threadTS, chunk, extra...); err != nil {
 					log.Printf("slack: send error: %v", err)
 				}
 			}
@@ -249,6 +307,150 @@ func (c *slackClient) runOutbound() {
 	}
 }
 
+// post sends text to a channel (and thread, if set) and returns the message ts.
+func (c *slackClient) post(channelID, threadTS, text string, extra ...slack.MsgOption) (string, error) {
+	opts := append([]slack.MsgOption{slack.MsgOptionText(text, false)}, extra...)
+	if threadTS != "" {
+		opts = append(opts, slack.MsgOptionTS(threadTS))
+	}
+	_, ts, err := c.poster.PostMessageContext(c.ctx, channelID, opts...)
+	if err == nil {
+		c.rememberChat(ts, formatSlackChatID(channelID, threadTS))
+	}
+	return ts, err
+}
+
+// maxRememberedChats bounds slackClient.chats; older entries are dropped.
+const maxRememberedChats = 1000
+
+// rememberChat records the chat a message belongs to.
+func (c *slackClient) rememberChat(ts, chatID string) {
+	if ts == "" {
+		return
+	}
+	c.chatsMu.Lock()
+	defer c.chatsMu.Unlock()
+	if c.chats == nil || len(c.chats) >= maxRememberedChats {
+		c.chats = make(map[string]string)
+	}
+	c.chats[ts] = chatID
+}
+
+// handleReaction turns a stop reaction (:octagonal_sign: or :no_entry:) on a
+// message into /stop for that message's chat. Requires the reaction_added
+// event subscription and the reactions:read scope.
+func (c *slackClient) handleReaction(ev *slackevents.ReactionAddedEvent) {
+	if ev.User == "" || ev.User == c.botID || !isStopReaction(ev.Reaction) {
+		return
+	}
+	channelID := ev.Item.Channel
+	if channelID == "" {
+		return
+	}
+	isDM := strings.HasPrefix(channelID, "D")
+	if !c.isAllowed(ev.User, channelID, isDM) {
+		return
+	}
+	c.chatsMu.Lock()
+	chatID, ok := c.chats[ev.Item.Timestamp]
+	c.chatsMu.Unlock()
+	if !ok {
+		chatID = channelID
+	}
+	log.Printf("slack: stop reaction from %s in %s", ev.User, chatID)
+	c.hub.In <- stopInbound("slack", ev.User, chatID, ev.Reaction)
+}
+
+// slackButtons renders text with buttons below it as Block Kit blocks. The
+// button values carry their data back in the block_actions interaction.
+func slackButtons(text string, buttons []chat.Button) slack.MsgOption {
+	elems := make([]slack.BlockElement, len(buttons))
+	for i, b := range buttons {
+		elems[i] = slack.NewButtonBlockElement(fmt.Sprintf("picobot_button_%d", i), b.Data, slack.NewTextBlockObject(slack.PlainTextType, b.Text, true, false))
+	}
+	return slack.MsgOptionBlocks(
+		slack.NewSectionBlock(slack.NewTextBlockObject(slack.MarkdownType, text, false, false), nil, nil),
+		slack.NewActionBlock("picobot_buttons", elems...),
+	)
+}
+
+// handleInteraction turns a pressed button into an inbound message carrying
+// the button's data, and replaces the message with its plain text so the
+// buttons cannot be pressed again. Requires Interactivity to be enabled for
+// the app (Socket Mode delivers the events).
+func (c *slackClient) handleInteraction(cb slack.InteractionCallback) {
+	if cb.Type != slack.InteractionTypeBlockActions || len(cb.ActionCallback.BlockActions) == 0 {
+		return
+	}
+	channelID := cb.Channel.ID
+	isDM := strings.HasPrefix(channelID, "D")
+	if cb.User.ID == "" || !c.isAllowed(cb.User.ID, channelID, isDM) {
+		c.logUnauthorized(cb.User.ID, channelID, isDM)
+		return
+	}
+	ts := cb.Message.Timestamp
+	c.chatsMu.Lock()
+	chatID, ok := c.chats[ts]
+	c.chatsMu.Unlock()
+	if !ok {
+		chatID = formatSlackChatID(channelID, cb.Message.ThreadTimestamp)
+	}
+	if ts != "" {
+		if _, _, _, err := c.poster.UpdateMessageContext(c.ctx, channelID, ts, slack.MsgOptionText(cb.Message.Text, false), slack.MsgOptionBlocks([]slack.Block{}...)); err != nil {
+			log.Printf("slack: update error: %v", err)
+		}
+	}
+	data := cb.ActionCallback.BlockActions[0].Value
+	if data == "" {
+		return
+	}
+	log.Printf("slack: button press from %s in %s", cb.User.ID, chatID)
+	c.hub.In <- chat.Inbound{
+		Channel:   "slack",
+		SenderID:  cb.User.ID,
+		ChatID:    chatID,
+		Content:   data,
+		Timestamp: time.Now(),
+		Metadata: map[string]interface{}{
+			"channel_id": channelID,
+			"team_id":    cb.Team.ID,
+			"button":     true,
+		},
+	}
+}
+
+// updateStream renders a streamed reply by posting its first update and
+// editing that message with chat.update afterwards. It returns the chunks that
+// still need to be posted as new messages (overflow of a final update).
+func (c *slackClient) updateStream(channelID, threadTS string, out chat.Outbound, chunks []string) []string {
+	if c.streams == nil {
+		c.streams = make(map[string]string)
+	}
+	ts, ok := c.streams[out.StreamID]
+	if !out.Partial {
+		delete(c.streams, out.StreamID)
+	}
+	if !ok {
+		if !out.Partial {
+			return chunks
+		}
+		newTS, err := c.post(channelID, threadTS, chunks[0])
+		if err != nil {
+			log.Printf("slack: send error: %v", err)
+		} else {
+			c.streams[out.StreamID] = newTS
+		}
+		return nil
+	}
+	if _, _, _, err := c.poster.UpdateMessageContext(c.ctx, channelID, ts, slack.MsgOptionText(chunks[0], false)); err != nil {
+		log.Printf("slack: update error: %v", err)
+	}
+	if out.Partial {
+		return nil
+	}
+	return chunks[1:]
+}
+
 func (c *slackClient) isAllowed(userID, channelID string, isDM bool) bool {
 	if len(c.allowedUsers) > 0 {
 		if _, ok := c.allowedUsers[userID]; !ok {
@@ -287,6 +489,30 @@ func stripSlackMention(text, botID string) string {
 	return strings.ReplaceAll(text, "<@"+botID+">", "")
 }
 
+// downloadImages fetches image files as data URIs, since Slack file URLs
+// require the bot token. Files that are not images, are too large, or fail to
+// download are returned in others to be referenced by URL instead.
+func (c *slackClient) downloadImages(files []slackevents.File) (images []string, others []slackevents.File) {
+	for _, file := range files {
+		url := file.URLPrivateDownload
+		if url == "" {
+			url = file.URLPrivate
+		}
+		if c.files == nil || url == "" || !media.IsImage(file.Mimetype) || file.Size > media.MaxImageBytes {
+			others = append(others, file)
+			continue
+		}
+		var buf bytes.Buffer
+		if err := c.files.GetFileContext(c.ctx, url, &buf); err != nil {
+			log.Printf("slack: file download error: %v", err)
+			others = append(others, file)
+			continue
+		}
+		images = append(images, media.DataURI(file.Mimetype, buf.Bytes()))
+	}
+	return images, others
+}
+
 func appendSlackAttachments(content string, files []slackevents.File) string {
 	for _, file := range files {
 		url := file.URLPrivate
@@ -2,6 +2,7 @@ package channels
 
 import (
 	"context"
+	"io"
 	"strings"
 	"sync"
 	"testing"
@@ -14,15 +15,23 @@ import (
 
 // mockSlackPoster captures outbound Slack posts for testing without a live connection.
 type mockSlackPoster struct {
-	mu   sync.Mutex
-	sent []string // channel IDs received by PostMessageContext
+	mu      sync.Mutex
+	sent    []string // channel IDs received by PostMessageContext
+	updated []string // timestamps received by UpdateMessageContext
 }
 
 func (m *mockSlackPoster) PostMessageContext(_ context.Context, channelID string, _ ...slack.MsgOption) (string, string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.sent = append(m.sent, channelID)
-	return "", "", nil
+	return channelID, "1700000000.000100", nil
+}
+
+func (m *mockSlackPoster) UpdateMessageContext(_ context.Context, channelID, timestamp string, _ ...slack.MsgOption) (string, string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updated = append(m.updated, timestamp)
+	return channelID, timestamp, "", nil
 }
 
 func TestStartSlack_EmptyTokens(t *testing.T) {
@@ -120,6 +129,40 @@ func TestSlackClient_OutboundThread(t *testing.T) {
 	}
 }
 
+// TestSlackClient_OutboundStream verifies that streamed updates post once and
+// then edit the same message via chat.update.
+func TestSlackClient_OutboundStream(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	poster := &mockSlackPoster{}
+	hub := chat.NewHub(10)
+	c := &slackClient{
+		poster: poster,
+		hub:    hub,
+		outCh:  hub.Subscribe("slack"),
+		botID:  "UBOT",
+		ctx:    ctx,
+	}
+	go c.runOutbound()
+	hub.StartRouter(ctx)
+
+	hub.Out <- chat.Outbound{Channel: "slack", ChatID: "C123", Content: "Hel", StreamID: "s1", Partial: true}
+	hub.Out <- chat.Outbound{Channel: "slack", ChatID: "C123", Content: "Hello", StreamID: "s1", Partial: true}
+	hub.Out <- chat.Outbound{Channel: "slack", ChatID: "C123", Content: "Hello world", StreamID: "s1"}
+
+	time.Sleep(50 * time.Millisecond)
+
+	poster.mu.Lock()
+	defer poster.mu.Unlock()
+	if len(poster.sent) != 1 {
+		t.Fatalf("expected 1 post, got %d", len(poster.sent))
+	}
+	if len(poster.updated) != 2 {
+		t.Fatalf("expected 2 updates, got %d", len(poster.updated))
+	}
+}
+
 func TestSlackChatIDHelpers(t *testing.T) {
 	channelID := "C123456"
 	threadTS := "1699999999.123456"
@@ -184,6 +227,58 @@ func TestSlackAttachmentAppend(t *testing.T) {
 	}
 }
 
+// mockSlackFiles serves fixed bytes for any file download.
+type mockSlackFiles struct{}
+
+func (mockSlackFiles) GetFileContext(_ context.Context, _ string, w io.Writer) error {
+	_, err := w.Write([]byte("imgbytes"))
+	return err
+}
+
+func TestSlackDownloadImages(t *testing.T) {
+	c := &slackClient{files: mockSlackFiles{}, ctx: context.Background()}
+	images, others := c.downloadImages([]slackevents.File{
+		{Mimetype: "image/png", Size: 8, URLPrivateDownload: "https://files.example.com/receipt.png"},
+		{Mimetype: "application/pdf", Size: 8, URLPrivate: "https://files.example.com/doc.pdf"},
+	})
+	if len(images) != 1 || !strings.HasPrefix(images[0], "data:image/png;base64,") {
+		t.Fatalf("expected one PNG data URI, got %v", images)
+	}
+	if len(others) != 1 || others[0].Mimetype != "application/pdf" {
+		t.Fatalf("expected the PDF to stay an attachment reference, got %+v", others)
+	}
+}
+
+func TestSlackMentionWithImage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	hub := chat.NewHub(10)
+	c := newSlackClient(ctx, nil, &mockSlackPoster{}, hub, "UBOT", nil, nil)
+	c.files = mockSlackFiles{}
+
+	event, err := slackevents.ParseEvent([]byte(`{
+	  "type": "event_callback",
+	  "event": {
+	    "type": "app_mention", "user": "U1", "channel": "C1", "ts": "1700000000.000200",
+	    "text": "<@UBOT> what does this say?",
+	    "files": [{"mimetype": "image/png", "size": 8, "url_private_download": "https://files.example.com/sign.png"}]
+	  }
+	}`), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		t.Fatalf("ParseEvent: %v", err)
+	}
+	c.handleCallbackEvent(event)
+
+	select {
+	case msg := <-hub.In:
+		if msg.Content != "what does this say?" || len(msg.Media) != 1 || !strings.HasPrefix(msg.Media[0], "data:image/png;base64,") {
+			t.Fatalf("expected the mention with its image, got %+v", msg)
+		}
+	default:
+		t.Fatal("expected an inbound message")
+	}
+}
+
 func TestFirstNonEmpty(t *testing.T) {
 	tests := []struct {
 		values []string
@@ -201,3 +296,68 @@ func TestFirstNonEmpty(t *testing.T) {
 		}
 	}
 }
+
+// TestSlackStopReaction verifies that a stop reaction on a bot reply in a
+// thread stops the turn of that thread.
+func TestSlackStopReaction(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	poster := &mockSlackPoster{}
+	hub := chat.NewHub(10)
+	c := newSlackClient(ctx, nil, poster, hub, "UBOT", nil, nil)
+	if _, err := c.post("C123", "1234567890.000001", "working on it"); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+
+	c.handleReaction(&slackevents.ReactionAddedEvent{User: "U1", Reaction: "thumbsup", Item: slackevents.Item{Channel: "C123", Timestamp: "1700000000.000100"}})
+	c.handleReaction(&slackevents.ReactionAddedEvent{User: "U1", Reaction: "octagonal_sign", Item: slackevents.Item{Channel: "C123", Timestamp: "1700000000.000100"}})
+
+	select {
+	case msg := <-hub.In:
+		if msg.Content != "/stop" || msg.ChatID != "C123::1234567890.000001" {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+	if len(hub.In) != 0 {
+		t.Fatalf("expected only the stop reaction to be forwarded")
+	}
+}
+
+func TestSlackButtons(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	poster := &mockSlackPoster{}
+	hub := chat.NewHub(10)
+	c := newSlackClient(ctx, nil, poster, hub, "UBOT", []string{"U1"}, nil)
+	if _, err := c.post("C123", "1234567890.000001", "Allow exec?", slackButtons("Allow exec?", []chat.Button{{Text: "Approve", Data: "/approve ab12"}})); err != nil {
+		t.Fatalf("post: %v", err)
+	}
+
+	press := func(user string) {
+		cb := slack.InteractionCallback{Type: slack.InteractionTypeBlockActions, User: slack.User{ID: user}, Channel: slack.Channel{GroupConversation: slack.GroupConversation{Conversation: slack.Conversation{ID: "C123"}}}}
+		cb.Message.Timestamp = "1700000000.000100"
+		cb.Message.Text = "Allow exec?"
+		cb.ActionCallback.BlockActions = []*slack.BlockAction{{ActionID: "picobot_button_0", Value: "/approve ab12"}}
+		c.handleInteraction(cb)
+	}
+	press("U2") // not in allowUsers
+	press("U1")
+
+	select {
+	case msg := <-hub.In:
+		if msg.Content != "/approve ab12" || msg.SenderID != "U1" || msg.ChatID != "C123::1234567890.000001" {
+			t.Fatalf("unexpected inbound: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the button press")
+	}
+	poster.mu.Lock()
+	defer poster.mu.Unlock()
+	if len(poster.updated) != 1 || poster.updated[0] != "1700000000.000100" {
+		t.Fatalf("expected the buttons to be removed once, got updates %v", poster.updated)
+	}
+}
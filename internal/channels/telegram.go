@@ -9,26 +9,38 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/media"
+	"github.com/local/picobot/internal/transcribe"
 )
 
+// telegramAudio is the voice or audio attachment of a Telegram message.
+type telegramAudio struct {
+	FileID   string `json:"file_id"`
+	MimeType string `json:"mime_type"`
+	FileSize int64  `json:"file_size"`
+	Duration int    `json:"duration"`
+}
+
 // StartTelegram is a convenience wrapper that uses the real polling implementation
 // with the standard Telegram base URL.
 // allowFrom is a list of Telegram user IDs permitted to interact with the bot.
 // If empty, ALL users are allowed (open mode).
-func StartTelegram(ctx context.Context, hub *chat.Hub, token string, allowFrom []string) error {
+// tr transcribes voice and audio messages; nil disables transcription.
+func StartTelegram(ctx context.Context, hub *chat.Hub, token string, allowFrom []string, tr transcribe.Transcriber) error {
 	if token == "" {
 		return fmt.Errorf("telegram token not provided")
 	}
 	base := "https://api.telegram.org/bot" + token
-	return StartTelegramWithBase(ctx, hub, token, base, allowFrom)
+	return StartTelegramWithBase(ctx, hub, token, base, allowFrom, tr)
 }
 
 // StartTelegramWithBase starts long-polling against the given base URL (e.g., https://api.telegram.org/bot<TOKEN> or a test server URL).
 // allowFrom restricts which Telegram user IDs may send messages. Empty means allow all.
-func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base string, allowFrom []string) error {
+func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base string, allowFrom []string, tr transcribe.Transcriber) error {
 	if base == "" {
 		return fmt.Errorf("base URL is required")
 	}
@@ -41,6 +53,16 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 
 	client := &http.Client{Timeout: 45 * time.Second}
 
+	// Telegram clients send /start when a user opens the bot for the first time.
+	hub.Commands.Register(chat.Command{
+		Name:        "start",
+		Description: "Say hello (sent by Telegram when a chat is opened)",
+		Immediate:   true,
+		Handler: func(ctx context.Context, msg chat.Inbound, args string) string {
+			return "Hi! I'm Picobot. Send me a message to get started, or /help for commands."
+		},
+	})
+
 	// inbound polling goroutine
 	go func() {
 		offset := int64(0)
@@ -76,8 +98,22 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 						Chat struct {
 							ID int64 `json:"id"`
 						} `json:"chat"`
-						Text string `json:"text"`
+						Text    string `json:"text"`
+						Caption string `json:"caption"`
+						// Photo lists the available sizes, smallest first.
+						Photo []struct {
+							FileID   string `json:"file_id"`
+							FileSize int64  `json:"file_size"`
+						} `json:"photo"`
+						Document *struct {
+							FileID   string `json:"file_id"`
+							MimeType string `json:"mime_type"`
+							FileSize int64  `json:"file_size"`
+						} `json:"document"`
+						Voice *telegramAudio `json:"voice"`
+						Audio *telegramAudio `json:"audio"`
 					} `json:"message"`
+					CallbackQuery *telegramCallback `json:"callback_query"`
 				} `json:"result"`
 			}
 			if err := json.Unmarshal(body, &gu); err != nil {
@@ -88,6 +124,27 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 				if upd.UpdateID >= offset {
 					offset = upd.UpdateID + 1
 				}
+				if cq := upd.CallbackQuery; cq != nil {
+					// a pressed inline button: answer it and pass its data on
+					fromID := strconv.FormatInt(cq.From.ID, 10)
+					if _, ok := allowed[fromID]; len(allowed) > 0 && !ok {
+						log.Printf("telegram: dropping button press from unauthorized user %s", fromID)
+						continue
+					}
+					answerTelegramCallback(client, base, cq)
+					if cq.Message == nil || cq.Data == "" {
+						continue
+					}
+					hub.In <- chat.Inbound{
+						Channel:   "telegram",
+						SenderID:  fromID,
+						ChatID:    strconv.FormatInt(cq.Message.Chat.ID, 10),
+						Content:   cq.Data,
+						Timestamp: time.Now(),
+						Metadata:  map[string]interface{}{"button": true},
+					}
+					continue
+				}
 				if upd.Message == nil {
 					continue
 				}
@@ -104,13 +161,67 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 					}
 				}
 				chatID := strconv.FormatInt(m.Chat.ID, 10)
-				hub.In <- chat.Inbound{
+				content := m.Text
+				if content == "" {
+					content = m.Caption
+				}
+				var fileIDs []string
+				if n := len(m.Photo); n > 0 && m.Photo[n-1].FileSize <= media.MaxImageBytes {
+					fileIDs = append(fileIDs, m.Photo[n-1].FileID)
+				}
+				if d := m.Document; d != nil && media.IsImage(d.MimeType) && d.FileSize <= media.MaxImageBytes {
+					fileIDs = append(fileIDs, d.FileID)
+				}
+				var mediaURLs []string
+				for _, id := range fileIDs {
+					u, err := telegramFileURL(client, base, token, id)
+					if err != nil {
+						log.Printf("telegram: getFile error: %v", err)
+						continue
+					}
+					mediaURLs = append(mediaURLs, u)
+				}
+				msg := chat.Inbound{
 					Channel:   "telegram",
 					SenderID:  fromID,
 					ChatID:    chatID,
-					Content:   m.Text,
+					Content:   content,
 					Timestamp: time.Now(),
+					Media:     mediaURLs,
+				}
+				audio := m.Voice
+				if audio == nil {
+					audio = m.Audio
+				}
+				if audio != nil {
+					transcribeInBackground(ctx, tr, "telegram", func() ([]byte, string, error) {
+						if audio.FileSize > transcribe.MaxAudioBytes {
+							return nil, "", fmt.Errorf("audio too large (%d bytes)", audio.FileSize)
+						}
+						u, err := telegramFileURL(client, base, token, audio.FileID)
+						if err != nil {
+							return nil, "", err
+						}
+						data, _, err := media.Fetch(ctx, client, u, transcribe.MaxAudioBytes)
+						return data, audio.MimeType, err
+					}, func(transcript string, meta map[string]interface{}) {
+						if msg.Content != "" {
+							msg.Content += "\n\n" + transcript
+						} else {
+							msg.Content = transcript
+						}
+						msg.Metadata = meta
+						select {
+						case hub.In <- msg:
+						case <-ctx.Done():
+						}
+					})
+					continue
 				}
+				if content == "" && len(mediaURLs) == 0 {
+					continue
+				}
+				hub.In <- msg
 			}
 		}
 	}()
@@ -122,26 +233,163 @@ func StartTelegramWithBase(ctx context.Context, hub *chat.Hub, token, base strin
 	// outbound sender goroutine
 	go func() {
 		client := &http.Client{Timeout: 10 * time.Second}
+		// streams maps an Outbound.StreamID to the message_id being edited.
+		streams := make(map[string]int64)
+		send := func(chatID, text string, buttons []chat.Button) int64 {
+			v := url.Values{}
+			v.Set("chat_id", chatID)
+			v.Set("text", text)
+			if len(buttons) > 0 {
+				v.Set("reply_markup", telegramKeyboard(buttons))
+			}
+			resp, err := client.PostForm(base+"/sendMessage", v)
+			if err != nil {
+				log.Printf("telegram sendMessage error: %v", err)
+				return 0
+			}
+			defer resp.Body.Close()
+			var sr struct {
+				Result struct {
+					MessageID int64 `json:"message_id"`
+				} `json:"result"`
+			}
+			body, _ := io.ReadAll(resp.Body)
+			json.Unmarshal(body, &sr)
+			return sr.Result.MessageID
+		}
+		edit := func(chatID string, messageID int64, text string) {
+			v := url.Values{}
+			v.Set("chat_id", chatID)
+			v.Set("message_id", strconv.FormatInt(messageID, 10))
+			v.Set("text", text)
+			resp, err := client.PostForm(base+"/editMessageText", v)
+			if err != nil {
+				log.Printf("telegram editMessageText error: %v", err)
+				return
+			}
+			io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
 		for {
 			select {
 			case <-ctx.Done():
 				log.Println("telegram: stopping outbound sender")
 				return
 			case out := <-outCh:
-				u := base + "/sendMessage"
-				v := url.Values{}
-				v.Set("chat_id", out.ChatID)
-				v.Set("text", out.Content)
-				resp, err := client.PostForm(u, v)
-				if err != nil {
-					log.Printf("telegram sendMessage error: %v", err)
-					continue
+				chunks := splitMessage(out.Content, 4096)
+				if out.StreamID != "" {
+					msgID, ok := streams[out.StreamID]
+					if !out.Partial {
+						delete(streams, out.StreamID)
+					}
+					switch {
+					case !ok && out.Partial:
+						// first update of a stream: send it and remember the message to edit
+						if id := send(out.ChatID, chunks[0], nil); id != 0 {
+							streams[out.StreamID] = id
+						}
+						continue
+					case ok:
+						edit(out.ChatID, msgID, chunks[0])
+						if out.Partial {
+							continue
+						}
+						chunks = chunks[1:]
+					}
+				}
+				for i, chunk := range chunks {
+					var buttons []chat.Button
+					if i == len(chunks)-1 {
+						buttons = out.Buttons // under the last part of the message
+					}
+					send(out.ChatID, chunk, buttons)
 				}
-				io.ReadAll(resp.Body)
-				resp.Body.Close()
 			}
 		}
 	}()
 
 	return nil
 }
+
+// telegramFileURL resolves a file_id to a download URL via getFile. The URL
+// embeds the bot token, so it must only be fetched locally, never forwarded.
+func telegramFileURL(client *http.Client, base, token, fileID string) (string, error) {
+	resp, err := client.PostForm(base+"/getFile", url.Values{"file_id": {fileID}})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var gf struct {
+		Ok     bool `json:"ok"`
+		Result struct {
+			FilePath string `json:"file_path"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&gf); err != nil {
+		return "", err
+	}
+	if !gf.Ok || gf.Result.FilePath == "" {
+		return "", fmt.Errorf("file %s not available", fileID)
+	}
+	// https://api.telegram.org/bot<TOKEN> -> https://api.telegram.org/file/bot<TOKEN>/<path>
+	if prefix, ok := strings.CutSuffix(base, "/bot"+token); ok && token != "" {
+		return prefix + "/file/bot" + token + "/" + gf.Result.FilePath, nil
+	}
+	return base + "/file/" + gf.Result.FilePath, nil
+}
+
+// telegramCallback is a callback_query update, sent when a user presses an
+// inline keyboard button.
+type telegramCallback struct {
+	ID   string `json:"id"`
+	From struct {
+		ID int64 `json:"id"`
+	} `json:"from"`
+	Message *struct {
+		MessageID int64 `json:"message_id"`
+		Chat      struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+	} `json:"message"`
+	Data string `json:"data"`
+}
+
+// telegramKeyboard renders buttons as a one-row inline keyboard.
+func telegramKeyboard(buttons []chat.Button) string {
+	type button struct {
+		Text         string `json:"text"`
+		CallbackData string `json:"callback_data"`
+	}
+	row := make([]button, len(buttons))
+	for i, b := range buttons {
+		row[i] = button{Text: b.Text, CallbackData: b.Data}
+	}
+	markup, _ := json.Marshal(map[string]interface{}{"inline_keyboard": [][]button{row}})
+	return string(markup)
+}
+
+// answerTelegramCallback stops the client's loading indicator on a pressed
+// button and removes the keyboard, so each choice can be made only once.
+func answerTelegramCallback(client *http.Client, base string, cq *telegramCallback) {
+	resp, err := client.PostForm(base+"/answerCallbackQuery", url.Values{"callback_query_id": {cq.ID}})
+	if err != nil {
+		log.Printf("telegram answerCallbackQuery error: %v", err)
+	} else {
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	if cq.Message == nil {
+		return
+	}
+	v := url.Values{}
+	v.Set("chat_id", strconv.FormatInt(cq.Message.Chat.ID, 10))
+	v.Set("message_id", strconv.FormatInt(cq.Message.MessageID, 10))
+	v.Set("reply_markup", `{"inline_keyboard":[]}`)
+	resp, err = client.PostForm(base+"/editMessageReplyMarkup", v)
+	if err != nil {
+		log.Printf("telegram editMessageReplyMarkup error: %v", err)
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
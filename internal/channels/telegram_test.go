@@ -50,7 +50,7 @@ func TestStartTelegramWithBase(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	if err := StartTelegramWithBase(ctx, b, token, base, nil); err != nil {
+	if err := StartTelegramWithBase(ctx, b, token, base, nil, nil); err != nil {
 		t.Fatalf("StartTelegramWithBase failed: %v", err)
 	}
 	// Start the hub router so outbound messages sent to b.Out are dispatched
@@ -88,3 +88,231 @@ func TestStartTelegramWithBase(t *testing.T) {
 	// give a small grace period
 	time.Sleep(50 * time.Millisecond)
 }
+
+func TestTelegramPhotoPopulatesMedia(t *testing.T) {
+	token := "testtoken"
+	first := true
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			if first {
+				first = false
+				w.Write([]byte(`{"ok":true,"result":[{"update_id":1,"message":{"message_id":1,"from":{"id":123},"chat":{"id":456},"caption":"what does this receipt say?","photo":[{"file_id":"small","file_size":100},{"file_id":"large","file_size":2000}]}}]}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/getFile"):
+			r.ParseForm()
+			w.Write([]byte(`{"ok":true,"result":{"file_id":"` + r.PostForm.Get("file_id") + `","file_path":"photos/file_1.jpg"}}`))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer h.Close()
+
+	b := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := StartTelegramWithBase(ctx, b, token, h.URL+"/bot"+token, nil, nil); err != nil {
+		t.Fatalf("StartTelegramWithBase failed: %v", err)
+	}
+
+	select {
+	case msg := <-b.In:
+		if msg.Content != "what does this receipt say?" {
+			t.Fatalf("expected caption as content, got %q", msg.Content)
+		}
+		want := h.URL + "/file/bot" + token + "/photos/file_1.jpg"
+		if len(msg.Media) != 1 || msg.Media[0] != want {
+			t.Fatalf("expected media %q, got %v", want, msg.Media)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+}
+
+// fakeTranscriber records the audio it was given and returns a fixed text.
+type fakeTranscriber struct {
+	text     string
+	audio    string
+	mimeType string
+}
+
+func (f *fakeTranscriber) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	f.audio, f.mimeType = string(audio), mimeType
+	return f.text, nil
+}
+
+func TestTelegramVoiceIsTranscribed(t *testing.T) {
+	token := "testtoken"
+	first := true
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			if first {
+				first = false
+				w.Write([]byte(`{"ok":true,"result":[{"update_id":1,"message":{"message_id":1,"from":{"id":123},"chat":{"id":456},"voice":{"file_id":"v1","mime_type":"audio/ogg","file_size":8,"duration":2}}}]}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/getFile"):
+			w.Write([]byte(`{"ok":true,"result":{"file_id":"v1","file_path":"voice/file_2.oga"}}`))
+		case strings.HasSuffix(r.URL.Path, "/voice/file_2.oga"):
+			w.Write([]byte("oggbytes"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer h.Close()
+
+	tr := &fakeTranscriber{text: "remind me to call mum"}
+	b := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := StartTelegramWithBase(ctx, b, token, h.URL+"/bot"+token, nil, tr); err != nil {
+		t.Fatalf("StartTelegramWithBase failed: %v", err)
+	}
+
+	select {
+	case msg := <-b.In:
+		if msg.Content != "remind me to call mum" {
+			t.Fatalf("expected transcript as content, got %q", msg.Content)
+		}
+		if msg.Metadata["transcribed"] != true {
+			t.Fatalf("expected transcript to be marked in metadata, got %v", msg.Metadata)
+		}
+		if tr.audio != "oggbytes" || tr.mimeType != "audio/ogg" {
+			t.Fatalf("unexpected audio passed to transcriber: %q (%s)", tr.audio, tr.mimeType)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+}
+
+// blockingTranscriber holds every transcription until release is closed.
+type blockingTranscriber struct{ release chan struct{} }
+
+func (b blockingTranscriber) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	select {
+	case <-b.release:
+		return "slow transcript", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestTelegramSlowVoiceDoesNotBlockText(t *testing.T) {
+	token := "testtoken"
+	first := true
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			if first {
+				first = false
+				w.Write([]byte(`{"ok":true,"result":[
+				  {"update_id":1,"message":{"message_id":1,"from":{"id":123},"chat":{"id":456},"voice":{"file_id":"v1","mime_type":"audio/ogg","file_size":8}}},
+				  {"update_id":2,"message":{"message_id":2,"from":{"id":789},"chat":{"id":789},"text":"hello"}}]}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/getFile"):
+			w.Write([]byte(`{"ok":true,"result":{"file_id":"v1","file_path":"voice/file_2.oga"}}`))
+		case strings.HasSuffix(r.URL.Path, "/voice/file_2.oga"):
+			w.Write([]byte("oggbytes"))
+		default:
+			w.WriteHeader(404)
+		}
+	}))
+	defer h.Close()
+
+	tr := blockingTranscriber{release: make(chan struct{})}
+	b := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := StartTelegramWithBase(ctx, b, token, h.URL+"/bot"+token, nil, tr); err != nil {
+		t.Fatalf("StartTelegramWithBase failed: %v", err)
+	}
+
+	select {
+	case msg := <-b.In:
+		if msg.Content != "hello" {
+			t.Fatalf("expected the text message while the voice note is transcribed, got %q", msg.Content)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("the text message was held up by the voice note")
+	}
+	close(tr.release)
+	select {
+	case msg := <-b.In:
+		if msg.Content != "slow transcript" || msg.ChatID != "456" {
+			t.Fatalf("expected the voice note afterwards, got %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the voice note")
+	}
+}
+
+func TestTelegramButtons(t *testing.T) {
+	calls := make(chan string, 8)
+	sent := make(chan url.Values, 4)
+	first := true
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/getUpdates"):
+			if first {
+				first = false
+				w.Write([]byte(`{"ok":true,"result":[{"update_id":1,"callback_query":{"id":"cb1","from":{"id":123},"message":{"message_id":9,"chat":{"id":456}},"data":"/approve ab12"}}]}`))
+				return
+			}
+			w.Write([]byte(`{"ok":true,"result":[]}`))
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"):
+			sent <- r.PostForm
+			w.Write([]byte(`{"ok":true,"result":{}}`))
+		default:
+			calls <- r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:] + " " + r.PostForm.Encode()
+			w.Write([]byte(`{"ok":true,"result":true}`))
+		}
+	}))
+	defer h.Close()
+
+	b := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := StartTelegramWithBase(ctx, b, "tok", h.URL+"/bottok", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	b.StartRouter(ctx)
+
+	select {
+	case msg := <-b.In:
+		if msg.Content != "/approve ab12" || msg.SenderID != "123" || msg.ChatID != "456" {
+			t.Fatalf("unexpected inbound for a button press: %+v", msg)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for the button press")
+	}
+	for _, want := range []string{"answerCallbackQuery callback_query_id=cb1", "editMessageReplyMarkup chat_id=456&message_id=9"} {
+		select {
+		case got := <-calls:
+			if !strings.HasPrefix(got, want) {
+				t.Fatalf("expected %q, got %q", want, got)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("timeout waiting for %s", want)
+		}
+	}
+
+	b.Out <- chat.Outbound{Channel: "telegram", ChatID: "456", Content: "Allow exec?", Buttons: []chat.Button{{Text: "Approve", Data: "/approve cd34"}, {Text: "Deny", Data: "/deny cd34"}}}
+	select {
+	case v := <-sent:
+		want := `{"inline_keyboard":[[{"text":"Approve","callback_data":"/approve cd34"},{"text":"Deny","callback_data":"/deny cd34"}]]}`
+		if v.Get("reply_markup") != want {
+			t.Fatalf("unexpected reply_markup %s", v.Get("reply_markup"))
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timeout waiting for sendMessage")
+	}
+}
@@ -0,0 +1,255 @@
+package channels
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/local/picobot/internal/chat"
+)
+
+const (
+	terminalPrompt   = "you> "
+	terminalContinue = "...> "
+	// terminalFence on a line of its own starts and ends a multi-line message.
+	terminalFence = `"""`
+	// maxTerminalHistory caps the lines kept in the history file.
+	maxTerminalHistory = 500
+)
+
+// Terminal chats with the agent on the command line as the "cli" channel.
+// Messages come from sender "user" in one chat, whose session is kept like
+// that of any other chat.
+//
+// A line ending with a backslash continues on the next one, and lines between
+// two """ lines are sent as one message. /exit, Ctrl-D or Ctrl-C quits.
+//
+// When the input is a terminal, lines can be edited, earlier ones recalled
+// with the arrow keys, and the agent's replies and tool activity are printed
+// above the prompt as they arrive, so /stop can be sent while it works.
+// Otherwise (piped input) each message waits for its reply before the next
+// line is read.
+type Terminal struct {
+	hub         *chat.Hub
+	outCh       <-chan chat.Outbound
+	chatID      string
+	in          io.Reader
+	historyPath string
+
+	mu  sync.Mutex // guards out
+	out io.Writer
+
+	replied chan struct{} // signalled when a reply (not a tool notification) is printed
+}
+
+// NewTerminal creates a terminal chat in chatID and registers it as the hub's
+// "cli" outbound subscriber. historyPath is the file keeping the lines typed
+// across runs; empty keeps none.
+func NewTerminal(hub *chat.Hub, chatID string, in io.Reader, out io.Writer, historyPath string) *Terminal {
+	return &Terminal{
+		hub:         hub,
+		outCh:       hub.Subscribe("cli"),
+		chatID:      chatID,
+		in:          in,
+		historyPath: historyPath,
+		out:         out,
+		replied:     make(chan struct{}, 1),
+	}
+}
+
+// lineReader reads the user's input one line at a time.
+type lineReader interface {
+	ReadLine() (string, error)
+	SetPrompt(prompt string)
+}
+
+// plainLines reads lines from input that is not a terminal.
+type plainLines struct{ sc *bufio.Scanner }
+
+func (p plainLines) ReadLine() (string, error) {
+	if !p.sc.Scan() {
+		if err := p.sc.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return p.sc.Text(), nil
+}
+
+func (plainLines) SetPrompt(string) {}
+
+// Run reads and sends messages until the user quits, the input ends or ctx
+// is cancelled.
+func (t *Terminal) Run(ctx context.Context) error {
+	var lines lineReader = plainLines{sc: bufio.NewScanner(t.in)}
+	interactive := false
+	if f, ok := t.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fd := int(f.Fd())
+		state, err := term.MakeRaw(fd)
+		if err != nil {
+			return fmt.Errorf("terminal: %w", err)
+		}
+		defer func() { _ = term.Restore(fd, state) }()
+		vt := term.NewTerminal(struct {
+			io.Reader
+			io.Writer
+		}{f, t.out}, terminalPrompt)
+		if w, h, err := term.GetSize(fd); err == nil && w > 0 && h > 0 {
+			_ = vt.SetSize(w, h)
+		}
+		if t.historyPath != "" {
+			vt.History = loadHistory(t.historyPath)
+		}
+		// output goes through the terminal, which redraws the prompt below it
+		t.mu.Lock()
+		t.out = vt
+		t.mu.Unlock()
+		lines = vt
+		interactive = true
+	}
+
+	go t.runOutbound(ctx)
+
+	var buf []string
+	fenced := false
+	for ctx.Err() == nil {
+		line, err := lines.ReadLine()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil && !errors.Is(err, term.ErrPasteIndicator) {
+			return err
+		}
+		switch {
+		case strings.TrimSpace(line) == terminalFence:
+			fenced = !fenced
+			if fenced {
+				lines.SetPrompt(terminalContinue)
+				continue
+			}
+		case fenced:
+			buf = append(buf, line)
+			continue
+		case strings.HasSuffix(line, `\`):
+			buf = append(buf, strings.TrimSuffix(line, `\`))
+			lines.SetPrompt(terminalContinue)
+			continue
+		default:
+			buf = append(buf, line)
+		}
+		text := strings.TrimSpace(strings.Join(buf, "\n"))
+		buf = nil
+		lines.SetPrompt(terminalPrompt)
+		switch text {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return nil
+		}
+
+		select {
+		case <-t.replied: // forget replies nobody waited for
+		default:
+		}
+		msg := chat.Inbound{Channel: "cli", SenderID: "user", ChatID: t.chatID, Content: text, Timestamp: time.Now()}
+		select {
+		case t.hub.In <- msg:
+		case <-ctx.Done():
+			return nil
+		}
+		if !interactive {
+			select {
+			case <-t.replied:
+			case <-ctx.Done():
+			}
+		}
+	}
+	return nil
+}
+
+// runOutbound prints the agent's messages. Partial updates of streamed
+// replies are skipped; the final message carries the whole text.
+func (t *Terminal) runOutbound(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case out := <-t.outCh:
+			if out.Partial {
+				continue
+			}
+			if strings.HasPrefix(out.Content, "🤖") || strings.HasPrefix(out.Content, "📢") {
+				fmt.Fprintln(t, out.Content)
+				continue
+			}
+			fmt.Fprintf(t, "%s\n\n", strings.TrimRight(out.Content, "\n"))
+			select {
+			case t.replied <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// Write prints p above the prompt. It is safe for concurrent use, e.g. as the
+// output of the log package.
+func (t *Terminal) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.out.Write(p)
+}
+
+// fileHistory is the terminal's line history, kept in a file so that lines
+// can be recalled in later runs.
+type fileHistory struct {
+	path  string
+	lines []string // oldest first
+}
+
+// loadHistory reads the history kept at path. A missing or unreadable file
+// starts an empty history.
+func loadHistory(path string) *fileHistory {
+	h := &fileHistory{path: path}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return h
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		if line != "" {
+			h.lines = append(h.lines, line)
+		}
+	}
+	if len(h.lines) > maxTerminalHistory {
+		h.lines = h.lines[len(h.lines)-maxTerminalHistory:]
+		_ = os.WriteFile(path, []byte(strings.Join(h.lines, "\n")+"\n"), 0o600)
+	}
+	return h
+}
+
+func (h *fileHistory) Add(entry string) {
+	if strings.TrimSpace(entry) == "" || (len(h.lines) > 0 && h.lines[len(h.lines)-1] == entry) {
+		return
+	}
+	h.lines = append(h.lines, entry)
+	if len(h.lines) > maxTerminalHistory {
+		h.lines = h.lines[1:]
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	_, _ = f.WriteString(entry + "\n")
+}
+
+func (h *fileHistory) Len() int { return len(h.lines) }
+
+func (h *fileHistory) At(idx int) string { return h.lines[len(h.lines)-1-idx] }
@@ -0,0 +1,105 @@
+package channels
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/chat"
+)
+
+// syncBuffer is a bytes.Buffer safe for the terminal's concurrent writes.
+type syncBuffer struct {
+	mu sync.Mutex
+	b  bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.b.String()
+}
+
+func TestTerminalSendsMessagesAndPrintsReplies(t *testing.T) {
+	hub := chat.NewHub(10)
+	input := strings.Join([]string{
+		"hello",
+		"",
+		`first line \`,
+		"second line",
+		`"""`,
+		"fenced one",
+		"",
+		"fenced two",
+		`"""`,
+		"/exit",
+		"never sent",
+	}, "\n")
+	out := &syncBuffer{}
+	term := NewTerminal(hub, "dev", strings.NewReader(input), out, "")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	hub.StartRouter(ctx)
+
+	// a fake agent: a tool notification, a partial update, then the reply
+	var got []chat.Inbound
+	go func() {
+		for {
+			select {
+			case msg := <-hub.In:
+				got = append(got, msg)
+				hub.Out <- chat.Outbound{Channel: "cli", ChatID: msg.ChatID, Content: "🤖 Running: echo"}
+				hub.Out <- chat.Outbound{Channel: "cli", ChatID: msg.ChatID, Content: "ec", Partial: true, StreamID: "s"}
+				hub.Out <- chat.Outbound{Channel: "cli", ChatID: msg.ChatID, Content: "echo: " + msg.Content, StreamID: "s"}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if err := term.Run(ctx); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+
+	want := []string{"hello", "first line \nsecond line", "fenced one\n\nfenced two"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), got)
+	}
+	for i, msg := range got {
+		if msg.Content != want[i] || msg.Channel != "cli" || msg.ChatID != "dev" || msg.SenderID != "user" {
+			t.Fatalf("message %d: unexpected %+v", i, msg)
+		}
+	}
+	printed := out.String()
+	if !strings.Contains(printed, "🤖 Running: echo\necho: hello\n\n") {
+		t.Fatalf("expected the tool activity and reply, got %q", printed)
+	}
+	if strings.Contains(printed, "ec\n") {
+		t.Fatalf("partial updates must not be printed, got %q", printed)
+	}
+}
+
+func TestTerminalHistoryPersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	h := loadHistory(path)
+	h.Add("one")
+	h.Add("two")
+	h.Add("two") // repeats are kept once
+	h.Add("  ")
+
+	h = loadHistory(path)
+	if h.Len() != 2 || h.At(0) != "two" || h.At(1) != "one" {
+		t.Fatalf("unexpected history %q", h.lines)
+	}
+}
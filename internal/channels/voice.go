@@ -0,0 +1,47 @@
+package channels
+
+import (
+	"context"
+	"log"
+
+	"github.com/local/picobot/internal/transcribe"
+)
+
+// transcribeVoice turns a voice message into inbound content. download is only
+// called when a transcriber is configured. The returned metadata marks the
+// content as a transcript ("transcribed": true) so the agent can tell speech
+// from typed text; when transcription is unavailable or fails, the content is a
+// short note instead so the user still gets a reply.
+func transcribeVoice(ctx context.Context, tr transcribe.Transcriber, channel string, download func() ([]byte, string, error)) (string, map[string]interface{}) {
+	meta := map[string]interface{}{"voice": true, "transcribed": false}
+	if tr == nil {
+		return "[voice message received, but transcription is not configured]", meta
+	}
+	audio, mimeType, err := download()
+	if err != nil {
+		log.Printf("%s: voice download error: %v", channel, err)
+		return "[voice message could not be downloaded]", meta
+	}
+	text, err := tr.Transcribe(ctx, audio, mimeType)
+	if err != nil {
+		log.Printf("%s: transcription error: %v", channel, err)
+		return "[voice message could not be transcribed]", meta
+	}
+	if text == "" {
+		return "[voice message contained no speech]", meta
+	}
+	meta["transcribed"] = true
+	return text, meta
+}
+
+// transcribeInBackground runs transcribeVoice in its own goroutine and hands
+// the result to deliver, so that downloading and transcribing a voice message
+// (up to transcription.timeoutS) never holds up the other messages the
+// channel receives meanwhile. Text sent after a voice message may therefore
+// reach the agent first.
+func transcribeInBackground(ctx context.Context, tr transcribe.Transcriber, channel string, download func() ([]byte, string, error), deliver func(transcript string, meta map[string]interface{})) {
+	go func() {
+		transcript, meta := transcribeVoice(ctx, tr, channel, download)
+		deliver(transcript, meta)
+	}()
+}
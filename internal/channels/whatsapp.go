@@ -22,6 +22,8 @@ import (
 	_ "modernc.org/sqlite"
 
 	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/media"
+	"github.com/local/picobot/internal/transcribe"
 )
 
 // whatsappSender is the subset of *whatsmeow.Client used for outbound operations.
@@ -31,6 +33,7 @@ type whatsappSender interface {
 	SendChatPresence(ctx context.Context, chat types.JID, state types.ChatPresence, media types.ChatPresenceMedia) error
 	MarkRead(ctx context.Context, ids []types.MessageID, timestamp time.Time, chat, sender types.JID) error
 	SendPresence(ctx context.Context, state types.Presence) error
+	Download(ctx context.Context, msg whatsmeow.DownloadableMessage) ([]byte, error)
 }
 
 // realWhatsAppSender wraps *whatsmeow.Client to implement whatsappSender.
@@ -55,6 +58,10 @@ func (r *realWhatsAppSender) SendPresence(ctx context.Context, state types.Prese
 	return r.c.SendPresence(ctx, state)
 }
 
+func (r *realWhatsAppSender) Download(ctx context.Context, msg whatsmeow.DownloadableMessage) ([]byte, error) {
+	return r.c.Download(ctx, msg)
+}
+
 // whatsappLogger adapts the whatsmeow logger to use Go's standard logger.
 type whatsappLogger struct{}
 
@@ -85,7 +92,8 @@ func (l quietLogger) Sub(module string) waLog.Logger         { return l }
 // dbPath is the path to the SQLite database for storing session data.
 // allowFrom restricts which phone numbers (digits only, e.g. "15551234567") may
 // send messages; empty means allow all.
-func StartWhatsApp(ctx context.Context, hub *chat.Hub, dbPath string, allowFrom []string) error {
+// tr transcribes voice notes and audio messages; nil disables transcription.
+func StartWhatsApp(ctx context.Context, hub *chat.Hub, dbPath string, allowFrom []string, tr transcribe.Transcriber) error {
 	if dbPath == "" {
 		return fmt.Errorf("whatsapp database path not provided")
 	}
@@ -113,6 +121,7 @@ func StartWhatsApp(ctx context.Context, hub *chat.Hub, dbPath string, allowFrom
 	own := *rawClient.Store.ID
 	ownLID := rawClient.Store.GetLID()
 	waClient := newWhatsAppClient(ctx, sender, hub, allowFrom, own, ownLID)
+	waClient.transcriber = tr
 	rawClient.AddEventHandler(waClient.handleEvent)
 
 	if err := rawClient.Connect(); err != nil {
@@ -229,6 +238,8 @@ type whatsappClient struct {
 	ctx        context.Context
 	typingMu   sync.Mutex
 	typingStop map[string]chan struct{}
+	// transcriber turns voice notes into text; nil disables transcription.
+	transcriber transcribe.Transcriber
 }
 
 // newWhatsAppClient constructs a whatsappClient and registers it as the hub's
@@ -305,34 +316,57 @@ func (c *whatsappClient) handleMessage(msg *events.Message) {
 		}
 	}
 
-	// Use the full JID string for logging; the User part is used as SenderID in the hub.
-	senderJID := msg.Info.Sender.String()
-	senderID := msg.Info.Sender.User
-
 	// Send read receipt (blue ticks) before processing.
 	_ = c.sender.MarkRead(c.ctx, []types.MessageID{msg.Info.ID}, msg.Info.Timestamp, msg.Info.Chat, msg.Info.Sender)
 
-	content := extractMessageText(msg.Message)
-	if content == "" {
+	content := strings.TrimSpace(extractMessageText(msg.Message))
+	images := c.downloadImages(msg.Message)
+	metadata := map[string]interface{}{
+		"message_id": msg.Info.ID,
+		"is_group":   msg.Info.IsGroup,
+	}
+	if am := msg.Message.GetAudioMessage(); am != nil {
+		transcribeInBackground(c.ctx, c.transcriber, "whatsapp", func() ([]byte, string, error) {
+			if am.GetFileLength() > transcribe.MaxAudioBytes {
+				return nil, "", fmt.Errorf("audio too large (%d bytes)", am.GetFileLength())
+			}
+			data, err := c.sender.Download(c.ctx, am)
+			return data, am.GetMimetype(), err
+		}, func(transcript string, voiceMeta map[string]interface{}) {
+			for k, v := range voiceMeta {
+				metadata[k] = v
+			}
+			c.deliver(msg, transcript, images, metadata)
+		})
 		return
 	}
-	content = strings.TrimSpace(content)
+	if content == "" && len(images) == 0 {
+		return
+	}
+	c.deliver(msg, content, images, metadata)
+}
+
+// deliver passes an inbound message on to the hub and shows the typing
+// indicator while the agent works on it.
+func (c *whatsappClient) deliver(msg *events.Message, content string, images []string, metadata map[string]interface{}) {
 	chatID := msg.Info.Chat.String()
 
-	log.Printf("whatsapp: message from %s in chat %s: %s", senderJID, chatID, truncate(content, 50))
+	// Use the full JID string for logging; the User part is used as SenderID in the hub.
+	log.Printf("whatsapp: message from %s in chat %s: %s", msg.Info.Sender.String(), chatID, truncate(content, 50))
 
 	c.startTyping(msg.Info.Chat)
 
-	c.hub.In <- chat.Inbound{
+	select {
+	case c.hub.In <- chat.Inbound{
 		Channel:   "whatsapp",
-		SenderID:  senderID,
+		SenderID:  msg.Info.Sender.User,
 		ChatID:    chatID,
 		Content:   content,
 		Timestamp: msg.Info.Timestamp,
-		Metadata: map[string]interface{}{
-			"message_id": msg.Info.ID,
-			"is_group":   msg.Info.IsGroup,
-		},
+		Media:     images,
+		Metadata:  metadata,
+	}:
+	case <-c.ctx.Done():
 	}
 }
 
@@ -349,11 +383,8 @@ func extractMessageText(m *waE2E.Message) string {
 		return *m.ExtendedTextMessage.Text
 	}
 	if m.ImageMessage != nil {
-		caption := ""
-		if m.ImageMessage.Caption != nil {
-			caption = *m.ImageMessage.Caption
-		}
-		return caption + "\n[Image received - images not yet supported]"
+		// the image itself is delivered as media by downloadImages
+		return m.ImageMessage.GetCaption()
 	}
 	if m.DocumentMessage != nil {
 		caption := ""
@@ -368,6 +399,40 @@ func extractMessageText(m *waE2E.Message) string {
 	return ""
 }
 
+// downloadImages decrypts the image of an image message (or an image sent as a
+// document) and returns it as a data URI. Failures are logged and skipped.
+func (c *whatsappClient) downloadImages(m *waE2E.Message) []string {
+	if m == nil {
+		return nil
+	}
+	var (
+		dl       whatsmeow.DownloadableMessage
+		mimeType string
+		size     uint64
+	)
+	switch {
+	case m.ImageMessage != nil:
+		dl, mimeType, size = m.ImageMessage, m.ImageMessage.GetMimetype(), m.ImageMessage.GetFileLength()
+	case m.DocumentMessage != nil && media.IsImage(m.DocumentMessage.GetMimetype()):
+		dl, mimeType, size = m.DocumentMessage, m.DocumentMessage.GetMimetype(), m.DocumentMessage.GetFileLength()
+	default:
+		return nil
+	}
+	if size > media.MaxImageBytes {
+		log.Printf("whatsapp: image too large (%d bytes), skipping", size)
+		return nil
+	}
+	data, err := c.sender.Download(c.ctx, dl)
+	if err != nil {
+		log.Printf("whatsapp: image download error: %v", err)
+		return nil
+	}
+	if mimeType == "" {
+		mimeType = "image/jpeg"
+	}
+	return []string{media.DataURI(mimeType, data)}
+}
+
 // runOutbound reads replies from the hub's whatsapp subscription and sends them.
 func (c *whatsappClient) runOutbound() {
 	for {
@@ -376,6 +441,9 @@ func (c *whatsappClient) runOutbound() {
 			log.Println("whatsapp: stopping outbound sender")
 			return
 		case out := <-c.outCh:
+			if out.Partial {
+				continue // WhatsApp cannot edit sent messages; wait for the final reply
+			}
 			recipient, err := types.ParseJID(out.ChatID)
 			if err != nil {
 				log.Printf("whatsapp: invalid chat ID %s: %v", out.ChatID, err)
@@ -8,12 +8,13 @@ import (
 	"log"
 
 	"github.com/local/picobot/internal/chat"
+	"github.com/local/picobot/internal/transcribe"
 )
 
 // StartWhatsApp is a no-op stub used when the binary is built with the
 // 'lite' build tag. If WhatsApp is enabled in the config it logs a clear
 // warning and returns nil so the gateway continues with other channels.
-func StartWhatsApp(ctx context.Context, hub *chat.Hub, dbPath string, allowFrom []string) error {
+func StartWhatsApp(ctx context.Context, hub *chat.Hub, dbPath string, allowFrom []string, tr transcribe.Transcriber) error {
 	log.Println("whatsapp: channel not available in 'lite' version.")
 	return nil
 }
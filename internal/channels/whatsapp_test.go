@@ -9,6 +9,7 @@ import (
 	"testing"
 	"time"
 
+	"go.mau.fi/whatsmeow"
 	waE2E "go.mau.fi/whatsmeow/proto/waE2E"
 	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
@@ -66,6 +67,10 @@ func (m *mockWhatsAppSender) SendPresence(_ context.Context, state types.Presenc
 	return nil
 }
 
+func (m *mockWhatsAppSender) Download(_ context.Context, _ whatsmeow.DownloadableMessage) ([]byte, error) {
+	return []byte("jpegbytes"), nil
+}
+
 func (m *mockWhatsAppSender) sentCount() int {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -93,7 +98,7 @@ func makeWhatsAppMsg(senderUser string, isFromMe, isGroup bool, text string) *ev
 /*** StartWhatsApp / SetupWhatsApp guard tests ***/
 
 func TestStartWhatsApp_EmptyDBPath(t *testing.T) {
-	err := StartWhatsApp(context.Background(), chat.NewHub(10), "", nil)
+	err := StartWhatsApp(context.Background(), chat.NewHub(10), "", nil, nil)
 	if err == nil || err.Error() != "whatsapp database path not provided" {
 		t.Fatalf("expected 'whatsapp database path not provided', got %v", err)
 	}
@@ -142,6 +147,122 @@ func TestWhatsAppClient_HandleMessage_Inbound(t *testing.T) {
 	}
 }
 
+func TestWhatsAppClient_HandleMessage_ImageBecomesMedia(t *testing.T) {
+	hub := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newWhatsAppClient(ctx, &mockWhatsAppSender{}, hub, nil, types.JID{}, types.JID{})
+
+	caption := "total?"
+	mimeType := "image/jpeg"
+	msg := makeWhatsAppMsg("15551234567", false, false, "")
+	msg.Message = &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: &caption, Mimetype: &mimeType}}
+	c.handleMessage(msg)
+
+	select {
+	case in := <-hub.In:
+		if in.Content != caption {
+			t.Errorf("Content = %q, want %q", in.Content, caption)
+		}
+		if len(in.Media) != 1 || !strings.HasPrefix(in.Media[0], "data:image/jpeg;base64,") {
+			t.Errorf("expected one JPEG data URI in Media, got %v", in.Media)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+	c.stopAllTyping()
+}
+
+func TestWhatsAppClient_HandleMessage_VoiceTranscribed(t *testing.T) {
+	hub := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newWhatsAppClient(ctx, &mockWhatsAppSender{}, hub, nil, types.JID{}, types.JID{})
+	tr := &fakeTranscriber{text: "what's the weather tomorrow"}
+	c.transcriber = tr
+
+	msg := makeWhatsAppMsg("15551234567", false, false, "")
+	mimeType := "audio/ogg; codecs=opus"
+	ptt := true
+	msg.Message = &waE2E.Message{AudioMessage: &waE2E.AudioMessage{Mimetype: &mimeType, PTT: &ptt}}
+	c.handleMessage(msg)
+
+	select {
+	case in := <-hub.In:
+		if in.Content != tr.text {
+			t.Errorf("Content = %q, want %q", in.Content, tr.text)
+		}
+		if in.Metadata["transcribed"] != true || in.Metadata["message_id"] == nil {
+			t.Errorf("expected transcribed marker alongside message metadata, got %v", in.Metadata)
+		}
+		if tr.mimeType != mimeType {
+			t.Errorf("transcriber got mime type %q, want %q", tr.mimeType, mimeType)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+	c.stopAllTyping()
+}
+
+func TestWhatsAppClient_HandleMessage_SlowVoiceDoesNotBlockText(t *testing.T) {
+	hub := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newWhatsAppClient(ctx, &mockWhatsAppSender{}, hub, nil, types.JID{}, types.JID{})
+	tr := blockingTranscriber{release: make(chan struct{})}
+	c.transcriber = tr
+
+	voice := makeWhatsAppMsg("15551234567", false, false, "")
+	voice.Message = &waE2E.Message{AudioMessage: &waE2E.AudioMessage{}}
+	done := make(chan struct{})
+	go func() {
+		c.handleMessage(voice)
+		c.handleMessage(makeWhatsAppMsg("15557654321", false, false, "hello"))
+		close(done)
+	}()
+
+	select {
+	case in := <-hub.In:
+		if in.Content != "hello" {
+			t.Fatalf("expected the text message while the voice note is transcribed, got %q", in.Content)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the text message was held up by the voice note")
+	}
+	<-done
+	close(tr.release)
+	select {
+	case in := <-hub.In:
+		if in.Content != "slow transcript" || in.SenderID != "15551234567" {
+			t.Fatalf("expected the voice note afterwards, got %+v", in)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for the voice note")
+	}
+	c.stopAllTyping()
+}
+
+func TestWhatsAppClient_HandleMessage_VoiceWithoutTranscriber(t *testing.T) {
+	hub := chat.NewHub(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c := newWhatsAppClient(ctx, &mockWhatsAppSender{}, hub, nil, types.JID{}, types.JID{})
+
+	msg := makeWhatsAppMsg("15551234567", false, false, "")
+	msg.Message = &waE2E.Message{AudioMessage: &waE2E.AudioMessage{}}
+	c.handleMessage(msg)
+
+	select {
+	case in := <-hub.In:
+		if !strings.Contains(in.Content, "not configured") || in.Metadata["transcribed"] != false {
+			t.Errorf("expected a not-configured note, got %q (%v)", in.Content, in.Metadata)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for inbound message")
+	}
+	c.stopAllTyping()
+}
+
 func TestWhatsAppClient_HandleMessage_SkipsFromMe(t *testing.T) {
 	hub := chat.NewHub(10)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -404,7 +525,7 @@ func TestExtractMessageText(t *testing.T) {
 		{"nil message", nil, "", true},
 		{"conversation", &waE2E.Message{Conversation: &hello}, "Hello", false},
 		{"extended text", &waE2E.Message{ExtendedTextMessage: &waE2E.ExtendedTextMessage{Text: &hello}}, "Hello", false},
-		{"image no caption", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{}}, "[Image received", false},
+		{"image no caption", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{}}, "", true},
 		{"image with caption", &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: &caption}}, caption, false},
 		{"document with filename", &waE2E.Message{DocumentMessage: &waE2E.DocumentMessage{FileName: &docName}}, "report.pdf", false},
 		{"empty proto", &waE2E.Message{}, "", true},
@@ -26,6 +26,27 @@ type Outbound struct {
 	ReplyTo  string
 	Media    []string
 	Metadata map[string]interface{}
+
+	// StreamID groups the progressive updates of one streamed reply, so that
+	// channels supporting message edits can update a single message in place.
+	// It is empty for ordinary messages.
+	StreamID string
+	// Partial marks an in-progress streamed update; Content holds the full text
+	// received so far. Channels that cannot edit messages should ignore partial
+	// updates and deliver only the final message carrying the same StreamID.
+	Partial bool
+
+	// Buttons are choices shown under the message on channels with native
+	// buttons. Pressing one delivers its Data as the Content of an Inbound
+	// from the person who pressed it. Content must still say how to answer
+	// by text, for channels that cannot show buttons.
+	Buttons []Button
+}
+
+// Button is one choice offered under an outbound message.
+type Button struct {
+	Text string // label shown to the user
+	Data string // content sent back when pressed, e.g. "/approve 3fa2"
 }
 
 // Hub provides simple buffered channels for inbound/outbound messages.
@@ -38,6 +59,10 @@ type Hub struct {
 	In  chan Inbound
 	Out chan Outbound
 
+	// Commands holds the slash commands answered without calling the LLM.
+	// The agent registers the built-in ones; channels may add their own.
+	Commands *Commands
+
 	subMu sync.RWMutex
 	subs  map[string]chan Outbound
 }
@@ -45,9 +70,10 @@ type Hub struct {
 // NewHub constructs a new Hub with the given buffer size.
 func NewHub(buffer int) *Hub {
 	return &Hub{
-		In:   make(chan Inbound, buffer),
-		Out:  make(chan Outbound, buffer),
-		subs: make(map[string]chan Outbound),
+		In:       make(chan Inbound, buffer),
+		Out:      make(chan Outbound, buffer),
+		Commands: NewCommands(),
+		subs:     make(map[string]chan Outbound),
 	}
 }
 
@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Command is a slash command answered without calling the LLM.
+type Command struct {
+	Name        string // without the leading slash, e.g. "reset"
+	Usage       string // argument synopsis shown by /help, e.g. "<name>"
+	Description string
+	// Immediate commands run as soon as they arrive instead of waiting behind
+	// the chat's running turn (e.g. /stop).
+	Immediate bool
+	// Handler returns the reply sent back to the chat. args is the text after
+	// the command name.
+	Handler func(ctx context.Context, msg Inbound, args string) string
+}
+
+// commandRE matches "/name", "/name args" and Telegram's "/name@botname".
+// Paths such as "/etc/hosts" do not match.
+var commandRE = regexp.MustCompile(`^/([A-Za-z][A-Za-z0-9_]*)(?:@[A-Za-z0-9_]+)?(?:\s+(.*))?$`)
+
+// Commands is a registry of slash commands shared by the agent and the
+// channels. It is safe for concurrent use.
+type Commands struct {
+	mu     sync.RWMutex
+	cmds   map[string]Command
+	parent *Commands
+}
+
+// NewCommands returns an empty registry.
+func NewCommands() *Commands {
+	return &Commands{cmds: make(map[string]Command)}
+}
+
+// Child returns an empty registry that falls back to c for commands it does
+// not have itself, e.g. for an agent on a hub of its own that must still
+// answer the commands channels register on the shared hub.
+func (c *Commands) Child() *Commands {
+	return &Commands{cmds: make(map[string]Command), parent: c}
+}
+
+// Register adds or replaces a command.
+func (c *Commands) Register(cmd Command) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cmds[strings.ToLower(cmd.Name)] = cmd
+}
+
+// Parse reports whether content is a slash command and returns its lower-cased
+// name and arguments. The command does not have to be registered.
+func Parse(content string) (name, args string, ok bool) {
+	m := commandRE.FindStringSubmatch(strings.TrimSpace(content))
+	if m == nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), strings.TrimSpace(m[2]), true
+}
+
+// Lookup returns the registered command named name.
+func (c *Commands) Lookup(name string) (Command, bool) {
+	c.mu.RLock()
+	cmd, ok := c.cmds[strings.ToLower(name)]
+	c.mu.RUnlock()
+	if !ok && c.parent != nil {
+		return c.parent.Lookup(name)
+	}
+	return cmd, ok
+}
+
+// List returns all registered commands sorted by name.
+func (c *Commands) List() []Command {
+	var out []Command
+	if c.parent != nil {
+		for _, cmd := range c.parent.List() {
+			if c.own(cmd.Name) {
+				continue // overridden here
+			}
+			out = append(out, cmd)
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, cmd := range c.cmds {
+		out = append(out, cmd)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// own reports whether name is registered in c itself.
+func (c *Commands) own(name string) bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	_, ok := c.cmds[strings.ToLower(name)]
+	return ok
+}
@@ -118,7 +118,10 @@ Never create files directly in the workspace root. Always use a project folder.
 - Use read_memory to check what is already stored before writing new entries
 - Use edit_memory to update or correct individual facts without rewriting the whole file
 - Use list_memory to see all available memory files
+- Use search_memory to find what was said about something on an earlier day, instead of reading every file
 - Use delete_memory to clean up outdated daily notes
+- Use upsert_fact for stable facts about the user, people and things (city, birthday, employer); storing a new value replaces the old one, so facts never contradict each other
+- Use retract_fact when a fact turns out to be wrong or no longer holds
 - Do NOT just say you'll remember something — actually call write_memory
 - NEVER write heartbeat results, health checks, or periodic status logs to memory — these are ephemeral and must be discarded after each run
 - Memory is for durable user knowledge only: facts, preferences, project notes, decisions
@@ -233,6 +236,11 @@ Persist information to memory files. Never store redundant information like hear
 List all memory files (daily notes and long-term MEMORY.md).
 - No arguments needed
 
+### search_memory
+Search every memory file for notes matching some words; returns file, line and a snippet for each match.
+- query: words to search for, e.g. "dentist appointment"
+- limit: maximum number of results (default 5)
+
 ### read_memory
 Read the contents of a specific memory file.
 - target: "today", "long", or a date "YYYY-MM-DD"
@@ -247,6 +255,27 @@ Find and replace text within a memory file.
 Delete a daily memory file. Cannot delete long-term memory (MEMORY.md).
 - target: date in "YYYY-MM-DD" format
 
+### upsert_fact
+Store a structured fact, or update it if the subject already has a value for the predicate. Storing the same value again confirms it.
+- subject: who or what the fact is about ("user" for the person you are talking to, or a name)
+- predicate: the attribute or relation, e.g. "city", "birthday", "sister"
+- object: the value, e.g. "Lisbon"
+- tags: optional list of tags, e.g. ["family"]
+- confidence: "low", "medium" (default) or "high"
+
+### query_facts
+Look up facts. All filters are optional; with none, every fact is listed.
+- subject, predicate, tag: exact matches (case-insensitive)
+- text: text contained in any field
+- limit: maximum number of facts (default 20)
+
+### retract_fact
+Remove a fact that is wrong or no longer true.
+- id: the fact id shown by query_facts, or
+- subject and predicate of the fact
+
+When memory is kept per user, these tools work on the memory of the person you are talking to. Pass shared: true to write_memory, read_memory, edit_memory, delete_memory, list_memory, upsert_fact or retract_fact to use the memory shared by everyone instead (for facts that concern all users). search_memory and query_facts search both.
+
 ## Skill Management
 
 ### create_skill
@@ -269,7 +298,15 @@ Delete a skill from skills/.
 ## Background Tasks
 
 ### spawn
-Spawn a background subagent process.
+Run a task in the background with a sub-agent, so the conversation can go on meanwhile. The sub-agent sees nothing of the conversation and has its own tools and budget.
+- action: start (default), status, wait, list or cancel
+- task: complete, self-contained instructions (start)
+- agent: short name for the sub-agent (start)
+- tools: tools it may use (start; default: web, web_search, filesystem and the memory and skill reading tools)
+- max_iterations, timeout_s: its budget (start; default 10 model calls, 300 seconds)
+- deliver: post the result to this chat when done (start; default true)
+- id: the sub-agent (status, wait, cancel)
+Use wait when you need the result for your current reply.
 
 ### cron
 Schedule or manage cron jobs.
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+)
+
+// DefaultAgent is the name of the agent built from agents.defaults, which
+// answers every message no route claims.
+const DefaultAgent = "default"
+
+// AgentNames returns the names of the agents to run: DefaultAgent followed
+// by the other profiles in alphabetical order.
+func (c Config) AgentNames() []string {
+	names := []string{DefaultAgent}
+	for name := range c.Agents.Profiles {
+		if name != DefaultAgent {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// AgentProfile returns the settings of agent name with every unset field
+// filled in from agents.defaults and the top-level config. A profile's
+// workspace defaults to "workspace-<name>" beside the default workspace, so
+// that profiles cannot read each other's files. MCPServers is always
+// resolved to the list of server names to connect to.
+func (c Config) AgentProfile(name string) (AgentProfile, error) {
+	p, ok := c.Agents.Profiles[name]
+	if !ok && name != DefaultAgent {
+		return AgentProfile{}, fmt.Errorf("unknown agent profile %q", name)
+	}
+	d := c.Agents.Defaults
+	if p.Model == "" {
+		p.Model = d.Model
+	}
+	if p.Workspace == "" {
+		p.Workspace = d.Workspace
+		if name != DefaultAgent {
+			p.Workspace = filepath.Join(filepath.Dir(d.Workspace), "workspace-"+name)
+		}
+	}
+	if p.MaxToolIterations <= 0 {
+		p.MaxToolIterations = d.MaxToolIterations
+	}
+	if p.MemoryScope == "" {
+		p.MemoryScope = c.Memory.Scope
+	}
+	if p.MCPServers == nil {
+		p.MCPServers = make([]string, 0, len(c.MCPServers))
+		for server := range c.MCPServers {
+			p.MCPServers = append(p.MCPServers, server)
+		}
+		sort.Strings(p.MCPServers)
+	}
+	for _, server := range p.MCPServers {
+		if _, ok := c.MCPServers[server]; !ok {
+			return AgentProfile{}, fmt.Errorf("agent profile %q: unknown MCP server %q", name, server)
+		}
+	}
+	return p, nil
+}
+
+// ValidateRoutes checks that every route names a known agent.
+func (c Config) ValidateRoutes() error {
+	for i, r := range c.Agents.Routes {
+		if r.Agent == "" {
+			return fmt.Errorf("route %d: no agent given", i+1)
+		}
+		if _, ok := c.Agents.Profiles[r.Agent]; !ok && r.Agent != DefaultAgent {
+			return fmt.Errorf("route %d: unknown agent profile %q", i+1, r.Agent)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAgentProfileFallsBackToDefaults(t *testing.T) {
+	cfg := Config{
+		Agents: AgentsConfig{
+			Defaults: AgentDefaults{Workspace: "/home/me/.picobot/workspace", Model: "big-model", MaxToolIterations: 50},
+			Profiles: map[string]AgentProfile{
+				"ops":    {Model: "small-model", Tools: []string{"exec"}, MCPServers: []string{"github"}},
+				"family": {MemoryScope: "chat"},
+			},
+		},
+		MCPServers: map[string]MCPServerConfig{"github": {Command: "gh-mcp"}, "notes": {URL: "http://notes"}},
+		Memory:     MemoryConfig{Scope: "sender"},
+	}
+
+	if got := cfg.AgentNames(); !reflect.DeepEqual(got, []string{"default", "family", "ops"}) {
+		t.Fatalf("AgentNames() = %v", got)
+	}
+
+	def, err := cfg.AgentProfile(DefaultAgent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := AgentProfile{Model: "big-model", Workspace: "/home/me/.picobot/workspace", MaxToolIterations: 50, MCPServers: []string{"github", "notes"}, MemoryScope: "sender"}
+	if !reflect.DeepEqual(def, want) {
+		t.Fatalf("default profile = %+v, want %+v", def, want)
+	}
+
+	ops, err := cfg.AgentProfile("ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = AgentProfile{Model: "small-model", Workspace: filepath.Join("/home/me/.picobot", "workspace-ops"), MaxToolIterations: 50, Tools: []string{"exec"}, MCPServers: []string{"github"}, MemoryScope: "sender"}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("ops profile = %+v, want %+v", ops, want)
+	}
+
+	family, _ := cfg.AgentProfile("family")
+	if family.MemoryScope != "chat" || family.Tools != nil || family.Model != "big-model" {
+		t.Fatalf("family profile = %+v", family)
+	}
+
+	if _, err := cfg.AgentProfile("nobody"); err == nil {
+		t.Fatal("expected an error for an unknown profile")
+	}
+	cfg.Agents.Profiles["broken"] = AgentProfile{MCPServers: []string{"missing"}}
+	if _, err := cfg.AgentProfile("broken"); err == nil {
+		t.Fatal("expected an error for an unknown MCP server")
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	cfg := Config{Agents: AgentsConfig{
+		Profiles: map[string]AgentProfile{"ops": {}},
+		Routes:   []AgentRoute{{Channel: "slack", Agent: "ops"}, {ChatID: "42", Agent: "default"}},
+	}}
+	if err := cfg.ValidateRoutes(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cfg.Agents.Routes = append(cfg.Agents.Routes, AgentRoute{Channel: "telegram", Agent: "family"})
+	if err := cfg.ValidateRoutes(); err == nil {
+		t.Fatal("expected an error for a route to an unknown profile")
+	}
+}
@@ -2,10 +2,16 @@ package config
 
 // Config holds picobot configuration (minimal for v0).
 type Config struct {
-	Agents     AgentsConfig               `json:"agents"`
-	MCPServers map[string]MCPServerConfig `json:"mcpServers"`
-	Channels   ChannelsConfig             `json:"channels"`
-	Providers  ProvidersConfig            `json:"providers"`
+	Agents        AgentsConfig               `json:"agents"`
+	MCPServers    map[string]MCPServerConfig `json:"mcpServers"`
+	Channels      ChannelsConfig             `json:"channels"`
+	Providers     ProvidersConfig            `json:"providers"`
+	Usage         UsageConfig                `json:"usage"`
+	Transcription TranscriptionConfig        `json:"transcription"`
+	Storage       StorageConfig              `json:"storage"`
+	Embeddings    EmbeddingsConfig           `json:"embeddings"`
+	Memory        MemoryConfig               `json:"memory"`
+	Approval      ApprovalConfig             `json:"approval"`
 }
 
 // MCPServerConfig describes a single MCP server connection.
@@ -19,6 +25,35 @@ type MCPServerConfig struct {
 
 type AgentsConfig struct {
 	Defaults AgentDefaults `json:"defaults"`
+	// Profiles are named agents with their own model, workspace, tools and
+	// memory; unset fields fall back to Defaults. A profile named "default"
+	// adjusts the agent that unrouted messages go to.
+	Profiles map[string]AgentProfile `json:"profiles,omitempty"`
+	// Routes pick the profile that answers a message; the first match wins
+	// and unmatched messages go to the default agent.
+	Routes []AgentRoute `json:"routes,omitempty"`
+}
+
+// AgentProfile configures one named agent.
+type AgentProfile struct {
+	Model             string `json:"model,omitempty"`
+	Workspace         string `json:"workspace,omitempty"` // bootstrap files, memory, sessions and skills
+	MaxToolIterations int    `json:"maxToolIterations,omitempty"`
+	// Tools lists the tools the agent may use; nil allows all of them.
+	Tools []string `json:"tools,omitempty"`
+	// MCPServers names the entries of the top-level mcpServers the agent
+	// connects to; nil connects to all of them.
+	MCPServers  []string `json:"mcpServers,omitempty"`
+	MemoryScope string   `json:"memoryScope,omitempty"`
+}
+
+// AgentRoute sends the messages matching all of its non-empty fields to the
+// profile named Agent.
+type AgentRoute struct {
+	Channel  string `json:"channel,omitempty"`
+	ChatID   string `json:"chatId,omitempty"`
+	SenderID string `json:"senderId,omitempty"`
+	Agent    string `json:"agent"`
 }
 
 type AgentDefaults struct {
@@ -29,6 +64,10 @@ type AgentDefaults struct {
 	MaxToolIterations  int     `json:"maxToolIterations"`
 	HeartbeatIntervalS int     `json:"heartbeatIntervalS"`
 	RequestTimeoutS    int     `json:"requestTimeoutS"`
+	Streaming          bool    `json:"streaming"`
+	MaxConcurrency     int     `json:"maxConcurrency"`
+	MaxParallelTools   int     `json:"maxParallelTools"`
+	ContextBudget      int     `json:"contextBudgetTokens"`
 }
 
 type ChannelsConfig struct {
@@ -65,10 +104,107 @@ type WhatsAppConfig struct {
 }
 
 type ProvidersConfig struct {
-	OpenAI *ProviderConfig `json:"openai,omitempty"`
+	OpenAI    *ProviderConfig  `json:"openai,omitempty"`
+	Anthropic *ProviderConfig  `json:"anthropic,omitempty"`
+	Fallbacks []FallbackConfig `json:"fallbacks,omitempty"`
+	Retry     *RetryConfig     `json:"retry,omitempty"`
+}
+
+// FallbackConfig is a backup provider tried, in order, after the primary one.
+type FallbackConfig struct {
+	Type    string `json:"type"` // "openai" (default) | "anthropic"
+	APIKey  string `json:"apiKey"`
+	APIBase string `json:"apiBase"`
+	Model   string `json:"model"` // empty means the agent's model
+}
+
+// RetryConfig tunes retries and circuit breaking across the provider chain.
+// Zero values fall back to the built-in defaults.
+type RetryConfig struct {
+	MaxAttempts      int `json:"maxAttempts"`
+	InitialBackoffMs int `json:"initialBackoffMs"`
+	MaxBackoffMs     int `json:"maxBackoffMs"`
+	BreakerThreshold int `json:"breakerThreshold"`
+	BreakerCooldownS int `json:"breakerCooldownS"`
 }
 
 type ProviderConfig struct {
 	APIKey  string `json:"apiKey"`
 	APIBase string `json:"apiBase"`
 }
+
+// UsageConfig configures token usage reporting.
+type UsageConfig struct {
+	// Prices maps a model name (or a prefix such as "anthropic/") to its price.
+	Prices map[string]ModelPrice `json:"prices,omitempty"`
+}
+
+// ModelPrice is the cost of a model in USD per million tokens.
+type ModelPrice struct {
+	Prompt     float64 `json:"prompt"`
+	Completion float64 `json:"completion"`
+}
+
+// TranscriptionConfig selects how voice messages are turned into text.
+type TranscriptionConfig struct {
+	Provider string `json:"provider"` // "" (disabled) | "openai" | "command"
+
+	// openai: an OpenAI-compatible /audio/transcriptions endpoint. Empty
+	// APIKey and APIBase default to providers.openai.
+	APIKey  string `json:"apiKey,omitempty"`
+	APIBase string `json:"apiBase,omitempty"`
+	Model   string `json:"model,omitempty"` // default whisper-1
+
+	// command: a local program that prints the transcript of an audio file.
+	Command string   `json:"command,omitempty"`
+	Args    []string `json:"args,omitempty"` // "{input}" is replaced by the file path
+
+	TimeoutS int `json:"timeoutS,omitempty"`
+}
+
+// StorageConfig selects where sessions, memory items, cron jobs and usage
+// records are kept.
+type StorageConfig struct {
+	Backend string `json:"backend"`        // "files" (default) | "sqlite"
+	Path    string `json:"path,omitempty"` // sqlite database, default ~/.picobot/picobot.db
+}
+
+// EmbeddingsConfig enables semantic memory search through an embeddings API.
+type EmbeddingsConfig struct {
+	Provider string `json:"provider"` // "" (disabled) | "openai"
+
+	// Empty APIKey and APIBase default to providers.openai.
+	APIKey   string `json:"apiKey,omitempty"`
+	APIBase  string `json:"apiBase,omitempty"`
+	Model    string `json:"model,omitempty"` // default text-embedding-3-small
+	TimeoutS int    `json:"timeoutS,omitempty"`
+}
+
+// MemoryConfig controls how the agent maintains its memory files.
+type MemoryConfig struct {
+	Scope         string              `json:"scope,omitempty"` // "global" (default) | "sender" | "chat"
+	Consolidation ConsolidationConfig `json:"consolidation"`
+}
+
+// ConsolidationConfig schedules the nightly merge of daily notes into
+// MEMORY.md.
+type ConsolidationConfig struct {
+	Enabled bool   `json:"enabled"`
+	At      string `json:"at,omitempty"`   // local time "HH:MM", default "03:00"
+	Days    int    `json:"days,omitempty"` // days of notes to read, default 7
+	Archive bool   `json:"archive"`        // move consolidated notes to memory/archive/
+}
+
+// ApprovalConfig makes sensitive tool calls wait for the user's approval,
+// asked in the chat the request came from.
+type ApprovalConfig struct {
+	Enabled  bool           `json:"enabled"`
+	Rules    []ApprovalRule `json:"rules,omitempty"`    // default: exec, filesystem writes, delete_memory, mcp_*
+	TimeoutS int            `json:"timeoutS,omitempty"` // unanswered requests are denied after this, default 120
+}
+
+// ApprovalRule selects the tool calls that need approval.
+type ApprovalRule struct {
+	Tool string            `json:"tool"`           // tool name, or a prefix ending in "*" such as "mcp_*"
+	Args map[string]string `json:"args,omitempty"` // argument name -> regular expression its value must match
+}
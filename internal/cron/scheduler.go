@@ -15,14 +15,23 @@ type Job struct {
 	FireAt    time.Time
 	Channel   string // originating channel (e.g., "telegram")
 	ChatID    string // originating chat ID
+	Agent     string // agent profile that scheduled the job, if known
 	Recurring bool   // if true, re-schedule after firing
 	Interval  time.Duration
 	fired     bool
+	fn        func() // set for internal jobs added with AddFunc
 }
 
 // FireCallback is called when a job fires. The scheduler passes the job details.
 type FireCallback func(job Job)
 
+// Store persists pending jobs so they survive a restart.
+type Store interface {
+	LoadJobs() ([]Job, error)
+	SaveJob(j Job) error
+	DeleteJob(id string) error
+}
+
 // Scheduler manages in-memory scheduled jobs and fires them when due.
 type Scheduler struct {
 	mu       sync.Mutex
@@ -30,6 +39,7 @@ type Scheduler struct {
 	callback FireCallback
 	nextID   int
 	running  bool
+	store    Store // optional; nil keeps jobs in memory only
 }
 
 // NewScheduler creates a new scheduler with the given fire callback.
@@ -40,8 +50,52 @@ func NewScheduler(callback FireCallback) *Scheduler {
 	}
 }
 
-// Add schedules a new job. Returns the job ID.
-func (s *Scheduler) Add(name, message string, delay time.Duration, channel, chatID string) string {
+// SetStore loads the jobs kept in st and persists every later change to it.
+// Jobs that came due while picobot was stopped fire on the next tick.
+func (s *Scheduler) SetStore(st Store) error {
+	jobs, err := st.LoadJobs()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, j := range jobs {
+		s.jobs[j.ID] = &j
+		var n int
+		if _, err := fmt.Sscanf(j.ID, "job-%d", &n); err == nil && n > s.nextID {
+			s.nextID = n
+		}
+	}
+	s.store = st
+	if len(jobs) > 0 {
+		log.Printf("cron: restored %d job(s)", len(jobs))
+	}
+	return nil
+}
+
+// save persists j. Callers hold s.mu.
+func (s *Scheduler) save(j *Job) {
+	if s.store == nil || j.fn != nil {
+		return
+	}
+	if err := s.store.SaveJob(*j); err != nil {
+		log.Printf("cron: error storing job %s: %v", j.ID, err)
+	}
+}
+
+// forget removes a job from the store. Callers hold s.mu.
+func (s *Scheduler) forget(id string) {
+	if s.store == nil {
+		return
+	}
+	if err := s.store.DeleteJob(id); err != nil {
+		log.Printf("cron: error deleting stored job %s: %v", id, err)
+	}
+}
+
+// Add schedules a new job for agent, the profile that should answer it (empty
+// if there is only one). Returns the job ID.
+func (s *Scheduler) Add(name, message string, delay time.Duration, channel, chatID, agent string) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.nextID++
@@ -53,13 +107,16 @@ func (s *Scheduler) Add(name, message string, delay time.Duration, channel, chat
 		FireAt:  time.Now().Add(delay),
 		Channel: channel,
 		ChatID:  chatID,
+		Agent:   agent,
 	}
+	s.save(s.jobs[id])
 	log.Printf("cron: scheduled job %q (%s) to fire in %v", name, id, delay)
 	return id
 }
 
-// AddRecurring schedules a recurring job. Returns the job ID.
-func (s *Scheduler) AddRecurring(name, message string, interval time.Duration, channel, chatID string) string {
+// AddRecurring schedules a recurring job for agent (see Add). Returns the job
+// ID.
+func (s *Scheduler) AddRecurring(name, message string, interval time.Duration, channel, chatID, agent string) string {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.nextID++
@@ -71,19 +128,43 @@ func (s *Scheduler) AddRecurring(name, message string, interval time.Duration, c
 		FireAt:    time.Now().Add(interval),
 		Channel:   channel,
 		ChatID:    chatID,
+		Agent:     agent,
 		Recurring: true,
 		Interval:  interval,
 	}
+	s.save(s.jobs[id])
 	log.Printf("cron: scheduled recurring job %q (%s) every %v", name, id, interval)
 	return id
 }
 
+// AddFunc schedules fn to run at first and then every interval (once if
+// interval is zero). Func jobs are internal housekeeping: they run in their
+// own goroutine instead of going through the fire callback, are not persisted,
+// not listed and cannot be cancelled. Returns the job ID.
+func (s *Scheduler) AddFunc(name string, first time.Time, interval time.Duration, fn func()) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := fmt.Sprintf("job-%d", s.nextID)
+	s.jobs[id] = &Job{
+		ID:        id,
+		Name:      name,
+		FireAt:    first,
+		Recurring: interval > 0,
+		Interval:  interval,
+		fn:        fn,
+	}
+	log.Printf("cron: scheduled %q (%s) at %s", name, id, first.Format(time.RFC3339))
+	return id
+}
+
 // Cancel removes a job by ID. Returns true if found.
 func (s *Scheduler) Cancel(id string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	if _, ok := s.jobs[id]; ok {
+	if j, ok := s.jobs[id]; ok && j.fn == nil {
 		delete(s.jobs, id)
+		s.forget(id)
 		log.Printf("cron: cancelled job %s", id)
 		return true
 	}
@@ -95,8 +176,9 @@ func (s *Scheduler) CancelByName(name string) bool {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	for id, j := range s.jobs {
-		if j.Name == name {
+		if j.Name == name && j.fn == nil {
 			delete(s.jobs, id)
+			s.forget(id)
 			log.Printf("cron: cancelled job %q (%s)", name, id)
 			return true
 		}
@@ -104,13 +186,15 @@ func (s *Scheduler) CancelByName(name string) bool {
 	return false
 }
 
-// List returns all pending jobs.
+// List returns all pending jobs except internal func jobs.
 func (s *Scheduler) List() []Job {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	result := make([]Job, 0, len(s.jobs))
 	for _, j := range s.jobs {
-		result = append(result, *j)
+		if j.fn == nil {
+			result = append(result, *j)
+		}
 	}
 	return result
 }
@@ -148,15 +232,22 @@ func (s *Scheduler) tick(now time.Time) {
 	for _, j := range toFire {
 		if j.Recurring {
 			j.FireAt = now.Add(j.Interval)
+			s.save(j)
 		} else {
 			j.fired = true
 			delete(s.jobs, j.ID)
+			s.forget(j.ID)
 		}
 	}
 	s.mu.Unlock()
 
 	// fire callbacks outside lock
 	for _, j := range toFire {
+		if j.fn != nil {
+			log.Printf("cron: running %q (%s)", j.Name, j.ID)
+			go j.fn()
+			continue
+		}
 		log.Printf("cron: firing job %q (%s): %s", j.Name, j.ID, j.Message)
 		if s.callback != nil {
 			s.callback(*j)
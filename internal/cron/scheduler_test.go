@@ -19,7 +19,7 @@ func TestSchedulerFiresJob(t *testing.T) {
 	done := make(chan struct{})
 	go s.Start(done)
 
-	s.Add("test-reminder", "buy cheesecake", 100*time.Millisecond, "telegram", "123")
+	s.Add("test-reminder", "buy cheesecake", 100*time.Millisecond, "telegram", "123", "")
 
 	time.Sleep(2 * time.Second)
 	close(done)
@@ -42,8 +42,8 @@ func TestSchedulerFiresJob(t *testing.T) {
 
 func TestSchedulerList(t *testing.T) {
 	s := NewScheduler(nil)
-	s.Add("job-a", "do A", 5*time.Minute, "telegram", "1")
-	s.Add("job-b", "do B", 10*time.Minute, "telegram", "2")
+	s.Add("job-a", "do A", 5*time.Minute, "telegram", "1", "")
+	s.Add("job-b", "do B", 10*time.Minute, "telegram", "2", "")
 
 	jobs := s.List()
 	if len(jobs) != 2 {
@@ -53,7 +53,7 @@ func TestSchedulerList(t *testing.T) {
 
 func TestSchedulerCancel(t *testing.T) {
 	s := NewScheduler(nil)
-	s.Add("cancel-me", "msg", 5*time.Minute, "telegram", "1")
+	s.Add("cancel-me", "msg", 5*time.Minute, "telegram", "1", "")
 
 	if !s.CancelByName("cancel-me") {
 		t.Error("expected CancelByName to return true")
@@ -76,7 +76,7 @@ func TestSchedulerDoesNotFireCancelled(t *testing.T) {
 	done := make(chan struct{})
 	go s.Start(done)
 
-	s.Add("will-cancel", "nope", 100*time.Millisecond, "telegram", "1")
+	s.Add("will-cancel", "nope", 100*time.Millisecond, "telegram", "1", "")
 	s.CancelByName("will-cancel")
 
 	time.Sleep(300 * time.Millisecond)
@@ -88,3 +88,107 @@ func TestSchedulerDoesNotFireCancelled(t *testing.T) {
 		t.Errorf("expected 0 fired jobs after cancel, got %d", len(fired))
 	}
 }
+
+// mapStore is an in-memory Store for tests.
+type mapStore struct {
+	mu   sync.Mutex
+	jobs map[string]Job
+}
+
+func (m *mapStore) LoadJobs() ([]Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []Job
+	for _, j := range m.jobs {
+		out = append(out, j)
+	}
+	return out, nil
+}
+
+func (m *mapStore) SaveJob(j Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[j.ID] = j
+	return nil
+}
+
+func (m *mapStore) DeleteJob(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.jobs, id)
+	return nil
+}
+
+func TestSchedulerStoreRestoresAndPersistsJobs(t *testing.T) {
+	st := &mapStore{jobs: map[string]Job{
+		"job-7": {ID: "job-7", Name: "overdue", Message: "water plants", FireAt: time.Now().Add(-time.Minute), Channel: "telegram", ChatID: "1"},
+	}}
+
+	var fired []Job
+	s := NewScheduler(func(job Job) { fired = append(fired, job) })
+	if err := s.SetStore(st); err != nil {
+		t.Fatalf("SetStore: %v", err)
+	}
+
+	// new IDs continue after the restored ones
+	if id := s.Add("later", "stretch", time.Hour, "telegram", "1", ""); id != "job-8" {
+		t.Fatalf("expected job-8, got %s", id)
+	}
+	if _, ok := st.jobs["job-8"]; !ok {
+		t.Fatal("expected the new job to be stored")
+	}
+
+	s.tick(time.Now())
+	if len(fired) != 1 || fired[0].Message != "water plants" {
+		t.Fatalf("expected the overdue job to fire, got %v", fired)
+	}
+	if _, ok := st.jobs["job-7"]; ok {
+		t.Fatal("expected the fired job to be removed from the store")
+	}
+
+	s.Cancel("job-8")
+	if len(st.jobs) != 0 {
+		t.Fatalf("expected an empty store, got %v", st.jobs)
+	}
+}
+
+func TestSchedulerAddFunc(t *testing.T) {
+	st := &mapStore{jobs: map[string]Job{}}
+	var fired []Job
+	s := NewScheduler(func(job Job) { fired = append(fired, job) })
+	s.SetStore(st)
+
+	ran := make(chan struct{}, 2)
+	now := time.Now()
+	s.AddFunc("consolidate", now.Add(-time.Second), time.Hour, func() { ran <- struct{}{} })
+	if len(st.jobs) != 0 || len(s.List()) != 0 {
+		t.Fatalf("expected func jobs to be neither stored nor listed, got store=%v list=%v", st.jobs, s.List())
+	}
+	if s.CancelByName("consolidate") {
+		t.Fatal("expected func jobs not to be cancellable")
+	}
+
+	s.tick(now)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("expected the func to run")
+	}
+	if len(fired) != 0 {
+		t.Fatalf("expected the fire callback not to be called, got %v", fired)
+	}
+
+	// rescheduled an interval later
+	s.tick(now.Add(30 * time.Minute))
+	s.tick(now.Add(61 * time.Minute))
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("expected the func to run again after the interval")
+	}
+	select {
+	case <-ran:
+		t.Fatal("expected the func to run only once per interval")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,100 @@
+// Package media loads images referenced by inbound chat messages so they can
+// be passed to vision models as inline data.
+package media
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// MaxImageBytes is the largest image that is forwarded to a provider. It
+// matches the strictest common provider limit (Anthropic's 5 MB per image).
+const MaxImageBytes = 5 << 20
+
+// IsImage reports whether mimeType is an image type.
+func IsImage(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(mimeType)), "image/")
+}
+
+// DataURI encodes data as a base64 data: URI.
+func DataURI(mimeType string, data []byte) string {
+	return "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(data)
+}
+
+// Load resolves ref, either a data: URI or an http(s) URL, to a data: URI
+// holding an image no larger than maxBytes. Remote images are downloaded so
+// that URLs carrying credentials (e.g. Telegram file links) never reach the
+// provider.
+func Load(ctx context.Context, client *http.Client, ref string, maxBytes int64) (string, error) {
+	if strings.HasPrefix(ref, "data:") {
+		return checkDataURI(ref, maxBytes)
+	}
+	if !strings.HasPrefix(ref, "http://") && !strings.HasPrefix(ref, "https://") {
+		return "", fmt.Errorf("unsupported media reference")
+	}
+	data, mimeType, err := Fetch(ctx, client, ref, maxBytes)
+	if err != nil {
+		return "", err
+	}
+	if !IsImage(mimeType) {
+		// servers often send application/octet-stream; trust the bytes instead
+		mimeType = http.DetectContentType(data)
+	}
+	if !IsImage(mimeType) {
+		return "", fmt.Errorf("not an image (%s)", mimeType)
+	}
+	return DataURI(mimeType, data), nil
+}
+
+// Fetch downloads url, failing if the body exceeds maxBytes. It returns the
+// data and the media type from the Content-Type header (which may be empty).
+func Fetch(ctx context.Context, client *http.Client, url string, maxBytes int64) ([]byte, string, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		// the URL may embed a token; report only the failure
+		return nil, "", fmt.Errorf("media download failed")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, "", fmt.Errorf("media download failed: %s", resp.Status)
+	}
+	if resp.ContentLength > maxBytes {
+		return nil, "", fmt.Errorf("media too large (%d bytes, limit %d)", resp.ContentLength, maxBytes)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(data)) > maxBytes {
+		return nil, "", fmt.Errorf("media too large (limit %d bytes)", maxBytes)
+	}
+	mimeType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	return data, mimeType, nil
+}
+
+// checkDataURI validates that a data: URI is a base64 image within maxBytes.
+func checkDataURI(ref string, maxBytes int64) (string, error) {
+	meta, data, ok := strings.Cut(strings.TrimPrefix(ref, "data:"), ",")
+	mimeType, isBase64 := strings.CutSuffix(meta, ";base64")
+	if !ok || !isBase64 {
+		return "", fmt.Errorf("malformed data URI")
+	}
+	if !IsImage(mimeType) {
+		return "", fmt.Errorf("not an image (%s)", mimeType)
+	}
+	if int64(base64.StdEncoding.DecodedLen(len(data))) > maxBytes+2 {
+		return "", fmt.Errorf("image too large (limit %d bytes)", maxBytes)
+	}
+	return ref, nil
+}
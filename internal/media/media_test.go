@@ -0,0 +1,61 @@
+package media
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// pngHeader is enough for http.DetectContentType to recognise a PNG.
+var pngHeader = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+
+func TestLoadDownloadsImage(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(pngHeader)
+	}))
+	defer h.Close()
+
+	uri, err := Load(context.Background(), nil, h.URL+"/photo", MaxImageBytes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(uri, "data:image/png;base64,") {
+		t.Fatalf("expected PNG data URI, got %q", uri)
+	}
+}
+
+func TestLoadRejectsLargeAndNonImage(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/big" {
+			w.Write(append(pngHeader, make([]byte, 100)...))
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html></html>"))
+	}))
+	defer h.Close()
+
+	if _, err := Load(context.Background(), nil, h.URL+"/big", 50); err == nil || !strings.Contains(err.Error(), "too large") {
+		t.Fatalf("expected size error, got %v", err)
+	}
+	if _, err := Load(context.Background(), nil, h.URL+"/page", MaxImageBytes); err == nil {
+		t.Fatalf("expected error for non-image content")
+	}
+}
+
+func TestLoadDataURI(t *testing.T) {
+	uri := DataURI("image/jpeg", []byte("jpegbytes"))
+	got, err := Load(context.Background(), nil, uri, MaxImageBytes)
+	if err != nil || got != uri {
+		t.Fatalf("expected data URI to pass through, got %q, %v", got, err)
+	}
+	if _, err := Load(context.Background(), nil, DataURI("application/pdf", []byte("x")), MaxImageBytes); err == nil {
+		t.Fatalf("expected error for non-image data URI")
+	}
+	if _, err := Load(context.Background(), nil, "file:///etc/passwd", MaxImageBytes); err == nil {
+		t.Fatalf("expected error for unsupported scheme")
+	}
+}
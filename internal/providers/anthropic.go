@@ -0,0 +1,260 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// anthropicVersion is the Messages API version header sent with every request.
+const anthropicVersion = "2023-06-01"
+
+// anthropicDefaultMaxTokens is used when no maxTokens is configured, because
+// the Messages API (unlike OpenAI) requires max_tokens on every request.
+const anthropicDefaultMaxTokens = 4096
+
+// AnthropicProvider calls the native Anthropic Messages API.
+type AnthropicProvider struct {
+	APIKey    string
+	APIBase   string // e.g. https://api.anthropic.com/v1
+	MaxTokens int    // 0 means anthropicDefaultMaxTokens
+	Client    *http.Client
+}
+
+func NewAnthropicProvider(apiKey, apiBase string, timeoutSecs, maxTokens int) *AnthropicProvider {
+	if apiBase == "" {
+		apiBase = "https://api.anthropic.com/v1"
+	}
+	if timeoutSecs <= 1 {
+		timeoutSecs = 60 // default 60 seconds
+	}
+	return &AnthropicProvider{
+		APIKey:    apiKey,
+		APIBase:   strings.TrimRight(apiBase, "/"),
+		MaxTokens: maxTokens,
+		Client: &http.Client{
+			Timeout: time.Duration(timeoutSecs) * time.Second,
+		},
+	}
+}
+
+func (p *AnthropicProvider) GetDefaultModel() string { return "claude-sonnet-4-5" }
+
+// Request/response shapes for the Messages API.
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	System    string             `json:"system,omitempty"`
+	Messages  []anthropicMessage `json:"messages"`
+	Tools     []anthropicTool    `json:"tools,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string           `json:"role"` // "user" | "assistant"
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicBlock is a content block. Only the fields relevant to Type are set.
+type anthropicBlock struct {
+	Type string `json:"type"` // "text" | "image" | "tool_use" | "tool_result"
+
+	// text
+	Text string `json:"text,omitempty"`
+
+	// image
+	Source *anthropicImageSource `json:"source,omitempty"`
+
+	// tool_use
+	// Input is kept raw so that a call without arguments is sent as {}: the
+	// API rejects tool_use blocks without input.
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+
+	// tool_result
+	ToolUseID string `json:"tool_use_id,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+// anthropicImageSource is either inline base64 data or a URL.
+type anthropicImageSource struct {
+	Type      string `json:"type"` // "base64" | "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// toAnthropicImage converts a data: URI or http(s) URL into an image source.
+func toAnthropicImage(ref string) *anthropicImageSource {
+	if rest, ok := strings.CutPrefix(ref, "data:"); ok {
+		meta, data, found := strings.Cut(rest, ",")
+		mediaType, isBase64 := strings.CutSuffix(meta, ";base64")
+		if found && isBase64 {
+			return &anthropicImageSource{Type: "base64", MediaType: mediaType, Data: data}
+		}
+		return nil
+	}
+	return &anthropicImageSource{Type: "url", URL: ref}
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	Model      string           `json:"model"`
+	Content    []anthropicBlock `json:"content"`
+	StopReason string           `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// toAnthropicMessages converts provider messages to the Messages API shape.
+// System messages are collected into a single top-level system string, tool
+// results become tool_result blocks on a user turn, and consecutive messages
+// with the same role are merged because the API requires strict alternation.
+func toAnthropicMessages(messages []Message) (string, []anthropicMessage) {
+	var system []string
+	out := make([]anthropicMessage, 0, len(messages))
+
+	appendBlocks := func(role string, blocks []anthropicBlock) {
+		if len(blocks) == 0 {
+			return
+		}
+		if n := len(out); n > 0 && out[n-1].Role == role {
+			out[n-1].Content = append(out[n-1].Content, blocks...)
+			return
+		}
+		out = append(out, anthropicMessage{Role: role, Content: blocks})
+	}
+
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			if s := strings.TrimSpace(m.Content); s != "" {
+				system = append(system, s)
+			}
+		case "assistant":
+			var blocks []anthropicBlock
+			if m.Content != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				input, err := json.Marshal(tc.Arguments)
+				if err != nil || tc.Arguments == nil {
+					input = json.RawMessage("{}")
+				}
+				blocks = append(blocks, anthropicBlock{Type: "tool_use", ID: tc.ID, Name: tc.Name, Input: input})
+			}
+			appendBlocks("assistant", blocks)
+		case "tool":
+			appendBlocks("user", []anthropicBlock{{Type: "tool_result", ToolUseID: m.ToolCallID, Content: m.Content}})
+		default:
+			if len(m.Parts) > 0 {
+				var blocks []anthropicBlock
+				for _, part := range m.Parts {
+					switch part.Type {
+					case "text":
+						if part.Text != "" {
+							blocks = append(blocks, anthropicBlock{Type: "text", Text: part.Text})
+						}
+					case "image":
+						if src := toAnthropicImage(part.ImageURL); src != nil {
+							blocks = append(blocks, anthropicBlock{Type: "image", Source: src})
+						}
+					}
+				}
+				appendBlocks("user", blocks)
+			} else if m.Content != "" {
+				appendBlocks("user", []anthropicBlock{{Type: "text", Text: m.Content}})
+			}
+		}
+	}
+	return strings.Join(system, "\n\n"), out
+}
+
+// Chat calls the Anthropic Messages endpoint and returns a normalized response.
+func (p *AnthropicProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	if model == "" {
+		model = p.GetDefaultModel()
+	}
+	maxTokens := p.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = anthropicDefaultMaxTokens
+	}
+
+	system, msgs := toAnthropicMessages(messages)
+	reqBody := anthropicRequest{Model: model, MaxTokens: maxTokens, System: system, Messages: msgs}
+	for _, t := range tools {
+		schema := t.Parameters
+		if schema == nil {
+			schema = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+		}
+		reqBody.Tools = append(reqBody.Tools, anthropicTool{Name: t.Name, Description: t.Description, InputSchema: schema})
+	}
+
+	b, err := json.Marshal(reqBody)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	url := fmt.Sprintf("%s/messages", p.APIBase)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(b)))
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("anthropic-version", anthropicVersion)
+	if p.APIKey != "" {
+		req.Header.Set("x-api-key", p.APIKey)
+	}
+
+	resp, err := p.Client.Do(req)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return LLMResponse{}, newAPIError("Anthropic", resp)
+	}
+
+	var out anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return LLMResponse{}, err
+	}
+	if len(out.Content) == 0 && out.StopReason == "" {
+		return LLMResponse{}, errors.New("Anthropic API returned no content")
+	}
+
+	var text []string
+	var tcs []ToolCall
+	for _, block := range out.Content {
+		switch block.Type {
+		case "text":
+			text = append(text, block.Text)
+		case "tool_use":
+			args := map[string]interface{}{}
+			if len(block.Input) > 0 {
+				if err := json.Unmarshal(block.Input, &args); err != nil {
+					return LLMResponse{}, fmt.Errorf("Anthropic API returned invalid input for tool %s: %w", block.Name, err)
+				}
+			}
+			tcs = append(tcs, ToolCall{ID: block.ID, Name: block.Name, Arguments: args})
+		}
+	}
+	content := strings.TrimSpace(strings.Join(text, ""))
+	usage := Usage{PromptTokens: out.Usage.InputTokens, CompletionTokens: out.Usage.OutputTokens, Model: out.Model}
+	if len(tcs) > 0 {
+		return LLMResponse{Content: content, HasToolCalls: true, ToolCalls: tcs, Usage: usage}, nil
+	}
+	return LLMResponse{Content: content, HasToolCalls: false, Usage: usage}, nil
+}
@@ -0,0 +1,153 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAnthropicToolUseParsing(t *testing.T) {
+	var got anthropicRequest
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/messages" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("expected x-api-key header, got %q", r.Header.Get("x-api-key"))
+		}
+		if r.Header.Get("anthropic-version") == "" {
+			t.Errorf("expected anthropic-version header")
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(200)
+		w.Write([]byte(`{
+		  "content": [
+		    {"type": "text", "text": "Let me send that."},
+		    {"type": "tool_use", "id": "toolu_01", "name": "message", "input": {"content": "Hello from tool"}}
+		  ],
+		  "stop_reason": "tool_use"
+		}`))
+	}))
+	defer h.Close()
+
+	p := NewAnthropicProvider("test-key", h.URL, 60, 0)
+	p.Client = &http.Client{Timeout: 5 * time.Second}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	msgs := []Message{
+		{Role: "system", Content: "You are a test."},
+		{Role: "user", Content: "trigger"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "toolu_00", Name: "web", Arguments: map[string]interface{}{"url": "http://x"}}}},
+		{Role: "tool", ToolCallID: "toolu_00", Content: "page body"},
+	}
+	tools := []ToolDefinition{{Name: "message", Description: "send"}}
+	resp, err := p.Chat(ctx, msgs, tools, "claude-test")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// request mapping
+	if got.System != "You are a test." {
+		t.Fatalf("expected system prompt in top-level field, got %q", got.System)
+	}
+	if got.MaxTokens != anthropicDefaultMaxTokens {
+		t.Fatalf("expected default max_tokens, got %d", got.MaxTokens)
+	}
+	if len(got.Messages) != 3 {
+		t.Fatalf("expected 3 messages (user, assistant, user), got %d", len(got.Messages))
+	}
+	if b := got.Messages[1].Content[0]; b.Type != "tool_use" || b.ID != "toolu_00" || string(b.Input) != `{"url":"http://x"}` {
+		t.Fatalf("unexpected tool_use block: %+v", b)
+	}
+	if b := got.Messages[2]; b.Role != "user" || b.Content[0].Type != "tool_result" || b.Content[0].ToolUseID != "toolu_00" {
+		t.Fatalf("unexpected tool_result message: %+v", b)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].InputSchema == nil {
+		t.Fatalf("expected tool with input_schema, got %+v", got.Tools)
+	}
+
+	// response mapping
+	if !resp.HasToolCalls || len(resp.ToolCalls) != 1 {
+		t.Fatalf("expected one tool call, got: has=%v len=%d", resp.HasToolCalls, len(resp.ToolCalls))
+	}
+	if resp.ToolCalls[0].Name != "message" || resp.ToolCalls[0].Arguments["content"] != "Hello from tool" {
+		t.Fatalf("unexpected tool call: %+v", resp.ToolCalls[0])
+	}
+	if resp.Content != "Let me send that." {
+		t.Fatalf("unexpected content: %q", resp.Content)
+	}
+}
+
+func TestAnthropicMergesConsecutiveToolResults(t *testing.T) {
+	_, msgs := toAnthropicMessages([]Message{
+		{Role: "user", Content: "do two things"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "a", Name: "x"}, {ID: "b", Name: "y"}}},
+		{Role: "tool", ToolCallID: "a", Content: "1"},
+		{Role: "tool", ToolCallID: "b", Content: "2"},
+		{Role: "user", Content: "thanks"},
+	})
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 alternating messages, got %d: %+v", len(msgs), msgs)
+	}
+	if n := len(msgs[2].Content); n != 3 {
+		t.Fatalf("expected both tool results and the user text in one turn, got %d blocks", n)
+	}
+}
+
+func TestAnthropicErrorStatus(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`))
+	}))
+	defer h.Close()
+
+	p := NewAnthropicProvider("bad", h.URL, 5, 0)
+	_, err := p.Chat(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "")
+	if err == nil {
+		t.Fatalf("expected error for 401 response")
+	}
+}
+
+func TestAnthropicImageParts(t *testing.T) {
+	_, msgs := toAnthropicMessages([]Message{{
+		Role: "user",
+		Parts: []ContentPart{
+			{Type: "text", Text: "what is this?"},
+			{Type: "image", ImageURL: "data:image/jpeg;base64,/9j/AA=="},
+			{Type: "image", ImageURL: "https://example.com/cat.png"},
+		},
+	}})
+	if len(msgs) != 1 || len(msgs[0].Content) != 3 {
+		t.Fatalf("expected one user turn with 3 blocks, got %+v", msgs)
+	}
+	if src := msgs[0].Content[1].Source; src == nil || src.Type != "base64" || src.MediaType != "image/jpeg" || src.Data != "/9j/AA==" {
+		t.Fatalf("unexpected base64 image block: %+v", msgs[0].Content[1])
+	}
+	if src := msgs[0].Content[2].Source; src == nil || src.Type != "url" || src.URL != "https://example.com/cat.png" {
+		t.Fatalf("unexpected url image block: %+v", msgs[0].Content[2])
+	}
+}
+
+func TestAnthropicToolCallWithoutArguments(t *testing.T) {
+	_, msgs := toAnthropicMessages([]Message{
+		{Role: "user", Content: "what time is it?"},
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "a", Name: "clock"}}},
+		{Role: "tool", ToolCallID: "a", Content: "12:00"},
+	})
+	data, err := json.Marshal(msgs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `{"type":"tool_use","id":"a","name":"clock","input":{}}`) {
+		t.Fatalf("expected the tool_use block to carry an empty input, got %s", data)
+	}
+}
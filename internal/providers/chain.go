@@ -0,0 +1,232 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Defaults used by NewChainProvider.
+const (
+	defaultChainMaxAttempts      = 3
+	defaultChainInitialBackoff   = 1 * time.Second
+	defaultChainMaxBackoff       = 30 * time.Second
+	defaultChainBreakerThreshold = 3
+	defaultChainBreakerCooldown  = 60 * time.Second
+)
+
+// ChainEntry is one backend of a ChainProvider.
+type ChainEntry struct {
+	Name     string // used in logs; defaults to the provider type
+	Provider LLMProvider
+	Model    string // overrides the requested model when set
+}
+
+// ChainProvider tries an ordered list of backends. Transient failures (see
+// IsRetryable) are retried with exponential backoff, honouring Retry-After,
+// before the chain fails over to the next backend. Each backend has a circuit
+// breaker that skips it for a cooldown period after repeated failures.
+type ChainProvider struct {
+	MaxAttempts      int           // attempts per backend, including the first
+	InitialBackoff   time.Duration // delay before the first retry; doubled after each
+	MaxBackoff       time.Duration // cap on the backoff and on an acceptable Retry-After
+	BreakerThreshold int           // consecutive failures that open a backend's circuit
+	BreakerCooldown  time.Duration // how long an open circuit skips the backend
+
+	backends []*chainBackend
+	now      func() time.Time
+	sleep    func(ctx context.Context, d time.Duration) error
+}
+
+// chainBackend holds a ChainEntry together with its circuit-breaker state.
+type chainBackend struct {
+	ChainEntry
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// NewChainProvider creates a ChainProvider over entries, tried in order.
+func NewChainProvider(entries ...ChainEntry) *ChainProvider {
+	c := &ChainProvider{
+		MaxAttempts:      defaultChainMaxAttempts,
+		InitialBackoff:   defaultChainInitialBackoff,
+		MaxBackoff:       defaultChainMaxBackoff,
+		BreakerThreshold: defaultChainBreakerThreshold,
+		BreakerCooldown:  defaultChainBreakerCooldown,
+		now:              time.Now,
+		sleep:            sleepContext,
+	}
+	for _, e := range entries {
+		if e.Name == "" {
+			e.Name = strings.TrimPrefix(fmt.Sprintf("%T", e.Provider), "*providers.")
+		}
+		c.backends = append(c.backends, &chainBackend{ChainEntry: e})
+	}
+	return c
+}
+
+// GetDefaultModel returns the default model of the first backend.
+func (c *ChainProvider) GetDefaultModel() string {
+	if len(c.backends) == 0 {
+		return ""
+	}
+	return c.backends[0].Provider.GetDefaultModel()
+}
+
+// Chat sends the request to the first healthy backend, retrying and failing
+// over as needed.
+func (c *ChainProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	return c.run(ctx, model, func(b *chainBackend, model string) (LLMResponse, bool, error) {
+		resp, err := b.Provider.Chat(ctx, messages, tools, model)
+		return resp, false, err
+	})
+}
+
+// ChatStream behaves like Chat but streams deltas from backends that support
+// it. A backend is only retried or failed over while it has not emitted any
+// text yet; once output has reached the user the error is returned as is.
+// Backends without streaming support deliver their reply as a single delta.
+func (c *ChainProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta func(delta string)) (LLMResponse, error) {
+	return c.run(ctx, model, func(b *chainBackend, model string) (LLMResponse, bool, error) {
+		sp, ok := b.Provider.(StreamingProvider)
+		if !ok {
+			resp, err := b.Provider.Chat(ctx, messages, tools, model)
+			if err == nil && resp.Content != "" && onDelta != nil {
+				onDelta(resp.Content)
+			}
+			return resp, false, err
+		}
+		emitted := false
+		resp, err := sp.ChatStream(ctx, messages, tools, model, func(delta string) {
+			emitted = true
+			if onDelta != nil {
+				onDelta(delta)
+			}
+		})
+		return resp, emitted, err
+	})
+}
+
+// run drives the retry/failover loop. call reports whether the backend had
+// already produced output, in which case its error is final.
+func (c *ChainProvider) run(ctx context.Context, model string, call func(b *chainBackend, model string) (LLMResponse, bool, error)) (LLMResponse, error) {
+	if len(c.backends) == 0 {
+		return LLMResponse{}, errors.New("provider chain is empty")
+	}
+	var errs []error
+	for _, b := range c.backends {
+		if !b.available(c.now()) {
+			errs = append(errs, fmt.Errorf("%s: circuit open", b.Name))
+			continue
+		}
+		m := model
+		if b.Model != "" {
+			m = b.Model
+		}
+
+		var err error
+		for attempt := 0; attempt < c.maxAttempts(); attempt++ {
+			var resp LLMResponse
+			var emitted bool
+			resp, emitted, err = call(b, m)
+			if err == nil {
+				b.recordSuccess()
+				return resp, nil
+			}
+			if ctx.Err() != nil || emitted {
+				return LLMResponse{}, err
+			}
+			if !IsRetryable(err) || attempt == c.maxAttempts()-1 {
+				break
+			}
+			delay, ok := c.backoff(attempt, err)
+			if !ok {
+				break
+			}
+			log.Printf("provider chain: %s failed (%v), retrying in %s", b.Name, err, delay)
+			if serr := c.sleep(ctx, delay); serr != nil {
+				return LLMResponse{}, err
+			}
+		}
+
+		if IsRetryable(err) && b.recordFailure(c.now(), c.BreakerThreshold, c.BreakerCooldown) {
+			log.Printf("provider chain: circuit opened for %s for %s", b.Name, c.BreakerCooldown)
+		}
+		log.Printf("provider chain: %s failed: %v", b.Name, err)
+		errs = append(errs, fmt.Errorf("%s: %w", b.Name, err))
+	}
+	if len(errs) == 1 {
+		return LLMResponse{}, errs[0]
+	}
+	return LLMResponse{}, fmt.Errorf("all providers failed: %w", errors.Join(errs...))
+}
+
+func (c *ChainProvider) maxAttempts() int {
+	if c.MaxAttempts <= 0 {
+		return 1
+	}
+	return c.MaxAttempts
+}
+
+// backoff returns the delay before retry number attempt+1. A Retry-After hint
+// from the server takes precedence; if it exceeds MaxBackoff the backend is
+// not worth waiting for and ok is false so the chain fails over instead.
+func (c *ChainProvider) backoff(attempt int, err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		if c.MaxBackoff > 0 && apiErr.RetryAfter > c.MaxBackoff {
+			return 0, false
+		}
+		return apiErr.RetryAfter, true
+	}
+	d := c.InitialBackoff << attempt
+	if c.MaxBackoff > 0 && (d > c.MaxBackoff || d <= 0) {
+		d = c.MaxBackoff
+	}
+	return d, true
+}
+
+// available reports whether the backend's circuit is closed, or its cooldown
+// has elapsed so that a trial request may go through (half-open).
+func (b *chainBackend) available(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !now.Before(b.openUntil)
+}
+
+func (b *chainBackend) recordSuccess() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// recordFailure counts a failed request and opens the circuit once threshold
+// consecutive failures have been seen. It reports whether the circuit opened.
+func (b *chainBackend) recordFailure(now time.Time, threshold int, cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if threshold <= 0 || b.failures < threshold {
+		return false
+	}
+	b.openUntil = now.Add(cooldown)
+	return true
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
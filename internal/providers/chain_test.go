@@ -0,0 +1,175 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// scriptedProvider returns the queued errors in order, then succeeds.
+type scriptedProvider struct {
+	errs  []error
+	calls int
+}
+
+func (s *scriptedProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	s.calls++
+	if len(s.errs) > 0 {
+		err := s.errs[0]
+		s.errs = s.errs[1:]
+		return LLMResponse{}, err
+	}
+	return LLMResponse{Content: "ok from " + model}, nil
+}
+
+func (s *scriptedProvider) GetDefaultModel() string { return "scripted" }
+
+// newTestChain returns a chain whose sleeps are recorded instead of waited on.
+func newTestChain(slept *[]time.Duration, entries ...ChainEntry) *ChainProvider {
+	c := NewChainProvider(entries...)
+	c.sleep = func(ctx context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return nil
+	}
+	return c
+}
+
+func TestChainRetriesWithBackoff(t *testing.T) {
+	p := &scriptedProvider{errs: []error{
+		&APIError{Provider: "OpenAI", StatusCode: 503, Status: "503 Service Unavailable"},
+		&APIError{Provider: "OpenAI", StatusCode: 502, Status: "502 Bad Gateway"},
+	}}
+	var slept []time.Duration
+	c := newTestChain(&slept, ChainEntry{Provider: p})
+
+	resp, err := c.Chat(context.Background(), nil, nil, "m")
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if resp.Content != "ok from m" || p.calls != 3 {
+		t.Fatalf("unexpected result %q after %d calls", resp.Content, p.calls)
+	}
+	if len(slept) != 2 || slept[0] != c.InitialBackoff || slept[1] != 2*c.InitialBackoff {
+		t.Fatalf("expected exponential backoff, got %v", slept)
+	}
+}
+
+func TestChainHonoursRetryAfter(t *testing.T) {
+	p := &scriptedProvider{errs: []error{
+		&APIError{Provider: "OpenAI", StatusCode: 429, Status: "429 Too Many Requests", RetryAfter: 7 * time.Second},
+	}}
+	var slept []time.Duration
+	c := newTestChain(&slept, ChainEntry{Provider: p})
+
+	if _, err := c.Chat(context.Background(), nil, nil, "m"); err != nil {
+		t.Fatalf("expected success, got %v", err)
+	}
+	if len(slept) != 1 || slept[0] != 7*time.Second {
+		t.Fatalf("expected Retry-After delay of 7s, got %v", slept)
+	}
+}
+
+func TestChainFailsOverWithModelOverride(t *testing.T) {
+	primary := &scriptedProvider{errs: []error{
+		&APIError{Provider: "OpenAI", StatusCode: 401, Status: "401 Unauthorized"},
+	}}
+	backup := &scriptedProvider{}
+	var slept []time.Duration
+	c := newTestChain(&slept, ChainEntry{Provider: primary}, ChainEntry{Provider: backup, Model: "llama3"})
+
+	resp, err := c.Chat(context.Background(), nil, nil, "gpt")
+	if err != nil {
+		t.Fatalf("expected failover success, got %v", err)
+	}
+	if primary.calls != 1 {
+		t.Fatalf("non-retryable errors should not be retried, got %d calls", primary.calls)
+	}
+	if resp.Content != "ok from llama3" {
+		t.Fatalf("expected fallback model to be used, got %q", resp.Content)
+	}
+}
+
+func TestChainCircuitBreaker(t *testing.T) {
+	down := &APIError{Provider: "OpenAI", StatusCode: 500, Status: "500 Internal Server Error"}
+	primary := &scriptedProvider{errs: []error{down, down, down, down}}
+	backup := &scriptedProvider{}
+	var slept []time.Duration
+	c := newTestChain(&slept, ChainEntry{Provider: primary}, ChainEntry{Provider: backup})
+	c.MaxAttempts = 1
+	c.BreakerThreshold = 2
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.Chat(context.Background(), nil, nil, "m"); err != nil {
+			t.Fatalf("call %d: expected failover success, got %v", i, err)
+		}
+	}
+	if primary.calls != 2 {
+		t.Fatalf("expected 2 primary calls before the circuit opened, got %d", primary.calls)
+	}
+
+	// circuit is open: the primary is skipped entirely
+	if _, err := c.Chat(context.Background(), nil, nil, "m"); err != nil {
+		t.Fatalf("expected backup to answer, got %v", err)
+	}
+	if primary.calls != 2 {
+		t.Fatalf("expected open circuit to skip primary, got %d calls", primary.calls)
+	}
+
+	// after the cooldown a trial request goes through again
+	now = now.Add(c.BreakerCooldown)
+	c.Chat(context.Background(), nil, nil, "m")
+	if primary.calls != 3 {
+		t.Fatalf("expected half-open trial call, got %d calls", primary.calls)
+	}
+}
+
+// brokenStreamProvider emits one delta and then fails with a retryable error.
+type brokenStreamProvider struct{ scriptedProvider }
+
+func (b *brokenStreamProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta func(string)) (LLMResponse, error) {
+	b.calls++
+	onDelta("Hi")
+	return LLMResponse{}, &APIError{Provider: "OpenAI", StatusCode: 502, Status: "502 Bad Gateway"}
+}
+
+func TestChainStreamDoesNotRetryAfterOutput(t *testing.T) {
+	primary := &brokenStreamProvider{}
+	backup := &scriptedProvider{}
+	var slept []time.Duration
+	c := newTestChain(&slept, ChainEntry{Provider: primary}, ChainEntry{Provider: backup})
+
+	var got string
+	_, err := c.ChatStream(context.Background(), nil, nil, "m", func(d string) { got += d })
+	if err == nil {
+		t.Fatalf("expected the mid-stream error to be returned")
+	}
+	if got != "Hi" || primary.calls != 1 || backup.calls != 0 {
+		t.Fatalf("expected no retry or failover after output, got %q, %d primary and %d backup calls", got, primary.calls, backup.calls)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	if d := parseRetryAfter("12", now); d != 12*time.Second {
+		t.Fatalf("expected 12s, got %s", d)
+	}
+	if d := parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now); d != 30*time.Second {
+		t.Fatalf("expected 30s from HTTP date, got %s", d)
+	}
+	if d := parseRetryAfter("soon", now); d != 0 {
+		t.Fatalf("expected 0 for invalid value, got %s", d)
+	}
+}
+
+func TestIsRetryable(t *testing.T) {
+	if !IsRetryable(&APIError{StatusCode: 429}) || !IsRetryable(&APIError{StatusCode: 503}) {
+		t.Fatalf("429 and 5xx should be retryable")
+	}
+	if IsRetryable(&APIError{StatusCode: 400}) || IsRetryable(context.Canceled) || IsRetryable(errors.New("boom")) {
+		t.Fatalf("4xx, cancellation and plain errors should not be retryable")
+	}
+}
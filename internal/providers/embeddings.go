@@ -0,0 +1,142 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/local/picobot/internal/config"
+)
+
+// Embedder turns texts into vectors for semantic search.
+type Embedder interface {
+	// Embed returns one vector per text, in order.
+	Embed(ctx context.Context, texts []string) ([][]float32, error)
+	// Model names the embedding model; vectors from different models are not
+	// comparable.
+	Model() string
+}
+
+// DefaultEmbeddingModel is used when embeddings.model is not configured.
+const DefaultEmbeddingModel = "text-embedding-3-small"
+
+// maxEmbedBatch caps the inputs sent in one /embeddings request.
+const maxEmbedBatch = 96
+
+// OpenAIEmbedder calls an OpenAI-compatible /embeddings endpoint.
+type OpenAIEmbedder struct {
+	APIKey  string
+	APIBase string
+	model   string
+	Client  *http.Client
+}
+
+// NewOpenAIEmbedder returns an embedder for apiBase (default
+// https://api.openai.com/v1) and model (default DefaultEmbeddingModel).
+func NewOpenAIEmbedder(apiKey, apiBase, model string, timeoutSecs int) *OpenAIEmbedder {
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	if timeoutSecs <= 1 {
+		timeoutSecs = 30
+	}
+	return &OpenAIEmbedder{
+		APIKey:  apiKey,
+		APIBase: strings.TrimRight(apiBase, "/"),
+		model:   model,
+		Client:  &http.Client{Timeout: time.Duration(timeoutSecs) * time.Second},
+	}
+}
+
+// NewEmbedderFromConfig returns the embedder selected by cfg.Embeddings, or
+// nil when embeddings are disabled.
+func NewEmbedderFromConfig(cfg config.Config) Embedder {
+	ec := cfg.Embeddings
+	switch ec.Provider {
+	case "":
+		return nil
+	case "openai":
+		apiKey, apiBase := ec.APIKey, ec.APIBase
+		if o := cfg.Providers.OpenAI; o != nil {
+			if apiKey == "" {
+				apiKey = o.APIKey
+			}
+			if apiBase == "" {
+				apiBase = o.APIBase
+			}
+		}
+		return NewOpenAIEmbedder(apiKey, apiBase, ec.Model, ec.TimeoutS)
+	default:
+		log.Printf("embeddings: unknown provider %q; disabled", ec.Provider)
+		return nil
+	}
+}
+
+// Model returns the embedding model name.
+func (e *OpenAIEmbedder) Model() string { return e.model }
+
+// Embed requests vectors in batches of maxEmbedBatch.
+func (e *OpenAIEmbedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	out := make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += maxEmbedBatch {
+		end := min(start+maxEmbedBatch, len(texts))
+		vecs, err := e.embedBatch(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+func (e *OpenAIEmbedder) embedBatch(ctx context.Context, texts []string) ([][]float32, error) {
+	body, err := json.Marshal(map[string]interface{}{"model": e.model, "input": texts})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.APIBase+"/embeddings", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+e.APIKey)
+	}
+	resp, err := e.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, newAPIError("OpenAI embeddings", resp)
+	}
+	defer resp.Body.Close()
+
+	var er struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&er); err != nil {
+		return nil, fmt.Errorf("embeddings: invalid response: %w", err)
+	}
+	if len(er.Data) != len(texts) {
+		return nil, fmt.Errorf("embeddings: got %d vectors for %d inputs", len(er.Data), len(texts))
+	}
+	vecs := make([][]float32, len(texts))
+	for _, d := range er.Data {
+		if d.Index < 0 || d.Index >= len(vecs) {
+			return nil, fmt.Errorf("embeddings: response index %d out of range", d.Index)
+		}
+		vecs[d.Index] = d.Embedding
+	}
+	return vecs, nil
+}
@@ -0,0 +1,85 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/local/picobot/internal/config"
+)
+
+func TestOpenAIEmbedder(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path != "/v1/embeddings" || r.Header.Get("Authorization") != "Bearer k" {
+			t.Errorf("unexpected request %s auth=%q", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		var req struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Model != "m" {
+			t.Errorf("expected model m, got %q", req.Model)
+		}
+		// answer out of order; the embedder must sort by index
+		type item struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		}
+		var data []item
+		for i := len(req.Input) - 1; i >= 0; i-- {
+			data = append(data, item{Index: i, Embedding: []float32{float32(len(req.Input[i]))}})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	defer srv.Close()
+
+	e := NewOpenAIEmbedder("k", srv.URL+"/v1/", "m", 5)
+	texts := make([]string, maxEmbedBatch+2)
+	for i := range texts {
+		texts[i] = string(make([]byte, i%7))
+	}
+	vecs, err := e.Embed(context.Background(), texts)
+	if err != nil {
+		t.Fatalf("Embed: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 batched requests, got %d", calls)
+	}
+	for i, v := range vecs {
+		if int(v[0]) != i%7 {
+			t.Fatalf("vector %d out of order: %v", i, v)
+		}
+	}
+}
+
+func TestOpenAIEmbedderError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":{"message":"bad key"}}`, http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+	_, err := NewOpenAIEmbedder("k", srv.URL, "", 5).Embed(context.Background(), []string{"x"})
+	if err == nil {
+		t.Fatal("expected an error for a 401 response")
+	}
+}
+
+func TestNewEmbedderFromConfig(t *testing.T) {
+	var cfg config.Config
+	if NewEmbedderFromConfig(cfg) != nil {
+		t.Fatal("expected no embedder when disabled")
+	}
+	cfg.Providers.OpenAI = &config.ProviderConfig{APIKey: "sk", APIBase: "https://example.com/v1"}
+	cfg.Embeddings.Provider = "openai"
+	e, ok := NewEmbedderFromConfig(cfg).(*OpenAIEmbedder)
+	if !ok {
+		t.Fatal("expected an OpenAIEmbedder")
+	}
+	if e.APIKey != "sk" || e.APIBase != "https://example.com/v1" || e.Model() != DefaultEmbeddingModel {
+		t.Fatalf("expected defaults from providers.openai, got %+v", e)
+	}
+}
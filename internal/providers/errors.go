@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is returned by HTTP-based providers when the API responds with a
+// non-2xx status. It carries enough detail for ChainProvider to decide whether
+// a request is worth retrying.
+type APIError struct {
+	Provider   string // e.g. "OpenAI" or "Anthropic"; used in the message
+	StatusCode int
+	Status     string
+	Body       string
+	RetryAfter time.Duration // parsed from the Retry-After header; 0 if absent
+}
+
+func (e *APIError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s API error: %s", e.Provider, e.Status)
+	}
+	return fmt.Sprintf("%s API error: %s - %s", e.Provider, e.Status, e.Body)
+}
+
+// newAPIError reads and closes the body of a non-2xx response and logs it
+// (never the API key) before wrapping it in an APIError.
+func newAPIError(provider string, resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	body := strings.TrimSpace(string(bodyBytes))
+	log.Printf("%s API non-2xx: %s body=%q", provider, resp.Status, body)
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Body:       body,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+// parseRetryAfter accepts both forms of the Retry-After header: a number of
+// seconds or an HTTP date.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// IsRetryable reports whether err is a transient failure: rate limiting,
+// a server-side error, a timeout or a network problem. Context cancellation
+// by the caller is never retryable.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests,
+			apiErr.StatusCode == http.StatusRequestTimeout,
+			apiErr.StatusCode >= 500:
+			return true
+		}
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -1,12 +1,83 @@
 package providers
 
-import "github.com/local/picobot/internal/config"
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/local/picobot/internal/config"
+)
 
 // NewProviderFromConfig creates a provider based on the configuration.
 // Simple rules (v0):
+//   - if Anthropic API key present or API base is set -> Anthropic
 //   - if OpenAI API key present or API base is set (for Ollama) -> OpenAI
 //   - else fallback to stub
+//
+// When providers.fallbacks or providers.retry is configured, the selected
+// provider and the fallbacks are wrapped in a ChainProvider.
 func NewProviderFromConfig(cfg config.Config) LLMProvider {
+	primary := newPrimaryProvider(cfg)
+	if len(cfg.Providers.Fallbacks) == 0 && cfg.Providers.Retry == nil {
+		if primary == nil {
+			return NewStubProvider()
+		}
+		return primary
+	}
+
+	var entries []ChainEntry
+	if primary != nil {
+		entries = append(entries, ChainEntry{Provider: primary})
+	}
+	for i, fb := range cfg.Providers.Fallbacks {
+		var p LLMProvider
+		switch fb.Type {
+		case "anthropic":
+			p = NewAnthropicProvider(fb.APIKey, fb.APIBase, cfg.Agents.Defaults.RequestTimeoutS, cfg.Agents.Defaults.MaxTokens)
+		case "", "openai":
+			p = NewOpenAIProvider(fb.APIKey, fb.APIBase, cfg.Agents.Defaults.RequestTimeoutS, cfg.Agents.Defaults.MaxTokens)
+		default:
+			log.Printf("providers: ignoring fallback %d with unknown type %q", i, fb.Type)
+			continue
+		}
+		entries = append(entries, ChainEntry{Name: fallbackName(i, fb), Provider: p, Model: fb.Model})
+	}
+	if len(entries) == 0 {
+		return NewStubProvider()
+	}
+
+	chain := NewChainProvider(entries...)
+	if r := cfg.Providers.Retry; r != nil {
+		if r.MaxAttempts > 0 {
+			chain.MaxAttempts = r.MaxAttempts
+		}
+		if r.InitialBackoffMs > 0 {
+			chain.InitialBackoff = time.Duration(r.InitialBackoffMs) * time.Millisecond
+		}
+		if r.MaxBackoffMs > 0 {
+			chain.MaxBackoff = time.Duration(r.MaxBackoffMs) * time.Millisecond
+		}
+		if r.BreakerThreshold > 0 {
+			chain.BreakerThreshold = r.BreakerThreshold
+		}
+		if r.BreakerCooldownS > 0 {
+			chain.BreakerCooldown = time.Duration(r.BreakerCooldownS) * time.Second
+		}
+	}
+	return chain
+}
+
+// newPrimaryProvider returns the provider selected by the v0 rules, or nil if
+// none is configured.
+func newPrimaryProvider(cfg config.Config) LLMProvider {
+	if cfg.Providers.Anthropic != nil && (cfg.Providers.Anthropic.APIKey != "" || cfg.Providers.Anthropic.APIBase != "") {
+		return NewAnthropicProvider(
+			cfg.Providers.Anthropic.APIKey,
+			cfg.Providers.Anthropic.APIBase,
+			cfg.Agents.Defaults.RequestTimeoutS,
+			cfg.Agents.Defaults.MaxTokens,
+		)
+	}
 	if cfg.Providers.OpenAI != nil && (cfg.Providers.OpenAI.APIKey != "" || cfg.Providers.OpenAI.APIBase != "") {
 		return NewOpenAIProvider(
 			cfg.Providers.OpenAI.APIKey,
@@ -15,5 +86,14 @@ func NewProviderFromConfig(cfg config.Config) LLMProvider {
 			cfg.Agents.Defaults.MaxTokens,
 		)
 	}
-	return NewStubProvider()
+	return nil
+}
+
+// fallbackName identifies a fallback in logs by position and endpoint.
+func fallbackName(i int, fb config.FallbackConfig) string {
+	name := "fallback" + strconv.Itoa(i+1)
+	if fb.APIBase != "" {
+		name += " (" + fb.APIBase + ")"
+	}
+	return name
 }
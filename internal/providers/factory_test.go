@@ -16,6 +16,17 @@ func TestNewProviderFromConfig_PicksOpenAI(t *testing.T) {
 	}
 }
 
+func TestNewProviderFromConfig_PicksAnthropic(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Providers.OpenAI = &config.ProviderConfig{APIKey: "test"}
+	cfg.Providers.Anthropic = &config.ProviderConfig{APIKey: "test"}
+	p := NewProviderFromConfig(cfg)
+	_, ok := p.(*AnthropicProvider)
+	if !ok {
+		t.Fatalf("expected AnthropicProvider, got %T", p)
+	}
+}
+
 func TestNewProviderFromConfig_FallbacksToStub(t *testing.T) {
 	cfg := config.Config{}
 	p := NewProviderFromConfig(cfg)
@@ -24,3 +35,17 @@ func TestNewProviderFromConfig_FallbacksToStub(t *testing.T) {
 		t.Fatalf("expected StubProvider, got %T", p)
 	}
 }
+
+func TestNewProviderFromConfig_WrapsFallbacksInChain(t *testing.T) {
+	cfg := config.Config{}
+	cfg.Providers.OpenAI = &config.ProviderConfig{APIKey: "test", APIBase: "https://openrouter.ai/api/v1"}
+	cfg.Providers.Fallbacks = []config.FallbackConfig{{APIBase: "http://localhost:11434/v1", Model: "llama3.2"}}
+	p := NewProviderFromConfig(cfg)
+	chain, ok := p.(*ChainProvider)
+	if !ok {
+		t.Fatalf("expected ChainProvider, got %T", p)
+	}
+	if len(chain.backends) != 2 || chain.backends[1].Model != "llama3.2" {
+		t.Fatalf("unexpected chain backends: %+v", chain.backends)
+	}
+}
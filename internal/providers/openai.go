@@ -5,8 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -45,6 +43,14 @@ type chatRequest struct {
 	Messages  []messageJSON `json:"messages"`
 	Tools     []toolWrapper `json:"tools,omitempty"`
 	MaxTokens int           `json:"max_tokens,omitempty"`
+	Stream    bool          `json:"stream,omitempty"`
+
+	// StreamOptions asks for a final usage chunk when streaming.
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+}
+
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // toolWrapper is the OpenAI tools array element: {"type": "function", "function": {...}}
@@ -61,11 +67,22 @@ type functionDef struct {
 
 type messageJSON struct {
 	Role       string         `json:"role"`
-	Content    *string        `json:"content"`
+	Content    interface{}    `json:"content"` // nil, a string, or []contentPartJSON
 	ToolCallID string         `json:"tool_call_id,omitempty"`
 	ToolCalls  []toolCallJSON `json:"tool_calls,omitempty"`
 }
 
+// contentPartJSON is an element of a multimodal "content" array.
+type contentPartJSON struct {
+	Type     string        `json:"type"` // "text" | "image_url"
+	Text     string        `json:"text,omitempty"`
+	ImageURL *imageURLJSON `json:"image_url,omitempty"`
+}
+
+type imageURLJSON struct {
+	URL string `json:"url"`
+}
+
 type toolCallJSON struct {
 	ID       string               `json:"id"`
 	Type     string               `json:"type"`
@@ -84,25 +101,39 @@ type messageResponseJSON struct {
 }
 
 type chatResponse struct {
+	Model   string `json:"model"`
 	Choices []struct {
 		Message messageResponseJSON `json:"message"`
 	} `json:"choices"`
+	Usage *usageJSON `json:"usage"`
 }
 
-// Chat calls an OpenAI-compatible chat completion endpoint and returns a simplified response.
-func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
-	if model == "" {
-		model = p.GetDefaultModel()
-	}
+type usageJSON struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
 
+// buildChatRequest converts provider messages and tools into the OpenAI request shape.
+func (p *OpenAIProvider) buildChatRequest(messages []Message, tools []ToolDefinition, model string) chatRequest {
 	reqBody := chatRequest{Model: model, Messages: make([]messageJSON, 0, len(messages)), MaxTokens: p.MaxTokens}
 	for _, m := range messages {
 		mj := messageJSON{Role: m.Role, ToolCallID: m.ToolCallID}
-		if len(m.ToolCalls) > 0 && m.Content == "" {
+		switch {
+		case len(m.Parts) > 0:
+			parts := make([]contentPartJSON, 0, len(m.Parts))
+			for _, part := range m.Parts {
+				switch part.Type {
+				case "text":
+					parts = append(parts, contentPartJSON{Type: "text", Text: part.Text})
+				case "image":
+					parts = append(parts, contentPartJSON{Type: "image_url", ImageURL: &imageURLJSON{URL: part.ImageURL}})
+				}
+			}
+			mj.Content = parts
+		case len(m.ToolCalls) > 0 && m.Content == "":
 			mj.Content = nil
-		} else {
-			c := m.Content
-			mj.Content = &c
+		default:
+			mj.Content = m.Content
 		}
 		// Convert provider ToolCall to JSON-serializable toolCallJSON
 		for _, tc := range m.ToolCalls {
@@ -137,39 +168,53 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []T
 			})
 		}
 	}
+	return reqBody
+}
 
+// postChat sends a chat completion request and returns the response on 2xx.
+// The caller is responsible for closing the response body.
+func (p *OpenAIProvider) postChat(ctx context.Context, reqBody chatRequest) (*http.Response, error) {
 	b, err := json.Marshal(reqBody)
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
 
 	url := fmt.Sprintf("%s/chat/completions", p.APIBase)
 	req, err := http.NewRequestWithContext(ctx, "POST", url, strings.NewReader(string(b)))
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if reqBody.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 	if p.APIKey != "" {
 		req.Header.Set("Authorization", "Bearer "+p.APIKey)
 	}
 
 	resp, err := p.Client.Do(req)
 	if err != nil {
-		return LLMResponse{}, err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		// attempt to read response body for more details (do not expose API key)
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		body := strings.TrimSpace(string(bodyBytes))
-		log.Printf("OpenAI API non-2xx: %s body=%q", resp.Status, body)
-		if body == "" {
-			return LLMResponse{}, fmt.Errorf("OpenAI API error: %s", resp.Status)
-		}
-		return LLMResponse{}, fmt.Errorf("OpenAI API error: %s - %s", resp.Status, body)
+		return nil, newAPIError("OpenAI", resp)
+	}
+	return resp, nil
+}
+
+// Chat calls an OpenAI-compatible chat completion endpoint and returns a simplified response.
+func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []ToolDefinition, model string) (LLMResponse, error) {
+	if model == "" {
+		model = p.GetDefaultModel()
 	}
 
+	resp, err := p.postChat(ctx, p.buildChatRequest(messages, tools, model))
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
 	var out chatResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
 		return LLMResponse{}, err
@@ -180,22 +225,37 @@ func (p *OpenAIProvider) Chat(ctx context.Context, messages []Message, tools []T
 	}
 
 	msg := out.Choices[0].Message
+	r := toLLMResponse(msg.Content, msg.ToolCalls)
+	r.Usage.Model = out.Model
+	if out.Usage != nil {
+		r.Usage.PromptTokens = out.Usage.PromptTokens
+		r.Usage.CompletionTokens = out.Usage.CompletionTokens
+	}
+	return r, nil
+}
+
+// toLLMResponse normalizes assistant content and raw tool calls into an LLMResponse.
+func toLLMResponse(content string, calls []toolCallJSON) LLMResponse {
 	// If the model requested tool calls, parse them
-	if len(msg.ToolCalls) > 0 {
+	if len(calls) > 0 {
 		var tcs []ToolCall
-		for _, tc := range msg.ToolCalls {
+		for _, tc := range calls {
+			args := tc.Function.Arguments
+			if strings.TrimSpace(args) == "" {
+				args = "{}" // tools without parameters may stream no argument fragments
+			}
 			var parsed map[string]interface{}
-			if err := json.Unmarshal([]byte(tc.Function.Arguments), &parsed); err != nil {
+			if err := json.Unmarshal([]byte(args), &parsed); err != nil {
 				// skip unparseable tool calls
 				continue
 			}
 			tcs = append(tcs, ToolCall{ID: tc.ID, Name: tc.Function.Name, Arguments: parsed})
 		}
 		if len(tcs) > 0 {
-			return LLMResponse{Content: strings.TrimSpace(msg.Content), HasToolCalls: true, ToolCalls: tcs}, nil
+			return LLMResponse{Content: strings.TrimSpace(content), HasToolCalls: true, ToolCalls: tcs}
 		}
 	}
 
 	// No tool calls
-	return LLMResponse{Content: strings.TrimSpace(msg.Content), HasToolCalls: false}, nil
+	return LLMResponse{Content: strings.TrimSpace(content), HasToolCalls: false}
 }
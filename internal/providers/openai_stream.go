@@ -0,0 +1,146 @@
+package providers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+)
+
+// chatStreamChunk is one `data:` payload of an OpenAI `stream: true` response.
+type chatStreamChunk struct {
+	Model   string     `json:"model"`
+	Usage   *usageJSON `json:"usage"`
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Type     string `json:"type"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+	} `json:"choices"`
+}
+
+// readSSE reads a Server-Sent Events stream and calls fn with the data of each
+// event. Multi-line data fields are joined with "\n". Reading stops at EOF or
+// when the OpenAI "[DONE]" sentinel is received.
+func readSSE(r io.Reader, fn func(data string) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	var data []string
+	flush := func() (bool, error) {
+		if len(data) == 0 {
+			return false, nil
+		}
+		payload := strings.Join(data, "\n")
+		data = data[:0]
+		if payload == "[DONE]" {
+			return true, nil
+		}
+		return false, fn(payload)
+	}
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" {
+			done, err := flush()
+			if err != nil || done {
+				return err
+			}
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			continue // comment / keep-alive
+		}
+		if v, ok := strings.CutPrefix(line, "data:"); ok {
+			data = append(data, strings.TrimPrefix(v, " "))
+		}
+		// other fields (event:, id:, retry:) are not used by OpenAI-compatible APIs
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	_, err := flush()
+	return err
+}
+
+// ChatStream calls the chat completion endpoint with `stream: true`, forwarding
+// content deltas to onDelta and accumulating tool-call fragments by index.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta func(delta string)) (LLMResponse, error) {
+	if model == "" {
+		model = p.GetDefaultModel()
+	}
+
+	reqBody := p.buildChatRequest(messages, tools, model)
+	reqBody.Stream = true
+	reqBody.StreamOptions = &streamOptions{IncludeUsage: true}
+	resp, err := p.postChat(ctx, reqBody)
+	if err != nil {
+		return LLMResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var content strings.Builder
+	var usage Usage
+	calls := map[int]*toolCallJSON{}
+	err = readSSE(resp.Body, func(data string) error {
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return err
+		}
+		if chunk.Model != "" {
+			usage.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			// sent in a final chunk with no choices when include_usage is set
+			usage.PromptTokens = chunk.Usage.PromptTokens
+			usage.CompletionTokens = chunk.Usage.CompletionTokens
+		}
+		for _, choice := range chunk.Choices {
+			if d := choice.Delta.Content; d != "" {
+				content.WriteString(d)
+				if onDelta != nil {
+					onDelta(d)
+				}
+			}
+			for _, tc := range choice.Delta.ToolCalls {
+				acc, ok := calls[tc.Index]
+				if !ok {
+					acc = &toolCallJSON{Type: "function"}
+					calls[tc.Index] = acc
+				}
+				if tc.ID != "" {
+					acc.ID = tc.ID
+				}
+				if tc.Function.Name != "" {
+					acc.Function.Name += tc.Function.Name
+				}
+				acc.Function.Arguments += tc.Function.Arguments
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return LLMResponse{}, err
+	}
+
+	indices := make([]int, 0, len(calls))
+	for i := range calls {
+		indices = append(indices, i)
+	}
+	sort.Ints(indices)
+	ordered := make([]toolCallJSON, 0, len(indices))
+	for _, i := range indices {
+		ordered = append(ordered, *calls[i])
+	}
+	r := toLLMResponse(content.String(), ordered)
+	r.Usage = usage
+	return r, nil
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenAIChatStreamContent(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req chatRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if !req.Stream || req.StreamOptions == nil || !req.StreamOptions.IncludeUsage {
+			t.Errorf("expected stream=true with include_usage in request")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": keep-alive\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"role\":\"assistant\",\"content\":\"Hel\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"lo \"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[{\"delta\":{\"content\":\"world\"}}]}\n\n"))
+		w.Write([]byte("data: {\"choices\":[],\"usage\":{\"prompt_tokens\":10,\"completion_tokens\":3}}\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer h.Close()
+
+	p := NewOpenAIProvider("k", h.URL, 60, 0)
+	p.Client = &http.Client{Timeout: 5 * time.Second}
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", func(d string) {
+		deltas = append(deltas, d)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Content != "Hello world" {
+		t.Fatalf("expected accumulated content, got %q", resp.Content)
+	}
+	if strings.Join(deltas, "|") != "Hel|lo |world" {
+		t.Fatalf("unexpected deltas: %v", deltas)
+	}
+	if resp.Usage.PromptTokens != 10 || resp.Usage.CompletionTokens != 3 {
+		t.Fatalf("expected usage from the final chunk, got %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIChatStreamToolCallAccumulation(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"message","arguments":""}}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"content\": "}}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"list_memory","arguments":""}}]}}]}` + "\n\n"))
+		w.Write([]byte(`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"\"hi\"}"}}]}}]}` + "\n\n"))
+		w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer h.Close()
+
+	p := NewOpenAIProvider("k", h.URL, 60, 0)
+	resp, err := p.ChatStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil, "m", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !resp.HasToolCalls || len(resp.ToolCalls) != 2 {
+		t.Fatalf("expected two tool calls, got %+v", resp)
+	}
+	if resp.ToolCalls[0].ID != "call_1" || resp.ToolCalls[0].Arguments["content"] != "hi" {
+		t.Fatalf("unexpected first tool call: %+v", resp.ToolCalls[0])
+	}
+	if resp.ToolCalls[1].Name != "list_memory" || len(resp.ToolCalls[1].Arguments) != 0 {
+		t.Fatalf("unexpected second tool call: %+v", resp.ToolCalls[1])
+	}
+}
+
+func TestReadSSEMultiLineData(t *testing.T) {
+	var got []string
+	err := readSSE(strings.NewReader("event: x\ndata: a\ndata: b\n\ndata: c"), func(d string) error {
+		got = append(got, d)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0] != "a\nb" || got[1] != "c" {
+		t.Fatalf("unexpected events: %q", got)
+	}
+}
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -31,7 +32,9 @@ func TestOpenAIFunctionCallParsing(t *testing.T) {
 		        ]
 		      }
 		    }
-		  ]
+		  ],
+		  "model": "model-x-2025",
+		  "usage": {"prompt_tokens": 42, "completion_tokens": 7, "total_tokens": 49}
 		}`))
 	}))
 	defer h.Close()
@@ -56,4 +59,25 @@ func TestOpenAIFunctionCallParsing(t *testing.T) {
 	if resp.ToolCalls[0].Arguments["content"] != "Hello from function" {
 		t.Fatalf("unexpected argument content: %v", resp.ToolCalls[0].Arguments)
 	}
+	if resp.Usage.PromptTokens != 42 || resp.Usage.CompletionTokens != 7 || resp.Usage.Model != "model-x-2025" {
+		t.Fatalf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIImagePartsSerialization(t *testing.T) {
+	p := NewOpenAIProvider("k", "http://unused", 60, 0)
+	req := p.buildChatRequest([]Message{{
+		Role:    "user",
+		Content: "read this",
+		Parts: []ContentPart{
+			{Type: "text", Text: "read this"},
+			{Type: "image", ImageURL: "data:image/png;base64,AAAA"},
+		},
+	}}, nil, "m")
+
+	b, _ := json.Marshal(req.Messages[0])
+	want := `{"role":"user","content":[{"type":"text","text":"read this"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]}`
+	if string(b) != want {
+		t.Fatalf("unexpected message JSON:\n got %s\nwant %s", b, want)
+	}
 }
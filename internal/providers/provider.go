@@ -8,6 +8,17 @@ type Message struct {
 	Content    string     `json:"content"`
 	ToolCallID string     `json:"tool_call_id,omitempty"` // set when Role == "tool"
 	ToolCalls  []ToolCall `json:"tool_calls,omitempty"`   // set on assistant msgs with tool calls
+
+	// Parts holds multimodal content (text and images) for user messages.
+	// When non-empty, providers send Parts instead of Content.
+	Parts []ContentPart `json:"parts,omitempty"`
+}
+
+// ContentPart is one piece of a multimodal message.
+type ContentPart struct {
+	Type     string `json:"type"`               // "text" | "image"
+	Text     string `json:"text,omitempty"`     // set when Type == "text"
+	ImageURL string `json:"imageUrl,omitempty"` // set when Type == "image"; a data: URI or an http(s) URL
 }
 
 // ToolDefinition is a lightweight description of a tool available to the model.
@@ -24,11 +35,19 @@ type ToolCall struct {
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
+// Usage reports the tokens consumed by a single provider call.
+type Usage struct {
+	PromptTokens     int    `json:"promptTokens"`
+	CompletionTokens int    `json:"completionTokens"`
+	Model            string `json:"model,omitempty"` // model that answered, if reported by the API
+}
+
 // LLMResponse is a normalized response from a provider.
 type LLMResponse struct {
 	Content      string     `json:"content"`
 	HasToolCalls bool       `json:"hasToolCalls"`
 	ToolCalls    []ToolCall `json:"toolCalls,omitempty"`
+	Usage        Usage      `json:"usage"`
 }
 
 // LLMProvider is the interface used by the agent loop to call LLMs.
@@ -39,3 +58,14 @@ type LLMProvider interface {
 	// GetDefaultModel returns the provider's default model string.
 	GetDefaultModel() string
 }
+
+// StreamingProvider is implemented by providers that can deliver the model's
+// reply incrementally. Callers should type-assert an LLMProvider to check support.
+type StreamingProvider interface {
+	LLMProvider
+
+	// ChatStream behaves like Chat but calls onDelta with each text fragment as
+	// it arrives. The returned LLMResponse holds the fully accumulated content
+	// and any tool calls, exactly as Chat would have returned them.
+	ChatStream(ctx context.Context, messages []Message, tools []ToolDefinition, model string, onDelta func(delta string)) (LLMResponse, error)
+}
@@ -0,0 +1,107 @@
+package session
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Export writes s to w in the given format:
+//   - "text": a plain transcript for the terminal
+//   - "md":   a Markdown transcript
+//   - "json": the session as stored
+//   - "jsonl": one message per line
+func Export(w io.Writer, s *Session, format string) error {
+	switch format {
+	case "text":
+		return writeTranscript(w, s, false)
+	case "md":
+		return writeTranscript(w, s, true)
+	case "json":
+		b, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "%s\n", b)
+		return err
+	case "jsonl":
+		enc := json.NewEncoder(w)
+		for _, m := range s.Messages {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q (want text, md, json or jsonl)", format)
+	}
+}
+
+// writeTranscript renders the summary and messages, with Markdown headings
+// and code blocks when md is set.
+func writeTranscript(w io.Writer, s *Session, md bool) error {
+	var sb strings.Builder
+	if md {
+		fmt.Fprintf(&sb, "# %s\n\n", s.Key)
+		if s.Summary != "" {
+			fmt.Fprintf(&sb, "> **Summary of earlier conversation:** %s\n\n", s.Summary)
+		}
+	} else if s.Summary != "" {
+		fmt.Fprintf(&sb, "(summary) %s\n\n", s.Summary)
+	}
+
+	for _, m := range s.Messages {
+		who := m.Role
+		if m.Role == "user" && m.Sender != "" {
+			who += " " + m.Sender
+		}
+		when := ""
+		if !m.Time.IsZero() {
+			when = m.Time.Local().Format(time.DateTime)
+		}
+		var body strings.Builder
+		if m.Content != "" {
+			if m.Role == "tool" && md {
+				fmt.Fprintf(&body, "```\n%s\n```\n", m.Content)
+			} else {
+				body.WriteString(m.Content + "\n")
+			}
+		}
+		for _, tc := range m.ToolCalls {
+			args, _ := json.Marshal(tc.Arguments)
+			if md {
+				fmt.Fprintf(&body, "- called `%s` `%s`\n", tc.Name, args)
+			} else {
+				fmt.Fprintf(&body, "-> %s %s\n", tc.Name, args)
+			}
+		}
+
+		if md {
+			header := "**" + who + "**"
+			if when != "" {
+				header += " · " + when
+			}
+			fmt.Fprintf(&sb, "%s\n\n%s\n", header, body.String())
+			continue
+		}
+		if when != "" {
+			when = "[" + when + "] "
+		}
+		fmt.Fprintf(&sb, "%s%s:\n%s\n", when, who, indent(body.String()))
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// indent prefixes every non-empty line with two spaces.
+func indent(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = "  " + l
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,73 @@
+package session
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/providers"
+)
+
+func exportSample() *Session {
+	s := &Session{Key: "telegram:1", Summary: "user likes tea"}
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.Local)
+	s.Add(Message{Role: "user", Content: "what's the weather?", Sender: "42", Time: at})
+	s.Add(Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "web", Arguments: map[string]interface{}{"url": "https://wttr.in"}}}, Time: at})
+	s.Add(Message{Role: "tool", Content: "sunny", ToolCallID: "c1", Time: at})
+	s.Add(Message{Role: "assistant", Content: "It's sunny.", Time: at})
+	return s
+}
+
+func TestExportMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportSample(), "md"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"# telegram:1", "user likes tea", "**user 42** · 2026-03-01 09:30:00", "called `web`", "```\nsunny\n```", "It's sunny."} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("expected %q in markdown export:\n%s", want, out)
+		}
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Export(&buf, exportSample(), "jsonl"); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d", len(lines))
+	}
+	var m Message
+	if err := json.Unmarshal([]byte(lines[2]), &m); err != nil || m.Role != "tool" || m.ToolCallID != "c1" {
+		t.Fatalf("unexpected line %q (%v)", lines[2], err)
+	}
+	if err := Export(&buf, exportSample(), "pdf"); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestManagerDeleteRemovesFile(t *testing.T) {
+	ws := t.TempDir()
+	sm := NewSessionManager(ws)
+	s := sm.GetOrCreate("slack:C1/2")
+	s.AddMessage("user", "hi")
+	if err := sm.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	if err := sm.Delete("slack:C1/2"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if len(sm.List()) != 0 {
+		t.Fatal("expected the session to be gone from memory")
+	}
+	reloaded := NewSessionManager(ws)
+	reloaded.LoadAll()
+	if _, ok := reloaded.Get("slack:C1/2"); ok {
+		t.Fatal("expected the session file to be deleted")
+	}
+}
@@ -0,0 +1,217 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	indexFileName = "index.json"
+	lockFileName  = ".lock"
+)
+
+// FileStore keeps one JSON file per session under workspace/sessions.
+//
+// Session keys contain channel-controlled chat IDs, so file names are
+// encoded with FileName and index.json maps every key to its file. Each
+// write goes to a temporary file that is renamed into place, under an
+// exclusive lock on sessions/.lock, so a crash mid-write leaves the previous
+// version intact and concurrent processes never see a partial file.
+type FileStore struct {
+	dir string
+	mu  sync.Mutex // serialises writers within this process
+}
+
+// NewFileStore returns a FileStore rooted at workspace.
+func NewFileStore(workspace string) *FileStore {
+	return &FileStore{dir: filepath.Join(workspace, "sessions")}
+}
+
+// LoadSessions reads every session file. Unreadable or malformed files are
+// skipped. Files written by older versions under the raw key are read too;
+// they are replaced by the encoded file on the next save.
+func (f *FileStore) LoadSessions() ([]*Session, error) {
+	unlock, err := f.lock(false)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, err
+	}
+	index := f.readIndex()
+	keyOf := make(map[string]string, len(index))
+	for key, name := range index {
+		keyOf[name] = key
+	}
+
+	byKey := map[string]*Session{}
+	var keys []string
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || name == indexFileName || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(f.dir, name))
+		if err != nil {
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal(b, &s); err != nil {
+			continue
+		}
+		if s.Key == "" {
+			key, ok := keyOf[name]
+			if !ok {
+				if key, ok = DecodeFileName(strings.TrimSuffix(name, ".json")); !ok {
+					continue
+				}
+			}
+			s.Key = key
+		}
+		if _, seen := byKey[s.Key]; !seen {
+			keys = append(keys, s.Key)
+		} else if name != FileName(s.Key)+".json" {
+			continue // a legacy copy; the encoded file wins
+		}
+		byKey[s.Key] = &s
+	}
+	out := make([]*Session, 0, len(keys))
+	for _, k := range keys {
+		out = append(out, byKey[k])
+	}
+	return out, nil
+}
+
+// SaveSession atomically writes s to its file and records it in the index.
+func (f *FileStore) SaveSession(s *Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	name := FileName(s.Key) + ".json"
+	if err := writeFileAtomic(filepath.Join(f.dir, name), b); err != nil {
+		return err
+	}
+
+	index := f.readIndex()
+	if index[s.Key] != name {
+		index[s.Key] = name
+		ib, err := json.MarshalIndent(index, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(f.dir, indexFileName), ib); err != nil {
+			return err
+		}
+	}
+
+	// remove the file an older version wrote under the raw key
+	if legacy := s.Key + ".json"; legacy != name && legacy != indexFileName && filepath.Base(legacy) == legacy {
+		if err := os.Remove(filepath.Join(f.dir, legacy)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteSession removes the session file and its index entry.
+func (f *FileStore) DeleteSession(key string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	unlock, err := f.lock(true)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	names := []string{FileName(key) + ".json"}
+	if legacy := key + ".json"; legacy != indexFileName && filepath.Base(legacy) == legacy {
+		names = append(names, legacy)
+	}
+	for _, name := range names {
+		if err := os.Remove(filepath.Join(f.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	index := f.readIndex()
+	if _, ok := index[key]; !ok {
+		return nil
+	}
+	delete(index, key)
+	b, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(f.dir, indexFileName), b)
+}
+
+// readIndex returns the key to file name map. A missing or damaged index is
+// treated as empty: it only speeds up lookups and is rebuilt on save.
+func (f *FileStore) readIndex() map[string]string {
+	index := map[string]string{}
+	b, err := os.ReadFile(filepath.Join(f.dir, indexFileName))
+	if err != nil {
+		return index
+	}
+	if err := json.Unmarshal(b, &index); err != nil {
+		return map[string]string{}
+	}
+	return index
+}
+
+// lock takes the directory lock shared for reads or exclusive for writes,
+// creating the directory if needed.
+func (f *FileStore) lock(exclusive bool) (unlock func(), err error) {
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return nil, err
+	}
+	lf, err := os.OpenFile(filepath.Join(f.dir, lockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lf, exclusive); err != nil {
+		lf.Close()
+		return nil, err
+	}
+	return func() { lf.Close() }, nil
+}
+
+// writeFileAtomic writes data to a temporary file in the same directory,
+// flushes it to disk and renames it over path.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
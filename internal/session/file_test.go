@@ -0,0 +1,107 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileNameRoundTrip(t *testing.T) {
+	for _, key := range []string{
+		"telegram:123",
+		"slack:C0123/1700000000.123456",
+		"discord:../../etc/passwd",
+		"whatsapp:12345@s.whatsapp.net",
+		"cli:ü%20",
+	} {
+		name := FileName(key)
+		if strings.ContainsAny(name, `/\:.`) {
+			t.Fatalf("FileName(%q) = %q contains unsafe characters", key, name)
+		}
+		got, ok := DecodeFileName(name)
+		if !ok || got != key {
+			t.Fatalf("DecodeFileName(%q) = %q, %v; want %q", name, got, ok, key)
+		}
+	}
+
+	long := "slack:" + strings.Repeat("x/", 150)
+	name := FileName(long)
+	if !strings.HasPrefix(name, hashedPrefix) || len(name) > maxFileNameLen {
+		t.Fatalf("expected a hashed name for a long key, got %q", name)
+	}
+	if _, ok := DecodeFileName(name); ok {
+		t.Fatal("hashed names must not decode")
+	}
+}
+
+func TestFileStoreSavesUnderEncodedNames(t *testing.T) {
+	ws := t.TempDir()
+	dir := filepath.Join(ws, "sessions")
+	os.MkdirAll(dir, 0755)
+	// a file written by an older version under the raw key
+	os.WriteFile(filepath.Join(dir, "telegram:1.json"), []byte(`{"Key":"telegram:1","History":["user: old"]}`), 0644)
+
+	fs := NewFileStore(ws)
+	long := "slack:" + strings.Repeat("thread/", 40)
+	for _, key := range []string{"telegram:1", "slack:C1/171.2", long} {
+		s := &Session{Key: key}
+		s.AddMessage("user", "hi from "+key)
+		if err := fs.SaveSession(s); err != nil {
+			t.Fatalf("SaveSession(%q): %v", key, err)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	got := strings.Join(names, " ")
+	if strings.Contains(got, "telegram:1.json") || strings.Contains(got, ".tmp-") {
+		t.Fatalf("expected the legacy and temporary files to be gone, got %s", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "slack%3AC1%2F171%2E2.json")); err != nil {
+		t.Fatalf("expected an encoded file name, got %s", got)
+	}
+
+	var index map[string]string
+	b, _ := os.ReadFile(filepath.Join(dir, indexFileName))
+	if err := json.Unmarshal(b, &index); err != nil || index[long] != FileName(long)+".json" || len(index) != 3 {
+		t.Fatalf("unexpected index %s (%v)", b, err)
+	}
+
+	loaded, err := fs.LoadSessions()
+	if err != nil {
+		t.Fatalf("LoadSessions: %v", err)
+	}
+	if len(loaded) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(loaded))
+	}
+	for _, s := range loaded {
+		if len(s.Messages) != 1 || s.Messages[0].Content != "hi from "+s.Key {
+			t.Fatalf("unexpected session %q: %+v", s.Key, s.Messages)
+		}
+	}
+}
+
+func TestFileStorePrefersEncodedFileOverLegacyCopy(t *testing.T) {
+	ws := t.TempDir()
+	fs := NewFileStore(ws)
+	s := &Session{Key: "telegram:1"}
+	s.AddMessage("user", "new")
+	if err := fs.SaveSession(s); err != nil {
+		t.Fatal(err)
+	}
+	// a stale legacy copy left behind by a crash during the upgrade
+	os.WriteFile(filepath.Join(ws, "sessions", "telegram:1.json"), []byte(`{"Key":"telegram:1","History":["user: old"]}`), 0644)
+
+	loaded, err := fs.LoadSessions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].Messages[0].Content != "new" {
+		t.Fatalf("expected only the encoded session, got %+v", loaded)
+	}
+}
@@ -0,0 +1,63 @@
+package session
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// maxFileNameLen keeps encoded names well under the 255-byte limit of common
+// filesystems, leaving room for the ".json" suffix.
+const maxFileNameLen = 200
+
+// hashedPrefix marks file names derived from a hash of a long key. Such
+// names can only be mapped back through the index.
+const hashedPrefix = "h-"
+
+// FileName returns the file name (without ".json") used for key. Letters,
+// digits, '-' and '_' are kept; every other byte is written as %XX, so names
+// are valid on every filesystem and can be decoded with DecodeFileName.
+// Keys whose encoding would be too long are replaced by a hash.
+func FileName(key string) string {
+	var sb strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' {
+			sb.WriteByte(c)
+		} else {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		}
+	}
+	name := sb.String()
+	if len(name) > maxFileNameLen || strings.HasPrefix(name, hashedPrefix) {
+		sum := sha256.Sum256([]byte(key))
+		return hashedPrefix + hex.EncodeToString(sum[:])
+	}
+	return name
+}
+
+// DecodeFileName reverses FileName. ok is false for hashed names and names
+// that were not produced by FileName.
+func DecodeFileName(name string) (key string, ok bool) {
+	if strings.HasPrefix(name, hashedPrefix) {
+		return "", false
+	}
+	var sb strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' {
+			sb.WriteByte(name[i])
+			continue
+		}
+		if i+2 >= len(name) {
+			return "", false
+		}
+		b, err := hex.DecodeString(name[i+1 : i+3])
+		if err != nil {
+			return "", false
+		}
+		sb.WriteByte(b[0])
+		i += 2
+	}
+	return sb.String(), true
+}
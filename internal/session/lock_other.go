@@ -0,0 +1,11 @@
+//go:build !(linux || darwin || freebsd || openbsd || netbsd || dragonfly)
+
+package session
+
+import "os"
+
+// lockFile is a no-op where flock is unavailable; writes are still atomic
+// through rename.
+func lockFile(f *os.File, exclusive bool) error {
+	return nil
+}
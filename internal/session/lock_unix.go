@@ -0,0 +1,18 @@
+//go:build linux || darwin || freebsd || openbsd || netbsd || dragonfly
+
+package session
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes an advisory lock on f, shared or exclusive, blocking until
+// it is granted. The lock is released when f is closed.
+func lockFile(f *os.File, exclusive bool) error {
+	how := syscall.LOCK_SH
+	if exclusive {
+		how = syscall.LOCK_EX
+	}
+	return syscall.Flock(int(f.Fd()), how)
+}
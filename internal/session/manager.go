@@ -2,32 +2,110 @@ package session
 
 import (
 	"encoding/json"
-	"os"
-	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"github.com/local/picobot/internal/providers"
 )
 
-// MaxHistorySize is the maximum number of messages kept in a session.
-// Older messages are trimmed on save to keep the session file small
-// and avoid blowing up the LLM context window.
+// MaxHistorySize is the maximum number of messages kept in a session by
+// default (see SessionManager.SetMaxHistory). Older messages are trimmed on
+// save to keep the session file small and avoid blowing up the LLM context
+// window.
 // Important information should be persisted via write_memory, not session history.
 const MaxHistorySize = 50
 
+// Message is one entry of a session's history.
+type Message struct {
+	Role       string               `json:"role"` // "user" | "assistant" | "tool"
+	Content    string               `json:"content"`
+	ToolCalls  []providers.ToolCall `json:"toolCalls,omitempty"`  // assistant messages that called tools
+	ToolCallID string               `json:"toolCallId,omitempty"` // tool results
+	Sender     string               `json:"sender,omitempty"`     // channel user ID of user messages
+	Time       time.Time            `json:"time"`
+	// Metadata holds channel-specific details of user messages (message IDs,
+	// usernames, whether a voice message was transcribed, ...).
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
 // Session holds a short chat history.
 type Session struct {
-	Key     string
-	History []string
+	Key      string    `json:"key"`
+	Messages []Message `json:"messages"`
+	// Summary condenses turns that were compacted out of Messages.
+	Summary string `json:"summary,omitempty"`
+}
+
+// UnmarshalJSON reads both the current format and the original one, in which
+// History was a list of "role: content" strings.
+func (s *Session) UnmarshalJSON(b []byte) error {
+	type current Session
+	var raw struct {
+		current
+		History []string `json:"History"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	*s = Session(raw.current)
+	if len(s.Messages) == 0 {
+		for _, h := range raw.History {
+			if h != "" {
+				s.Messages = append(s.Messages, parseLegacy(h))
+			}
+		}
+	}
+	return nil
+}
+
+// parseLegacy converts a "role: content" history line. Lines without a known
+// role prefix are treated as user messages.
+func parseLegacy(h string) Message {
+	if role, content, ok := strings.Cut(h, ": "); ok && (role == "user" || role == "assistant") {
+		return Message{Role: role, Content: content}
+	}
+	return Message{Role: "user", Content: h}
+}
+
+// Store persists sessions. Implementations must be safe for concurrent use.
+type Store interface {
+	// LoadSessions returns every stored session.
+	LoadSessions() ([]*Session, error)
+	// SaveSession writes s, replacing any previous version.
+	SaveSession(s *Session) error
+	// DeleteSession removes the session with key. Unknown keys are not an
+	// error.
+	DeleteSession(key string) error
 }
 
-// SessionManager stores sessions in memory and persists to disk under workspace.
+// SessionManager keeps sessions in memory and persists them to a Store.
 type SessionManager struct {
-	mu        sync.RWMutex
-	sessions  map[string]*Session
-	workspace string
+	mu         sync.RWMutex
+	sessions   map[string]*Session
+	store      Store
+	maxHistory int // messages kept on save; 0 keeps all
 }
 
+// NewSessionManager returns a manager that stores one JSON file per session
+// under workspace/sessions.
 func NewSessionManager(workspace string) *SessionManager {
-	return &SessionManager{sessions: make(map[string]*Session), workspace: workspace}
+	return NewSessionManagerWithStore(NewFileStore(workspace))
+}
+
+// NewSessionManagerWithStore returns a manager backed by st.
+func NewSessionManagerWithStore(st Store) *SessionManager {
+	return &SessionManager{sessions: make(map[string]*Session), store: st, maxHistory: MaxHistorySize}
+}
+
+// SetMaxHistory sets how many of the latest messages Save keeps (default
+// MaxHistorySize). 0 keeps every message, for callers that bound history
+// themselves, e.g. by summarising old turns.
+func (sm *SessionManager) SetMaxHistory(n int) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.maxHistory = n
 }
 
 func (sm *SessionManager) GetOrCreate(key string) *Session {
@@ -36,7 +114,7 @@ func (sm *SessionManager) GetOrCreate(key string) *Session {
 	if s, ok := sm.sessions[key]; ok {
 		return s
 	}
-	s := &Session{Key: key, History: make([]string, 0)}
+	s := &Session{Key: key, Messages: make([]Message, 0)}
 	sm.sessions[key] = s
 	return s
 }
@@ -45,57 +123,101 @@ func (sm *SessionManager) Save(s *Session) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 	// Trim history to the most recent messages
-	s.trim()
-	path := filepath.Join(sm.workspace, "sessions")
-	if err := os.MkdirAll(path, 0755); err != nil {
-		return err
-	}
-	fpath := filepath.Join(path, s.Key+".json")
-	b, err := json.MarshalIndent(s, "", "  ")
-	if err != nil {
-		return err
+	s.trim(sm.maxHistory)
+	return sm.store.SaveSession(s)
+}
+
+// Get returns the session with key, if it exists.
+func (sm *SessionManager) Get(key string) (*Session, bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	s, ok := sm.sessions[key]
+	return s, ok
+}
+
+// List returns all sessions sorted by key.
+func (sm *SessionManager) List() []*Session {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	out := make([]*Session, 0, len(sm.sessions))
+	for _, s := range sm.sessions {
+		out = append(out, s)
 	}
-	return os.WriteFile(fpath, b, 0644)
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
 }
 
-func (sm *SessionManager) LoadAll() error {
+// Delete removes a session from memory and from the store.
+func (sm *SessionManager) Delete(key string) error {
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
-	path := filepath.Join(sm.workspace, "sessions")
-	_ = os.MkdirAll(path, 0755)
-	entries, err := os.ReadDir(path)
+	delete(sm.sessions, key)
+	return sm.store.DeleteSession(key)
+}
+
+// LoadAll reads every stored session into memory.
+func (sm *SessionManager) LoadAll() error {
+	sessions, err := sm.store.LoadSessions()
 	if err != nil {
 		return err
 	}
-	for _, e := range entries {
-		if e.IsDir() {
-			continue
-		}
-		b, err := os.ReadFile(filepath.Join(path, e.Name()))
-		if err != nil {
-			continue
-		}
-		var s Session
-		if err := json.Unmarshal(b, &s); err != nil {
-			continue
-		}
-		sm.sessions[s.Key] = &s
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	for _, s := range sessions {
+		sm.sessions[s.Key] = s
 	}
 	return nil
 }
 
+// AddMessage appends a plain message with the current time.
 func (s *Session) AddMessage(role, content string) {
-	s.History = append(s.History, role+": "+content)
+	s.Add(Message{Role: role, Content: content})
+}
+
+// Add appends m, stamping it with the current time if it has none.
+func (s *Session) Add(m Message) {
+	if m.Time.IsZero() {
+		m.Time = time.Now()
+	}
+	s.Messages = append(s.Messages, m)
+}
+
+// Clear discards the session history and summary.
+func (s *Session) Clear() {
+	s.Messages = s.Messages[:0]
+	s.Summary = ""
+}
+
+// LastActive returns the time of the newest timestamped message, or the zero
+// time if no message has one (e.g. sessions migrated from the old format).
+func (s *Session) LastActive() time.Time {
+	for i := len(s.Messages) - 1; i >= 0; i-- {
+		if !s.Messages[i].Time.IsZero() {
+			return s.Messages[i].Time
+		}
+	}
+	return time.Time{}
 }
 
 // GetHistory returns the session history.
-func (s *Session) GetHistory() []string {
-	return s.History
+func (s *Session) GetHistory() []Message {
+	return s.Messages
+}
+
+// trim keeps only the last n messages, discarding the oldest. n <= 0 keeps
+// them all.
+func (s *Session) trim(n int) {
+	if n > 0 && len(s.Messages) > n {
+		s.Messages = s.Messages[TrimStart(s.Messages, len(s.Messages)-n):]
+	}
 }
 
-// trim keeps only the last MaxHistorySize messages, discarding the oldest.
-func (s *Session) trim() {
-	if len(s.History) > MaxHistorySize {
-		s.History = s.History[len(s.History)-MaxHistorySize:]
+// TrimStart returns the first index >= i at which history can start: tool
+// results whose assistant tool call would be cut off are skipped, since
+// providers reject them.
+func TrimStart(msgs []Message, i int) int {
+	for i < len(msgs) && msgs[i].Role == "tool" {
+		i++
 	}
+	return i
 }
@@ -0,0 +1,80 @@
+package session
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/local/picobot/internal/providers"
+)
+
+func TestLoadAllMigratesLegacyHistory(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "sessions"), 0755)
+	legacy := `{"Key":"telegram:1","History":["user: hi","assistant: hello: how can I help?","odd line"]}`
+	if err := os.WriteFile(filepath.Join(dir, "sessions", "telegram:1.json"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sm := NewSessionManager(dir)
+	if err := sm.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	h := sm.GetOrCreate("telegram:1").GetHistory()
+	want := []Message{
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", Content: "hello: how can I help?"},
+		{Role: "user", Content: "odd line"},
+	}
+	if len(h) != len(want) {
+		t.Fatalf("expected %d messages, got %+v", len(want), h)
+	}
+	for i, m := range want {
+		if h[i].Role != m.Role || h[i].Content != m.Content {
+			t.Fatalf("message %d: expected %+v, got %+v", i, m, h[i])
+		}
+	}
+}
+
+func TestSaveAndLoadKeepsToolCalls(t *testing.T) {
+	dir := t.TempDir()
+	sm := NewSessionManager(dir)
+	s := sm.GetOrCreate("discord:9")
+	s.Add(Message{Role: "user", Content: "weather?", Sender: "u1", Metadata: map[string]interface{}{"voice": true}})
+	s.Add(Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "web", Arguments: map[string]interface{}{"url": "x"}}}})
+	s.Add(Message{Role: "tool", Content: "sunny", ToolCallID: "c1"})
+	s.AddMessage("assistant", "It's sunny.")
+	if err := sm.Save(s); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded := NewSessionManager(dir)
+	if err := loaded.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	h := loaded.GetOrCreate("discord:9").GetHistory()
+	if len(h) != 4 {
+		t.Fatalf("expected 4 messages, got %+v", h)
+	}
+	if h[0].Sender != "u1" || h[0].Metadata["voice"] != true || h[0].Time.IsZero() {
+		t.Fatalf("user message lost its details: %+v", h[0])
+	}
+	if len(h[1].ToolCalls) != 1 || h[1].ToolCalls[0].Name != "web" || h[2].ToolCallID != "c1" {
+		t.Fatalf("tool call not preserved: %+v %+v", h[1], h[2])
+	}
+}
+
+func TestTrimSkipsOrphanedToolResults(t *testing.T) {
+	s := &Session{Key: "k"}
+	s.Add(Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "exec"}}})
+	s.Add(Message{Role: "tool", Content: "done", ToolCallID: "c1"})
+	for i := 0; i < MaxHistorySize-1; i++ {
+		s.AddMessage("user", "x")
+	}
+
+	// keeping the last MaxHistorySize messages would start at the tool result
+	s.trim(MaxHistorySize)
+	if len(s.Messages) != MaxHistorySize-1 || s.Messages[0].Role != "user" {
+		t.Fatalf("expected the orphaned tool result to be dropped, got %d messages starting with %q", len(s.Messages), s.Messages[0].Role)
+	}
+}
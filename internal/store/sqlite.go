@@ -0,0 +1,373 @@
+//go:build !lite
+
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/cron"
+	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/usage"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	key        TEXT PRIMARY KEY,
+	channel    TEXT NOT NULL,
+	chat_id    TEXT NOT NULL,
+	summary    TEXT NOT NULL DEFAULT '',
+	updated_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_chat ON sessions(channel, chat_id);
+CREATE INDEX IF NOT EXISTS sessions_updated ON sessions(updated_at);
+
+CREATE TABLE IF NOT EXISTS session_messages (
+	session_key  TEXT NOT NULL REFERENCES sessions(key) ON DELETE CASCADE,
+	seq          INTEGER NOT NULL,
+	role         TEXT NOT NULL,
+	content      TEXT NOT NULL,
+	tool_calls   TEXT,
+	tool_call_id TEXT NOT NULL DEFAULT '',
+	sender       TEXT NOT NULL DEFAULT '',
+	time         INTEGER NOT NULL,
+	metadata     TEXT,
+	PRIMARY KEY (session_key, seq)
+);
+CREATE INDEX IF NOT EXISTS session_messages_sender ON session_messages(sender, time);
+CREATE INDEX IF NOT EXISTS session_messages_time ON session_messages(time);
+
+CREATE TABLE IF NOT EXISTS memory_items (
+	id   INTEGER PRIMARY KEY,
+	kind TEXT NOT NULL,
+	text TEXT NOT NULL,
+	time INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS memory_items_kind ON memory_items(kind, time);
+
+CREATE TABLE IF NOT EXISTS cron_jobs (
+	id        TEXT PRIMARY KEY,
+	name      TEXT NOT NULL,
+	message   TEXT NOT NULL,
+	fire_at   INTEGER NOT NULL,
+	channel   TEXT NOT NULL,
+	chat_id   TEXT NOT NULL,
+	agent     TEXT NOT NULL DEFAULT '',
+	recurring INTEGER NOT NULL,
+	interval  INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS cron_jobs_fire_at ON cron_jobs(fire_at);
+
+CREATE TABLE IF NOT EXISTS usage (
+	id                INTEGER PRIMARY KEY,
+	time              INTEGER NOT NULL,
+	session           TEXT NOT NULL,
+	channel           TEXT NOT NULL,
+	sender            TEXT NOT NULL,
+	model             TEXT NOT NULL,
+	prompt_tokens     INTEGER NOT NULL,
+	completion_tokens INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS usage_time ON usage(time);
+CREATE INDEX IF NOT EXISTS usage_session ON usage(session, time);
+CREATE INDEX IF NOT EXISTS usage_sender ON usage(channel, sender, time);
+`
+
+// SQLite keeps all picobot state in a single SQLite database. It implements
+// session.Store, memory.ItemStore and cron.Store; usage records are reached
+// through Store().Usage.
+type SQLite struct {
+	db *sql.DB
+}
+
+// OpenSQLite opens (creating if needed) the database at path and applies the
+// schema.
+func OpenSQLite(path string) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=foreign_keys(on)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// one connection serialises writers; WAL keeps the file readable by
+	// other processes (e.g. picobot usage) while the gateway runs
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("sqlite %s: %w", path, err)
+	}
+	return &SQLite{db: db}, nil
+}
+
+// Store returns the database as a Store for every kind of state.
+func (s *SQLite) Store() *Store {
+	return &Store{Sessions: s, Memory: s, Jobs: s, Usage: sqliteUsage{s}, closer: s.Close}
+}
+
+// Close closes the database.
+func (s *SQLite) Close() error {
+	return s.db.Close()
+}
+
+// LoadSessions returns every stored session with its messages.
+func (s *SQLite) LoadSessions() ([]*session.Session, error) {
+	rows, err := s.db.Query(`SELECT key, summary FROM sessions`)
+	if err != nil {
+		return nil, err
+	}
+	byKey := map[string]*session.Session{}
+	var out []*session.Session
+	for rows.Next() {
+		sess := &session.Session{Messages: make([]session.Message, 0)}
+		if err := rows.Scan(&sess.Key, &sess.Summary); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		byKey[sess.Key] = sess
+		out = append(out, sess)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(`SELECT session_key, role, content, tool_calls, tool_call_id, sender, time, metadata
+		FROM session_messages ORDER BY session_key, seq`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var key string
+		var m session.Message
+		var toolCalls, metadata sql.NullString
+		var t int64
+		if err := rows.Scan(&key, &m.Role, &m.Content, &toolCalls, &m.ToolCallID, &m.Sender, &t, &metadata); err != nil {
+			return nil, err
+		}
+		m.Time = fromUnixNano(t)
+		if toolCalls.Valid {
+			if err := json.Unmarshal([]byte(toolCalls.String), &m.ToolCalls); err != nil {
+				return nil, fmt.Errorf("session %s: tool calls: %w", key, err)
+			}
+		}
+		if metadata.Valid {
+			if err := json.Unmarshal([]byte(metadata.String), &m.Metadata); err != nil {
+				return nil, fmt.Errorf("session %s: metadata: %w", key, err)
+			}
+		}
+		if sess, ok := byKey[key]; ok {
+			sess.Messages = append(sess.Messages, m)
+		}
+	}
+	return out, rows.Err()
+}
+
+// SaveSession replaces the stored session and its messages in one
+// transaction.
+func (s *SQLite) SaveSession(sess *session.Session) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	channel, chatID := chatOfKey(sess.Key)
+	if _, err := tx.Exec(`INSERT INTO sessions (key, channel, chat_id, summary, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET summary = excluded.summary, updated_at = excluded.updated_at`,
+		sess.Key, channel, chatID, sess.Summary, time.Now().UnixNano()); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM session_messages WHERE session_key = ?`, sess.Key); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`INSERT INTO session_messages
+		(session_key, seq, role, content, tool_calls, tool_call_id, sender, time, metadata)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, m := range sess.Messages {
+		toolCalls, err := nullJSON(len(m.ToolCalls) > 0, m.ToolCalls)
+		if err != nil {
+			return err
+		}
+		metadata, err := nullJSON(len(m.Metadata) > 0, m.Metadata)
+		if err != nil {
+			return err
+		}
+		if _, err := stmt.Exec(sess.Key, i, m.Role, m.Content, toolCalls, m.ToolCallID, m.Sender, unixNano(m.Time), metadata); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// DeleteSession removes a session and, through the foreign key, its
+// messages.
+func (s *SQLite) DeleteSession(key string) error {
+	_, err := s.db.Exec(`DELETE FROM sessions WHERE key = ?`, key)
+	return err
+}
+
+// AddMemoryItem stores a memory item.
+func (s *SQLite) AddMemoryItem(it memory.MemoryItem) error {
+	_, err := s.db.Exec(`INSERT INTO memory_items (kind, text, time) VALUES (?, ?, ?)`, it.Kind, it.Text, unixNano(it.Timestamp))
+	return err
+}
+
+// MemoryItems returns the newest n items of kind, oldest first. n <= 0
+// returns all of them.
+func (s *SQLite) MemoryItems(kind string, n int) ([]memory.MemoryItem, error) {
+	if n <= 0 {
+		n = -1 // no limit
+	}
+	rows, err := s.db.Query(`SELECT text, time FROM memory_items WHERE kind = ? ORDER BY time DESC, id DESC LIMIT ?`, kind, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []memory.MemoryItem
+	for rows.Next() {
+		it := memory.MemoryItem{Kind: kind}
+		var t int64
+		if err := rows.Scan(&it.Text, &t); err != nil {
+			return nil, err
+		}
+		it.Timestamp = fromUnixNano(t).UTC()
+		out = append(out, it)
+	}
+	// newest first from the query; callers expect oldest first
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out, rows.Err()
+}
+
+// LoadJobs returns every pending cron job.
+func (s *SQLite) LoadJobs() ([]cron.Job, error) {
+	rows, err := s.db.Query(`SELECT id, name, message, fire_at, channel, chat_id, agent, recurring, interval FROM cron_jobs ORDER BY fire_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []cron.Job
+	for rows.Next() {
+		var j cron.Job
+		var fireAt, interval int64
+		if err := rows.Scan(&j.ID, &j.Name, &j.Message, &fireAt, &j.Channel, &j.ChatID, &j.Agent, &j.Recurring, &interval); err != nil {
+			return nil, err
+		}
+		j.FireAt = fromUnixNano(fireAt)
+		j.Interval = time.Duration(interval)
+		out = append(out, j)
+	}
+	return out, rows.Err()
+}
+
+// SaveJob inserts or updates a cron job.
+func (s *SQLite) SaveJob(j cron.Job) error {
+	_, err := s.db.Exec(`INSERT INTO cron_jobs (id, name, message, fire_at, channel, chat_id, agent, recurring, interval)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET name = excluded.name, message = excluded.message, fire_at = excluded.fire_at,
+			channel = excluded.channel, chat_id = excluded.chat_id, agent = excluded.agent, recurring = excluded.recurring, interval = excluded.interval`,
+		j.ID, j.Name, j.Message, unixNano(j.FireAt), j.Channel, j.ChatID, j.Agent, j.Recurring, int64(j.Interval))
+	return err
+}
+
+// DeleteJob removes a cron job.
+func (s *SQLite) DeleteJob(id string) error {
+	_, err := s.db.Exec(`DELETE FROM cron_jobs WHERE id = ?`, id)
+	return err
+}
+
+// sqliteUsage is the usage.Store view of the database.
+type sqliteUsage struct {
+	s *SQLite
+}
+
+// Add stores a usage record.
+func (u sqliteUsage) Add(r usage.Record) error {
+	return u.AddBatch([]usage.Record{r})
+}
+
+// AddBatch stores records in one transaction.
+func (u sqliteUsage) AddBatch(records []usage.Record) error {
+	tx, err := u.s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT INTO usage (time, session, channel, sender, model, prompt_tokens, completion_tokens)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, r := range records {
+		if r.Time.IsZero() {
+			r.Time = time.Now()
+		}
+		if _, err := stmt.Exec(unixNano(r.Time), r.Session, r.Channel, r.Sender, r.Model, r.PromptTokens, r.CompletionTokens); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Records returns all records at or after since, oldest first.
+func (u sqliteUsage) Records(since time.Time) ([]usage.Record, error) {
+	rows, err := u.s.db.Query(`SELECT time, session, channel, sender, model, prompt_tokens, completion_tokens
+		FROM usage WHERE time >= ? ORDER BY time, id`, unixNano(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []usage.Record
+	for rows.Next() {
+		var r usage.Record
+		var t int64
+		if err := rows.Scan(&t, &r.Session, &r.Channel, &r.Sender, &r.Model, &r.PromptTokens, &r.CompletionTokens); err != nil {
+			return nil, err
+		}
+		r.Time = fromUnixNano(t)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// unixNano stores the zero time as 0 rather than an out-of-range value.
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
+
+// nullJSON encodes v, or returns NULL when present is false.
+func nullJSON(present bool, v interface{}) (sql.NullString, error) {
+	if !present {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
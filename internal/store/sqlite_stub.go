@@ -0,0 +1,21 @@
+//go:build lite
+
+package store
+
+import "fmt"
+
+// SQLite is unavailable in the 'lite' build.
+type SQLite struct{}
+
+// OpenSQLite returns an error explaining that SQLite storage is not compiled
+// into this binary.
+func OpenSQLite(path string) (*SQLite, error) {
+	return nil, fmt.Errorf("SQLite storage is not compiled into this binary\n" +
+		"Download the full version of picobot from the github releases page, or set storage.backend to \"files\"")
+}
+
+// Store is never reached in the lite build.
+func (s *SQLite) Store() *Store { return nil }
+
+// Close is never reached in the lite build.
+func (s *SQLite) Close() error { return nil }
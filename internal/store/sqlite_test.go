@@ -0,0 +1,218 @@
+//go:build !lite
+
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/cron"
+	"github.com/local/picobot/internal/providers"
+	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/usage"
+)
+
+func openTestDB(t *testing.T) *SQLite {
+	t.Helper()
+	db, err := OpenSQLite(filepath.Join(t.TempDir(), "picobot.db"))
+	if err != nil {
+		t.Fatalf("OpenSQLite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestSQLiteSessions(t *testing.T) {
+	db := openTestDB(t)
+	sess := &session.Session{Key: "telegram:42", Summary: "talked about tea"}
+	sess.Add(session.Message{Role: "user", Content: "weather?", Sender: "u1", Metadata: map[string]interface{}{"voice": true}})
+	sess.Add(session.Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c1", Name: "web", Arguments: map[string]interface{}{"url": "x"}}}})
+	sess.Add(session.Message{Role: "tool", Content: "sunny", ToolCallID: "c1"})
+	sess.AddMessage("assistant", "It's sunny.")
+	if err := db.SaveSession(sess); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+	// saving again replaces the messages rather than appending
+	sess.Messages = sess.Messages[2:]
+	if err := db.SaveSession(sess); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+
+	loaded, err := db.LoadSessions()
+	if err != nil {
+		t.Fatalf("LoadSessions: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Key != "telegram:42" || loaded[0].Summary != "talked about tea" {
+		t.Fatalf("unexpected sessions: %+v", loaded)
+	}
+	h := loaded[0].Messages
+	if len(h) != 2 || h[0].ToolCallID != "c1" || h[1].Content != "It's sunny." || h[1].Time.IsZero() {
+		t.Fatalf("unexpected messages: %+v", h)
+	}
+
+	sess.Messages = nil
+	sess.Add(session.Message{Role: "user", Content: "hi", Metadata: map[string]interface{}{"voice": true}})
+	sess.Add(session.Message{Role: "assistant", ToolCalls: []providers.ToolCall{{ID: "c2", Name: "exec"}}})
+	if err := db.SaveSession(sess); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+	loaded, _ = db.LoadSessions()
+	h = loaded[0].Messages
+	if h[0].Metadata["voice"] != true || len(h[1].ToolCalls) != 1 || h[1].ToolCalls[0].Name != "exec" {
+		t.Fatalf("metadata or tool calls not preserved: %+v", h)
+	}
+
+	if err := db.DeleteSession("telegram:42"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if loaded, _ = db.LoadSessions(); len(loaded) != 0 {
+		t.Fatalf("expected no sessions after delete, got %+v", loaded)
+	}
+	var orphans int
+	db.db.QueryRow(`SELECT COUNT(*) FROM session_messages`).Scan(&orphans)
+	if orphans != 0 {
+		t.Fatalf("expected messages to be deleted with the session, got %d", orphans)
+	}
+}
+
+func TestSQLiteMemoryItems(t *testing.T) {
+	db := openTestDB(t)
+	base := time.Now().UTC()
+	for i, text := range []string{"a", "b", "c"} {
+		if err := db.AddMemoryItem(memory.MemoryItem{Kind: "short", Text: text, Timestamp: base.Add(time.Duration(i) * time.Second)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.AddMemoryItem(memory.MemoryItem{Kind: "long", Text: "L", Timestamp: base})
+
+	items, err := db.MemoryItems("short", 2)
+	if err != nil {
+		t.Fatalf("MemoryItems: %v", err)
+	}
+	if len(items) != 2 || items[0].Text != "b" || items[1].Text != "c" {
+		t.Fatalf("expected the newest two short items oldest first, got %v", items)
+	}
+	if all, _ := db.MemoryItems("long", 0); len(all) != 1 || all[0].Text != "L" {
+		t.Fatalf("unexpected long items: %v", all)
+	}
+}
+
+func TestSQLiteJobs(t *testing.T) {
+	db := openTestDB(t)
+	fireAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	job := cron.Job{ID: "job-1", Name: "stretch", Message: "stand up", FireAt: fireAt, Channel: "slack", ChatID: "C1", Agent: "ops", Recurring: true, Interval: time.Hour}
+	if err := db.SaveJob(job); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+	job.FireAt = fireAt.Add(time.Hour)
+	if err := db.SaveJob(job); err != nil {
+		t.Fatalf("SaveJob update: %v", err)
+	}
+	jobs, err := db.LoadJobs()
+	if err != nil {
+		t.Fatalf("LoadJobs: %v", err)
+	}
+	if len(jobs) != 1 || !jobs[0].FireAt.Equal(job.FireAt) || jobs[0].Agent != "ops" || !jobs[0].Recurring || jobs[0].Interval != time.Hour {
+		t.Fatalf("unexpected jobs: %+v", jobs)
+	}
+	if err := db.DeleteJob("job-1"); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	if jobs, _ := db.LoadJobs(); len(jobs) != 0 {
+		t.Fatalf("expected no jobs, got %+v", jobs)
+	}
+}
+
+func TestSQLiteUsage(t *testing.T) {
+	u := openTestDB(t).Store().Usage
+	now := time.Now()
+	u.Add(usage.Record{Time: now.Add(-48 * time.Hour), Session: "telegram:1", Channel: "telegram", Sender: "u1", Model: "m", PromptTokens: 5})
+	u.Add(usage.Record{Time: now, Session: "discord:2", Channel: "discord", Sender: "u2", Model: "m", PromptTokens: 7, CompletionTokens: 3})
+
+	recent, err := u.Records(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Records: %v", err)
+	}
+	if len(recent) != 1 || recent[0].Session != "discord:2" || recent[0].CompletionTokens != 3 {
+		t.Fatalf("unexpected records: %+v", recent)
+	}
+	if all, _ := u.Records(time.Time{}); len(all) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(all))
+	}
+}
+
+func TestMigrateFilesToSQLite(t *testing.T) {
+	ws := t.TempDir()
+	files := Files(ws)
+	sess := &session.Session{Key: "whatsapp:99"}
+	sess.AddMessage("user", "hello")
+	files.Sessions.SaveSession(sess)
+	files.Usage.Add(usage.Record{Session: "whatsapp:99", Channel: "whatsapp", Model: "m", PromptTokens: 1})
+
+	db := openTestDB(t).Store()
+	rep, err := Migrate(files, db)
+	if err != nil {
+		t.Fatalf("Migrate: %v", err)
+	}
+	if rep.Sessions != 1 || rep.UsageRecords != 1 || rep.UsageSkipped {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	loaded, _ := db.Sessions.LoadSessions()
+	if len(loaded) != 1 || loaded[0].Messages[0].Content != "hello" {
+		t.Fatalf("session not migrated: %+v", loaded)
+	}
+
+	// a second run must not double count usage
+	rep, err = Migrate(files, db)
+	if err != nil {
+		t.Fatalf("Migrate again: %v", err)
+	}
+	if !rep.UsageSkipped {
+		t.Fatalf("expected usage to be skipped, got %+v", rep)
+	}
+	if records, _ := db.Usage.Records(time.Time{}); len(records) != 1 {
+		t.Fatalf("expected 1 usage record, got %d", len(records))
+	}
+}
+
+func TestSQLiteAgentSessionsAreSeparate(t *testing.T) {
+	db := openTestDB(t)
+	main := db.Store()
+	ops, family := main.ForAgent("ops", t.TempDir()), main.ForAgent("family", t.TempDir())
+	for _, st := range []*Store{main, ops, family} {
+		sess := &session.Session{Key: "telegram:42"}
+		sess.AddMessage("user", "hello")
+		sess.AddMessage("assistant", "hi")
+		if st == ops {
+			sess.AddMessage("user", "deploy?")
+		}
+		if err := st.Sessions.SaveSession(sess); err != nil {
+			t.Fatalf("SaveSession: %v", err)
+		}
+	}
+
+	loaded, err := ops.Sessions.LoadSessions()
+	if err != nil {
+		t.Fatalf("LoadSessions: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Key != "telegram:42" || len(loaded[0].Messages) != 3 {
+		t.Fatalf("expected only the ops session, got %+v", loaded)
+	}
+	if loaded, _ := family.Sessions.LoadSessions(); len(loaded) != 1 || len(loaded[0].Messages) != 2 {
+		t.Fatalf("expected the family session untouched, got %+v", loaded)
+	}
+	var channel, chatID string
+	db.db.QueryRow(`SELECT channel, chat_id FROM sessions WHERE key = 'ops/telegram:42'`).Scan(&channel, &chatID)
+	if channel != "telegram" || chatID != "42" {
+		t.Fatalf("expected the chat without the profile, got %q %q", channel, chatID)
+	}
+
+	if err := ops.Sessions.DeleteSession("telegram:42"); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if all, _ := main.Sessions.LoadSessions(); len(all) != 2 {
+		t.Fatalf("expected the other profiles' sessions to remain, got %+v", all)
+	}
+}
@@ -0,0 +1,187 @@
+// Package store selects where picobot keeps its state: sessions, memory
+// items, cron jobs and token usage.
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/local/picobot/internal/agent/memory"
+	"github.com/local/picobot/internal/config"
+	"github.com/local/picobot/internal/cron"
+	"github.com/local/picobot/internal/session"
+	"github.com/local/picobot/internal/usage"
+)
+
+// DefaultSQLitePath is used when storage.path is not configured.
+const DefaultSQLitePath = "~/.picobot/picobot.db"
+
+// Store bundles the storage backends.
+type Store struct {
+	Sessions session.Store
+	Memory   memory.ItemStore // nil keeps memory items in RAM only
+	Jobs     cron.Store       // nil keeps cron jobs in RAM only
+	Usage    usage.Store
+	closer   func() error
+	files    bool // the files backend
+}
+
+// Files returns the file-based store: one JSON file per session and a JSON
+// Lines usage ledger under workspace. Memory items and cron jobs are not
+// persisted.
+func Files(workspace string) *Store {
+	return &Store{
+		Sessions: session.NewFileStore(workspace),
+		Usage:    usage.NewLedger(workspace),
+		files:    true,
+	}
+}
+
+// ForAgent returns the store of the agent profile name with its own
+// workspace. With the files backend that is the files under workspace. A
+// database is shared by all profiles: the profile's sessions and usage go
+// to it, its session keys prefixed with "name/" so that profiles answering
+// in the same chat keep their own history, but its memory items stay in RAM
+// so profiles never see each other's memories. Cron jobs are only persisted
+// through the main store.
+func (s *Store) ForAgent(name, workspace string) *Store {
+	if s.files {
+		return Files(workspace)
+	}
+	return &Store{Sessions: agentSessions{store: s.Sessions, prefix: name + "/"}, Usage: s.Usage}
+}
+
+// agentSessions is the part of a shared session store that belongs to one
+// agent profile: the sessions whose keys start with prefix, which it strips
+// on load and adds back on save.
+type agentSessions struct {
+	store  session.Store
+	prefix string
+}
+
+func (a agentSessions) LoadSessions() ([]*session.Session, error) {
+	all, err := a.store.LoadSessions()
+	if err != nil {
+		return nil, err
+	}
+	var out []*session.Session
+	for _, sess := range all {
+		if key, ok := strings.CutPrefix(sess.Key, a.prefix); ok {
+			sess.Key = key
+			out = append(out, sess)
+		}
+	}
+	return out, nil
+}
+
+func (a agentSessions) SaveSession(sess *session.Session) error {
+	prefixed := &session.Session{Key: a.prefix + sess.Key, Messages: sess.Messages, Summary: sess.Summary}
+	return a.store.SaveSession(prefixed)
+}
+
+func (a agentSessions) DeleteSession(key string) error {
+	return a.store.DeleteSession(a.prefix + key)
+}
+
+// chatOfKey returns the channel and chat ID of a session key, dropping the
+// "profile/" prefix of ForAgent.
+func chatOfKey(key string) (channel, chatID string) {
+	if i := strings.Index(key, "/"); i >= 0 && i < strings.Index(key, ":") {
+		key = key[i+1:]
+	}
+	channel, chatID, _ = strings.Cut(key, ":")
+	return channel, chatID
+}
+
+// Open returns the store selected by cfg.Backend: "files" (the default) or
+// "sqlite".
+func Open(cfg config.StorageConfig, workspace string) (*Store, error) {
+	switch cfg.Backend {
+	case "", "files":
+		return Files(workspace), nil
+	case "sqlite":
+		db, err := OpenSQLite(SQLitePath(cfg))
+		if err != nil {
+			return nil, err
+		}
+		return db.Store(), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q (want files or sqlite)", cfg.Backend)
+	}
+}
+
+// SQLitePath returns the configured database path with a leading "~/"
+// expanded.
+func SQLitePath(cfg config.StorageConfig) string {
+	path := cfg.Path
+	if path == "" {
+		path = DefaultSQLitePath
+	}
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// Close releases the underlying database, if any.
+func (s *Store) Close() error {
+	if s.closer == nil {
+		return nil
+	}
+	return s.closer()
+}
+
+// MigrateReport counts what Migrate copied.
+type MigrateReport struct {
+	Sessions     int
+	UsageRecords int
+	UsageSkipped bool // the target already had usage records
+}
+
+// Migrate copies sessions and usage records from one store to another.
+// Sessions are overwritten by key, so running it twice is harmless; usage is
+// only copied into a target that has none yet, to avoid double counting.
+func Migrate(from, to *Store) (MigrateReport, error) {
+	var rep MigrateReport
+	sessions, err := from.Sessions.LoadSessions()
+	if err != nil {
+		return rep, fmt.Errorf("reading sessions: %w", err)
+	}
+	for _, s := range sessions {
+		if err := to.Sessions.SaveSession(s); err != nil {
+			return rep, fmt.Errorf("writing session %s: %w", s.Key, err)
+		}
+		rep.Sessions++
+	}
+
+	existing, err := to.Usage.Records(time.Time{})
+	if err != nil {
+		return rep, fmt.Errorf("reading target usage: %w", err)
+	}
+	if len(existing) > 0 {
+		rep.UsageSkipped = true
+		return rep, nil
+	}
+	records, err := from.Usage.Records(time.Time{})
+	if err != nil {
+		return rep, fmt.Errorf("reading usage: %w", err)
+	}
+	if b, ok := to.Usage.(interface{ AddBatch([]usage.Record) error }); ok {
+		if err := b.AddBatch(records); err != nil {
+			return rep, fmt.Errorf("writing usage: %w", err)
+		}
+		rep.UsageRecords = len(records)
+		return rep, nil
+	}
+	for _, r := range records {
+		if err := to.Usage.Add(r); err != nil {
+			return rep, fmt.Errorf("writing usage: %w", err)
+		}
+		rep.UsageRecords++
+	}
+	return rep, nil
+}
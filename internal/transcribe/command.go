@@ -0,0 +1,80 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// inputPlaceholder in CommandTranscriber.Args is replaced by the audio file path.
+const inputPlaceholder = "{input}"
+
+// CommandTranscriber runs a local program, such as whisper.cpp, on a temporary
+// file holding the audio and uses its standard output as the transcript. This
+// keeps voice messages on the machine. A run taking longer than Timeout is
+// killed.
+//
+// Example for whisper.cpp (which needs 16 kHz WAV, so wrap it in a script that
+// converts with ffmpeg first):
+//
+//	Command: "/usr/local/bin/transcribe.sh", Args: ["{input}"]
+type CommandTranscriber struct {
+	Command string
+	Args    []string // "{input}" is replaced by the file path; appended if absent
+	Timeout time.Duration
+}
+
+func NewCommandTranscriber(command string, args []string, timeoutSecs int) *CommandTranscriber {
+	if timeoutSecs <= 0 {
+		timeoutSecs = 60 // default 60 seconds
+	}
+	return &CommandTranscriber{Command: command, Args: args, Timeout: time.Duration(timeoutSecs) * time.Second}
+}
+
+// Transcribe writes audio to a temporary file and runs the command on it.
+func (t *CommandTranscriber) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	f, err := os.CreateTemp("", "picobot-voice-*"+fileExtension(mimeType))
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(audio); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+
+	args := make([]string, 0, len(t.Args)+1)
+	replaced := false
+	for _, a := range t.Args {
+		if strings.Contains(a, inputPlaceholder) {
+			a = strings.ReplaceAll(a, inputPlaceholder, f.Name())
+			replaced = true
+		}
+		args = append(args, a)
+	}
+	if !replaced {
+		args = append(args, f.Name())
+	}
+
+	// ctx is usually the channel's, which lives as long as the gateway
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.Command, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("transcription command timed out after %s", t.Timeout)
+		}
+		return "", fmt.Errorf("transcription command failed: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
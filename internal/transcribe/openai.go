@@ -0,0 +1,89 @@
+package transcribe
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenAITranscriber calls an OpenAI-compatible /audio/transcriptions endpoint
+// (OpenAI, Groq, a local faster-whisper server, ...).
+type OpenAITranscriber struct {
+	APIKey  string
+	APIBase string // e.g. https://api.openai.com/v1
+	Model   string // e.g. whisper-1
+	Client  *http.Client
+}
+
+func NewOpenAITranscriber(apiKey, apiBase, model string, timeoutSecs int) *OpenAITranscriber {
+	if apiBase == "" {
+		apiBase = "https://api.openai.com/v1"
+	}
+	if model == "" {
+		model = "whisper-1"
+	}
+	if timeoutSecs <= 1 {
+		timeoutSecs = 60 // default 60 seconds
+	}
+	return &OpenAITranscriber{
+		APIKey:  apiKey,
+		APIBase: strings.TrimRight(apiBase, "/"),
+		Model:   model,
+		Client:  &http.Client{Timeout: time.Duration(timeoutSecs) * time.Second},
+	}
+}
+
+// Transcribe uploads audio as multipart form data and returns the text.
+func (t *OpenAITranscriber) Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error) {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	if err := w.WriteField("model", t.Model); err != nil {
+		return "", err
+	}
+	if err := w.WriteField("response_format", "json"); err != nil {
+		return "", err
+	}
+	fw, err := w.CreateFormFile("file", "voice"+fileExtension(mimeType))
+	if err != nil {
+		return "", err
+	}
+	if _, err := fw.Write(audio); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", t.APIBase+"/audio/transcriptions", &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	if t.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.APIKey)
+	}
+
+	resp, err := t.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		b, _ := io.ReadAll(resp.Body)
+		return "", fmt.Errorf("transcription API error: %s - %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	var out struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out.Text), nil
+}
@@ -0,0 +1,75 @@
+// Package transcribe turns voice messages into text before they reach the
+// agent. Backends implement Transcriber.
+package transcribe
+
+import (
+	"context"
+	"log"
+	"mime"
+	"strings"
+
+	"github.com/local/picobot/internal/config"
+)
+
+// MaxAudioBytes is the largest voice message that is transcribed. It matches
+// the OpenAI /audio/transcriptions upload limit.
+const MaxAudioBytes = 25 << 20
+
+// Transcriber converts recorded speech to text.
+type Transcriber interface {
+	// Transcribe returns the transcript of audio, whose format is given by
+	// mimeType (e.g. "audio/ogg" for Telegram and WhatsApp voice notes).
+	Transcribe(ctx context.Context, audio []byte, mimeType string) (string, error)
+}
+
+// fileExtension picks a file extension for mimeType; backends use it because
+// they detect the audio format from the file name.
+func fileExtension(mimeType string) string {
+	base := strings.TrimSpace(strings.Split(mimeType, ";")[0])
+	switch base {
+	case "audio/ogg", "audio/opus", "":
+		return ".ogg"
+	case "audio/mpeg":
+		return ".mp3"
+	case "audio/mp4", "audio/m4a", "audio/x-m4a":
+		return ".m4a"
+	case "audio/wav", "audio/x-wav":
+		return ".wav"
+	case "audio/webm":
+		return ".webm"
+	}
+	if exts, _ := mime.ExtensionsByType(base); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".ogg"
+}
+
+// NewFromConfig returns the transcriber selected by cfg.Transcription, or nil
+// when transcription is disabled or misconfigured.
+func NewFromConfig(cfg config.Config) Transcriber {
+	tc := cfg.Transcription
+	switch tc.Provider {
+	case "":
+		return nil
+	case "openai":
+		apiKey, apiBase := tc.APIKey, tc.APIBase
+		if o := cfg.Providers.OpenAI; o != nil {
+			if apiKey == "" {
+				apiKey = o.APIKey
+			}
+			if apiBase == "" {
+				apiBase = o.APIBase
+			}
+		}
+		return NewOpenAITranscriber(apiKey, apiBase, tc.Model, tc.TimeoutS)
+	case "command":
+		if tc.Command == "" {
+			log.Printf("transcription: provider \"command\" needs transcription.command; disabled")
+			return nil
+		}
+		return NewCommandTranscriber(tc.Command, tc.Args, tc.TimeoutS)
+	default:
+		log.Printf("transcription: unknown provider %q; disabled", tc.Provider)
+		return nil
+	}
+}
@@ -0,0 +1,106 @@
+package transcribe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/config"
+)
+
+func TestOpenAITranscriberUploadsAudio(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/audio/transcriptions" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer k" {
+			t.Errorf("unexpected auth header %q", got)
+		}
+		if got := r.FormValue("model"); got != "whisper-1" {
+			t.Errorf("unexpected model %q", got)
+		}
+		f, hdr, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("missing file: %v", err)
+			return
+		}
+		data, _ := io.ReadAll(f)
+		if string(data) != "oggbytes" || !strings.HasSuffix(hdr.Filename, ".ogg") {
+			t.Errorf("unexpected upload %q (%s)", data, hdr.Filename)
+		}
+		w.Write([]byte(`{"text":" buy milk "}`))
+	}))
+	defer h.Close()
+
+	tr := NewOpenAITranscriber("k", h.URL+"/", "", 5)
+	got, err := tr.Transcribe(context.Background(), []byte("oggbytes"), "audio/ogg; codecs=opus")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "buy milk" {
+		t.Fatalf("expected trimmed transcript, got %q", got)
+	}
+}
+
+func TestOpenAITranscriberError(t *testing.T) {
+	h := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad audio", http.StatusBadRequest)
+	}))
+	defer h.Close()
+
+	_, err := NewOpenAITranscriber("", h.URL, "", 5).Transcribe(context.Background(), []byte("x"), "audio/ogg")
+	if err == nil || !strings.Contains(err.Error(), "bad audio") {
+		t.Fatalf("expected API error, got %v", err)
+	}
+}
+
+func TestCommandTranscriber(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	tr := NewCommandTranscriber("sh", []string{"-c", `echo "file: $(cat "$1")"`, "sh", "{input}"}, 0)
+	got, err := tr.Transcribe(context.Background(), []byte("hello"), "audio/ogg")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "file: hello" {
+		t.Fatalf("unexpected transcript %q", got)
+	}
+
+	failing := NewCommandTranscriber("sh", []string{"-c", "echo boom >&2; exit 1"}, 0)
+	if _, err := failing.Transcribe(context.Background(), []byte("x"), "audio/ogg"); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("expected command error with stderr, got %v", err)
+	}
+
+	hanging := NewCommandTranscriber("sh", []string{"-c", "exec sleep 10"}, 0)
+	hanging.Timeout = 100 * time.Millisecond
+	start := time.Now()
+	if _, err := hanging.Transcribe(context.Background(), []byte("x"), "audio/ogg"); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("the command was not stopped at the timeout")
+	}
+}
+
+func TestNewFromConfig(t *testing.T) {
+	if NewFromConfig(config.Config{}) != nil {
+		t.Fatalf("expected nil transcriber when disabled")
+	}
+	cfg := config.Config{
+		Providers:     config.ProvidersConfig{OpenAI: &config.ProviderConfig{APIKey: "k", APIBase: "http://local/v1"}},
+		Transcription: config.TranscriptionConfig{Provider: "openai"},
+	}
+	o, ok := NewFromConfig(cfg).(*OpenAITranscriber)
+	if !ok || o.APIKey != "k" || o.APIBase != "http://local/v1" || o.Model != "whisper-1" {
+		t.Fatalf("expected OpenAI transcriber using provider credentials, got %+v", o)
+	}
+	if NewFromConfig(config.Config{Transcription: config.TranscriptionConfig{Provider: "command"}}) != nil {
+		t.Fatalf("expected nil transcriber without a command")
+	}
+}
@@ -0,0 +1,180 @@
+// Package usage records LLM token consumption and reports it per session,
+// sender, channel or model.
+package usage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/local/picobot/internal/config"
+)
+
+// Record is one provider call in the ledger.
+type Record struct {
+	Time             time.Time `json:"time"`
+	Session          string    `json:"session"`
+	Channel          string    `json:"channel"`
+	Sender           string    `json:"sender"`
+	Model            string    `json:"model"`
+	PromptTokens     int       `json:"promptTokens"`
+	CompletionTokens int       `json:"completionTokens"`
+}
+
+// Store persists usage records.
+type Store interface {
+	Add(r Record) error
+	// Records returns all records at or after since, oldest first.
+	Records(since time.Time) ([]Record, error)
+}
+
+// Ledger is an append-only JSON Lines file of usage records, stored at
+// workspace/usage/usage.jsonl.
+type Ledger struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewLedger returns a ledger under the given workspace. The file is created on
+// the first Add.
+func NewLedger(workspace string) *Ledger {
+	return &Ledger{path: filepath.Join(workspace, "usage", "usage.jsonl")}
+}
+
+// Add appends a record to the ledger.
+func (l *Ledger) Add(r Record) error {
+	if r.Time.IsZero() {
+		r.Time = time.Now()
+	}
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(b, '\n'))
+	return err
+}
+
+// Records returns all records at or after since. A missing ledger file yields
+// no records. Malformed lines are skipped.
+func (l *Ledger) Records(since time.Time) ([]Record, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	f, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var out []Record
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.Time.Before(since) {
+			continue
+		}
+		out = append(out, r)
+	}
+	return out, sc.Err()
+}
+
+// Totals aggregates usage for one group.
+type Totals struct {
+	Key              string
+	Requests         int
+	PromptTokens     int
+	CompletionTokens int
+	Cost             float64 // estimated USD; 0 when no price is known
+	Priced           bool    // false if any record in the group had no price
+}
+
+// Aggregate groups records by "session", "sender", "channel" or "model" and
+// sorts the groups by cost, then by total tokens, highest first.
+func Aggregate(records []Record, by string, prices map[string]config.ModelPrice) ([]Totals, error) {
+	var keyOf func(Record) string
+	switch by {
+	case "session":
+		keyOf = func(r Record) string { return r.Session }
+	case "sender":
+		keyOf = func(r Record) string { return r.Channel + ":" + r.Sender }
+	case "channel":
+		keyOf = func(r Record) string { return r.Channel }
+	case "model":
+		keyOf = func(r Record) string { return r.Model }
+	default:
+		return nil, fmt.Errorf("unknown grouping %q (want session, sender, channel or model)", by)
+	}
+
+	groups := map[string]*Totals{}
+	for _, r := range records {
+		k := keyOf(r)
+		t, ok := groups[k]
+		if !ok {
+			t = &Totals{Key: k, Priced: true}
+			groups[k] = t
+		}
+		t.Requests++
+		t.PromptTokens += r.PromptTokens
+		t.CompletionTokens += r.CompletionTokens
+		cost, ok := Cost(prices, r.Model, r.PromptTokens, r.CompletionTokens)
+		t.Cost += cost
+		t.Priced = t.Priced && ok
+	}
+
+	out := make([]Totals, 0, len(groups))
+	for _, t := range groups {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Cost != out[j].Cost {
+			return out[i].Cost > out[j].Cost
+		}
+		ti := out[i].PromptTokens + out[i].CompletionTokens
+		tj := out[j].PromptTokens + out[j].CompletionTokens
+		if ti != tj {
+			return ti > tj
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
+
+// Cost estimates the USD cost of a call. The price for model is looked up by
+// exact name first, then by the longest configured prefix. ok is false when
+// no price matches.
+func Cost(prices map[string]config.ModelPrice, model string, prompt, completion int) (float64, bool) {
+	p, ok := prices[model]
+	if !ok {
+		best := ""
+		for k, v := range prices {
+			if strings.HasPrefix(model, k) && len(k) > len(best) {
+				best, p, ok = k, v, true
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	return (float64(prompt)*p.Prompt + float64(completion)*p.Completion) / 1e6, true
+}
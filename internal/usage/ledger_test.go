@@ -0,0 +1,65 @@
+package usage
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/local/picobot/internal/config"
+)
+
+func TestLedgerAddAndRecords(t *testing.T) {
+	l := NewLedger(t.TempDir())
+
+	recs, err := l.Records(time.Time{})
+	if err != nil || len(recs) != 0 {
+		t.Fatalf("expected empty ledger, got %v, %v", recs, err)
+	}
+
+	old := Record{Time: time.Now().Add(-48 * time.Hour), Session: "telegram:1", Channel: "telegram", Sender: "u1", Model: "m", PromptTokens: 5}
+	if err := l.Add(old); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+	if err := l.Add(Record{Session: "telegram:1", Channel: "telegram", Sender: "u1", Model: "m", PromptTokens: 10, CompletionTokens: 2}); err != nil {
+		t.Fatalf("add: %v", err)
+	}
+
+	all, _ := l.Records(time.Time{})
+	if len(all) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(all))
+	}
+	recent, _ := l.Records(time.Now().Add(-time.Hour))
+	if len(recent) != 1 || recent[0].PromptTokens != 10 || recent[0].Time.IsZero() {
+		t.Fatalf("expected only the recent record with a timestamp, got %+v", recent)
+	}
+}
+
+func TestAggregateAndCost(t *testing.T) {
+	prices := map[string]config.ModelPrice{
+		"openai/gpt-4o-mini": {Prompt: 0.15, Completion: 0.60},
+		"anthropic/":         {Prompt: 3, Completion: 15},
+	}
+	recs := []Record{
+		{Session: "telegram:1", Channel: "telegram", Sender: "u1", Model: "openai/gpt-4o-mini", PromptTokens: 1000000, CompletionTokens: 1000000},
+		{Session: "discord:2", Channel: "discord", Sender: "u2", Model: "anthropic/claude-sonnet-4-5", PromptTokens: 1000000},
+		{Session: "discord:2", Channel: "discord", Sender: "u2", Model: "llama3.2", PromptTokens: 500},
+	}
+
+	totals, err := Aggregate(recs, "channel", prices)
+	if err != nil {
+		t.Fatalf("aggregate: %v", err)
+	}
+	if len(totals) != 2 || totals[0].Key != "discord" {
+		t.Fatalf("expected discord first (highest cost), got %+v", totals)
+	}
+	if totals[0].Requests != 2 || totals[0].PromptTokens != 1000500 || totals[0].Priced {
+		t.Fatalf("unexpected discord totals: %+v", totals[0])
+	}
+	if math.Abs(totals[0].Cost-3) > 1e-9 || math.Abs(totals[1].Cost-0.75) > 1e-9 {
+		t.Fatalf("unexpected costs: %v and %v", totals[0].Cost, totals[1].Cost)
+	}
+
+	if _, err := Aggregate(recs, "nope", prices); err == nil {
+		t.Fatalf("expected error for unknown grouping")
+	}
+}